@@ -0,0 +1,436 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	flowcontrolv1 "k8s.io/api/flowcontrol/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// run the chaos actions concurrently for a duration, like:
+//   c := f.NewChaos(ChaosOptions{Duration: 5 * time.Minute, Jitter: 0.3, Seed: 42})
+//   c.Add(f.PodKillAction("kube-system", map[string]string{"app": "spiderpool-agent"}, 20*time.Second),
+//   	f.NodeRestartAction(nil, 2*time.Minute))
+//   timeline, err := c.Run(ctx)
+//   GinkgoWriter.Print(timeline.String())
+// the seed is logged, set it again to reproduce the order of the random choices
+
+// ChaosRestartedAtAnnotation is set on the pod template by DaemonSetRolloutAction, like `kubectl rollout restart`
+const ChaosRestartedAtAnnotation = "e2eframework.spidernet.io/restartedAt"
+
+// ChaosOptions configures Chaos
+type ChaosOptions struct {
+	// how long the actions run, 0 means until ctx is done
+	Duration time.Duration
+	// the ratio of the random variation of the interval of each action, like 0.2 for ±20%
+	Jitter float64
+	// the seed of the random choices and jitters, 0 means the current time
+	Seed int64
+	// StopOnError stops all the actions at the first failed injection, or it is recorded and the action goes on
+	StopOnError bool
+}
+
+// ChaosAction injects the fault repeatedly, once every Interval
+type ChaosAction struct {
+	// like "pod-kill"
+	Name     string
+	Interval time.Duration
+	// Inject injects the fault once and returns the detail for the timeline,
+	// the empty detail and nil error means nothing to inject this time.
+	// rnd belongs to the action, so it is used without lock
+	Inject func(ctx context.Context, rnd *rand.Rand) (string, error)
+}
+
+// ChaosEvent is one injection of the timeline
+type ChaosEvent struct {
+	Action string
+	Start  time.Time
+	// the end of the injection, the lasting fault like the API throttling ends when it is recovered
+	End    time.Time
+	Detail string
+	Err    error
+}
+
+// ChaosTimeline is the injections in the order of the start
+type ChaosTimeline []ChaosEvent
+
+// Chaos runs the actions, it runs once
+type Chaos struct {
+	Options ChaosOptions
+
+	f       *Framework
+	actions []ChaosAction
+
+	lock     sync.Mutex
+	timeline ChaosTimeline
+}
+
+// NewChaos returns the Chaos with no action
+func (f *Framework) NewChaos(opts ChaosOptions) *Chaos {
+	return &Chaos{Options: opts, f: f}
+}
+
+// Add adds the actions
+func (c *Chaos) Add(actions ...ChaosAction) *Chaos {
+	c.actions = append(c.actions, actions...)
+	return c
+}
+
+// Run runs every action in its goroutine until Duration passes or ctx is done, and returns the timeline.
+// The error is the invalid action, or the first failed injection with StopOnError
+func (c *Chaos) Run(ctx context.Context) (ChaosTimeline, error) {
+	if len(c.actions) == 0 || c.Options.Jitter < 0 || c.Options.Jitter >= 1 {
+		return nil, ErrWrongInput
+	}
+	for _, a := range c.actions {
+		if a.Name == "" || a.Interval <= 0 || a.Inject == nil {
+			return nil, fmt.Errorf("%w: chaos action %q needs the interval and the inject", ErrWrongInput, a.Name)
+		}
+	}
+
+	seed := c.Options.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	c.f.Log("chaos starts with seed %v for %v \n", seed, c.Options.Duration)
+
+	if c.Options.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Options.Duration)
+		defer cancel()
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	// every action has its own random, which is seeded in the order of Add
+	rnd := rand.New(rand.NewSource(seed))
+	wg := sync.WaitGroup{}
+	for _, a := range c.actions {
+		r := rand.New(rand.NewSource(rnd.Int63()))
+		wg.Add(1)
+		go func(a ChaosAction, r *rand.Rand) {
+			defer wg.Done()
+			c.runAction(ctx, cancel, a, r)
+		}(a, r)
+	}
+	wg.Wait()
+
+	err := context.Cause(ctx)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		err = nil
+	}
+	c.f.Log("chaos stops with %d injections \n", len(c.Timeline()))
+	return c.Timeline(), err
+}
+
+func (c *Chaos) runAction(ctx context.Context, cancel context.CancelCauseFunc, a ChaosAction, r *rand.Rand) {
+	for {
+		// the jitter is taken first, so the random choices do not depend on the time
+		interval := a.Interval
+		if c.Options.Jitter > 0 {
+			interval = time.Duration(float64(interval) * (1 + c.Options.Jitter*(2*r.Float64()-1)))
+		}
+
+		start := time.Now()
+		detail, err := a.Inject(ctx, r)
+		if err != nil && ctx.Err() != nil {
+			// the injection is interrupted by the end
+			return
+		}
+		if detail != "" || err != nil {
+			event := ChaosEvent{Action: a.Name, Start: start, End: time.Now(), Detail: detail, Err: err}
+			c.record(event)
+			if err != nil && c.Options.StopOnError {
+				cancel(fmt.Errorf("chaos action %s failed: %w", a.Name, err))
+				return
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		sleepContext(ctx, interval)
+	}
+}
+
+func (c *Chaos) record(event ChaosEvent) {
+	if event.Err != nil {
+		c.f.Log("chaos %s failed: %v \n", event.Action, event.Err)
+	} else {
+		c.f.Log("chaos %s: %s \n", event.Action, event.Detail)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.timeline = append(c.timeline, event)
+}
+
+// Timeline returns the injections till now, in the order of the start
+func (c *Chaos) Timeline() ChaosTimeline {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	r := append(ChaosTimeline{}, c.timeline...)
+	slices.SortStableFunc(r, func(a, b ChaosEvent) int { return a.Start.Compare(b.Start) })
+	return r
+}
+
+// Failed returns the failed injections
+func (t ChaosTimeline) Failed() ChaosTimeline {
+	r := ChaosTimeline{}
+	for _, e := range t {
+		if e.Err != nil {
+			r = append(r, e)
+		}
+	}
+	return r
+}
+
+// Between returns the injections overlapping the period, for finding the faults around a problem
+func (t ChaosTimeline) Between(start, end time.Time) ChaosTimeline {
+	r := ChaosTimeline{}
+	for _, e := range t {
+		if !e.Start.After(end) && !e.End.Before(start) {
+			r = append(r, e)
+		}
+	}
+	return r
+}
+
+// String renders the timeline as a table
+func (t ChaosTimeline) String() string {
+	b := &strings.Builder{}
+	w := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "START\tDURATION\tACTION\tDETAIL\tERROR")
+	for _, e := range t {
+		errStr := ""
+		if e.Err != nil {
+			errStr = e.Err.Error()
+		}
+		fmt.Fprintf(w, "%s\t%v\t%s\t%s\t%s\n", e.Start.Format(time.RFC3339Nano), e.End.Sub(e.Start).Round(time.Millisecond), e.Action, e.Detail, errStr)
+	}
+	w.Flush()
+	return b.String()
+}
+
+// ------------- actions
+
+// randomPod returns a random pod not being deleted, nil for no pod
+func (f *Framework) randomPod(ctx context.Context, rnd *rand.Rand, namespace string, label map[string]string, match func(*corev1.Pod) bool) (*corev1.Pod, error) {
+	podList := &corev1.PodList{}
+	opts := []client.ListOption{client.MatchingLabels(label)}
+	if namespace != "" {
+		opts = append(opts, client.InNamespace(namespace))
+	}
+	if err := f.ListResourceContext(ctx, podList, opts...); err != nil {
+		return nil, err
+	}
+	// the order of the list is not stable
+	slices.SortFunc(podList.Items, func(a, b corev1.Pod) int {
+		return strings.Compare(a.Namespace+"/"+a.Name, b.Namespace+"/"+b.Name)
+	})
+	pods := []*corev1.Pod{}
+	for n := range podList.Items {
+		pod := &podList.Items[n]
+		if pod.DeletionTimestamp == nil && (match == nil || match(pod)) {
+			pods = append(pods, pod)
+		}
+	}
+	if len(pods) == 0 {
+		return nil, nil
+	}
+	return pods[rnd.Intn(len(pods))], nil
+}
+
+// PodKillAction deletes a random pod of the label, empty namespace means all namespaces
+func (f *Framework) PodKillAction(namespace string, label map[string]string, interval time.Duration, opts ...client.DeleteOption) ChaosAction {
+	return ChaosAction{
+		Name:     "pod-kill",
+		Interval: interval,
+		Inject: func(ctx context.Context, rnd *rand.Rand) (string, error) {
+			pod, err := f.randomPod(ctx, rnd, namespace, label, nil)
+			if err != nil || pod == nil {
+				return "", err
+			}
+			detail := fmt.Sprintf("pod %s/%s on node %s", pod.Namespace, pod.Name, pod.Spec.NodeName)
+			if err := f.DeleteResourceContext(ctx, pod, opts...); err != nil {
+				return detail, err
+			}
+			return detail, nil
+		},
+	}
+}
+
+// ContainerKillAction stops the container of a random running pod of the label by crictl on the node,
+// then kubelet restarts it. Empty container means a random one of the pod
+func (f *Framework) ContainerKillAction(namespace string, label map[string]string, container string, interval time.Duration) ChaosAction {
+	running := func(s corev1.ContainerStatus) bool {
+		return s.State.Running != nil && s.ContainerID != "" && (container == "" || s.Name == container)
+	}
+	return ChaosAction{
+		Name:     "container-kill",
+		Interval: interval,
+		Inject: func(ctx context.Context, rnd *rand.Rand) (string, error) {
+			pod, err := f.randomPod(ctx, rnd, namespace, label, func(pod *corev1.Pod) bool {
+				return pod.Spec.NodeName != "" && slices.ContainsFunc(pod.Status.ContainerStatuses, running)
+			})
+			if err != nil || pod == nil {
+				return "", err
+			}
+			statuses := []corev1.ContainerStatus{}
+			for _, s := range pod.Status.ContainerStatuses {
+				if running(s) {
+					statuses = append(statuses, s)
+				}
+			}
+			s := statuses[rnd.Intn(len(statuses))]
+			// like containerd://abc
+			_, id, _ := strings.Cut(s.ContainerID, "://")
+
+			detail := fmt.Sprintf("container %s of pod %s/%s on node %s", s.Name, pod.Namespace, pod.Name, pod.Spec.NodeName)
+			node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: pod.Spec.NodeName}}
+			if _, err := f.ExecOnNode(ctx, node, []string{"crictl", "stop", "--timeout", "0", id}); err != nil {
+				return detail, err
+			}
+			return detail, nil
+		},
+	}
+}
+
+// NodeRestartAction restarts the container of a random kind node, empty nodes means KindNodeList
+func (f *Framework) NodeRestartAction(nodes []string, interval time.Duration) ChaosAction {
+	return ChaosAction{
+		Name:     "node-restart",
+		Interval: interval,
+		Inject: func(ctx context.Context, rnd *rand.Rand) (string, error) {
+			candidates := nodes
+			if len(candidates) == 0 {
+				candidates = f.Info.KindNodeList
+			}
+			if len(candidates) == 0 {
+				return "", fmt.Errorf("%w: no kind node to restart", ErrWrongInput)
+			}
+			node := candidates[rnd.Intn(len(candidates))]
+			detail := "node " + node
+
+			runtime, err := f.ContainerRuntime()
+			if err != nil {
+				return detail, err
+			}
+			if err := runtime.Stop(ctx, node); err != nil {
+				return detail, err
+			}
+			// the node is always started again, or the rest of the spec runs without it
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), f.Config.ApiOperateTimeout)
+			defer cancel()
+			return detail, runtime.Start(ctx, node)
+		},
+	}
+}
+
+// DaemonSetRolloutAction restarts all the pods of the DaemonSet by the rolling update, like `kubectl rollout restart`
+func (f *Framework) DaemonSetRolloutAction(name, namespace string, interval time.Duration) ChaosAction {
+	return ChaosAction{
+		Name:     "daemonset-rollout",
+		Interval: interval,
+		Inject: func(ctx context.Context, rnd *rand.Rand) (string, error) {
+			detail := fmt.Sprintf("daemonset %s/%s", namespace, name)
+			ds, err := f.GetDaemonSetContext(ctx, name, namespace)
+			if err != nil {
+				return detail, err
+			}
+			patch := client.MergeFrom(ds.DeepCopy())
+			if ds.Spec.Template.Annotations == nil {
+				ds.Spec.Template.Annotations = map[string]string{}
+			}
+			ds.Spec.Template.Annotations[ChaosRestartedAtAnnotation] = time.Now().Format(time.RFC3339Nano)
+			return detail, f.PatchResourceContext(ctx, ds, patch)
+		},
+	}
+}
+
+// APIThrottleAction limits the requests of the service accounts like "kube-system/spiderpool-agent"
+// by the API Priority and Fairness for the hold time, the requests over the limit are rejected with 429.
+// It creates a FlowSchema and a PriorityLevelConfiguration, which are deleted when the hold time passes
+func (f *Framework) APIThrottleAction(serviceAccounts []string, hold, interval time.Duration) ChaosAction {
+	return ChaosAction{
+		Name:     "api-throttle",
+		Interval: interval,
+		Inject: func(ctx context.Context, rnd *rand.Rand) (string, error) {
+			if len(serviceAccounts) == 0 || hold <= 0 {
+				return "", ErrWrongInput
+			}
+			subjects := []flowcontrolv1.Subject{}
+			for _, sa := range serviceAccounts {
+				ns, name, ok := strings.Cut(sa, "/")
+				if !ok || ns == "" || name == "" {
+					return "", fmt.Errorf("%w: service account %q, expect namespace/name", ErrWrongInput, sa)
+				}
+				subjects = append(subjects, flowcontrolv1.Subject{
+					Kind:           flowcontrolv1.SubjectKindServiceAccount,
+					ServiceAccount: &flowcontrolv1.ServiceAccountSubject{Namespace: ns, Name: name},
+				})
+			}
+
+			name := "e2e-chaos-throttle-" + utilrand.String(5)
+			detail := fmt.Sprintf("%s for %v of %s", name, hold, strings.Join(serviceAccounts, ","))
+			level := &flowcontrolv1.PriorityLevelConfiguration{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Spec: flowcontrolv1.PriorityLevelConfigurationSpec{
+					Type: flowcontrolv1.PriorityLevelEnablementLimited,
+					Limited: &flowcontrolv1.LimitedPriorityLevelConfiguration{
+						NominalConcurrencyShares: ptr.To[int32](1),
+						LimitResponse:            flowcontrolv1.LimitResponse{Type: flowcontrolv1.LimitResponseTypeReject},
+					},
+				},
+			}
+			schema := &flowcontrolv1.FlowSchema{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Spec: flowcontrolv1.FlowSchemaSpec{
+					PriorityLevelConfiguration: flowcontrolv1.PriorityLevelConfigurationReference{Name: name},
+					// before the suggested schemas of the service accounts
+					MatchingPrecedence: 50,
+					Rules: []flowcontrolv1.PolicyRulesWithSubjects{{
+						Subjects: subjects,
+						ResourceRules: []flowcontrolv1.ResourcePolicyRule{{
+							Verbs:        []string{flowcontrolv1.VerbAll},
+							APIGroups:    []string{flowcontrolv1.APIGroupAll},
+							Resources:    []string{flowcontrolv1.ResourceAll},
+							ClusterScope: true,
+							Namespaces:   []string{flowcontrolv1.NamespaceEvery},
+						}},
+					}},
+				},
+			}
+
+			// the throttling is always removed, even ctx is done in the hold time
+			defer func() {
+				ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), f.Config.ApiOperateTimeout)
+				defer cancel()
+				for _, obj := range []client.Object{schema, level} {
+					if err := f.DeleteResourceContext(ctx, obj); err == nil {
+						f.Untrack(obj)
+					}
+				}
+			}()
+			for _, obj := range []client.Object{level, schema} {
+				if err := f.CreateResourceContext(ctx, obj); err != nil {
+					return detail, err
+				}
+			}
+			sleepContext(ctx, hold)
+			return detail, nil
+		},
+	}
+}
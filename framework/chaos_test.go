@@ -0,0 +1,169 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	e2e "github.com/spidernet-io/e2eframework/framework"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	flowcontrolv1 "k8s.io/api/flowcontrol/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func chaosPod(name, node string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kube-system", Labels: map[string]string{"app": "agent"}},
+		Spec:       corev1.PodSpec{NodeName: node},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+			Name:        "agent",
+			ContainerID: "containerd://" + name + "-id",
+			State:       corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+		}}},
+	}
+}
+
+var _ = Describe("test chaos", Label("chaos"), func() {
+	var f *e2e.Framework
+	var ctx context.Context
+
+	BeforeEach(func() {
+		fakeEnv("/tmp/nokubeconfigfile")
+		// only the types registered by the framework, like the real client
+		scheme, e := e2e.NewScheme(nil)
+		Expect(e).NotTo(HaveOccurred())
+		f, e = e2e.NewFramework(GinkgoT(), nil, fake.NewClientBuilder().WithScheme(scheme).Build())
+		Expect(e).NotTo(HaveOccurred())
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 20*time.Second)
+		DeferCleanup(cancel)
+	})
+
+	It("run the actions with the seed", func() {
+		pick := e2e.ChaosAction{
+			Name:     "pick",
+			Interval: 10 * time.Millisecond,
+			Inject: func(ctx context.Context, rnd *rand.Rand) (string, error) {
+				return fmt.Sprint(rnd.Intn(1000)), nil
+			},
+		}
+		details := func() []string {
+			timeline, e := f.NewChaos(e2e.ChaosOptions{Duration: 200 * time.Millisecond, Jitter: 0.5, Seed: 7}).Add(pick).Run(ctx)
+			Expect(e).NotTo(HaveOccurred())
+			Expect(len(timeline)).To(BeNumerically(">", 5))
+			r := []string{}
+			for _, event := range timeline[:5] {
+				r = append(r, event.Detail)
+			}
+			return r
+		}
+		Expect(details()).To(Equal(details()))
+
+		failed := errors.New("failed")
+		n := 0
+		fail := e2e.ChaosAction{
+			Name:     "fail",
+			Interval: 10 * time.Millisecond,
+			Inject: func(ctx context.Context, rnd *rand.Rand) (string, error) {
+				n++
+				return "", failed
+			},
+		}
+		c := f.NewChaos(e2e.ChaosOptions{Duration: 5 * time.Second, StopOnError: true}).Add(fail)
+		_, e := c.Run(ctx)
+		Expect(e).To(MatchError(failed))
+		Expect(n).To(Equal(1))
+
+		timeline, e := f.NewChaos(e2e.ChaosOptions{Duration: 100 * time.Millisecond}).Add(fail, pick).Run(ctx)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(len(timeline.Failed())).To(BeNumerically(">", 1))
+		Expect(timeline.String()).To(ContainSubstring("pick"))
+		Expect(timeline.Between(time.Now().Add(time.Hour), time.Now().Add(2*time.Hour))).To(BeEmpty())
+
+		_, e = f.NewChaos(e2e.ChaosOptions{}).Run(ctx)
+		Expect(e).To(MatchError(e2e.ErrWrongInput))
+		_, e = f.NewChaos(e2e.ChaosOptions{}).Add(e2e.ChaosAction{Name: "no-interval", Inject: pick.Inject}).Run(ctx)
+		Expect(e).To(MatchError(e2e.ErrWrongInput))
+	})
+
+	It("kill the pod and the container", func() {
+		runtime := e2e.NewFakeContainerRuntime("master", "worker")
+		f.Runtime = runtime
+		Expect(f.CreateResourceContext(ctx, chaosPod("agent-1", "worker"))).To(Succeed())
+		rnd := rand.New(rand.NewSource(1))
+
+		detail, e := f.ContainerKillAction("kube-system", map[string]string{"app": "agent"}, "", time.Second).Inject(ctx, rnd)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(detail).To(Equal("container agent of pod kube-system/agent-1 on node worker"))
+		Expect(runtime.Calls()).To(Equal([]string{"exec worker crictl stop --timeout 0 agent-1-id"}))
+
+		detail, e = f.PodKillAction("kube-system", map[string]string{"app": "agent"}, time.Second).Inject(ctx, rnd)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(detail).To(Equal("pod kube-system/agent-1 on node worker"))
+
+		// nothing to inject
+		detail, e = f.PodKillAction("kube-system", map[string]string{"app": "agent"}, time.Second).Inject(ctx, rnd)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(detail).To(BeEmpty())
+	})
+
+	It("restart the node and roll out the daemonset", func() {
+		runtime := e2e.NewFakeContainerRuntime("master", "worker")
+		f.Runtime = runtime
+		rnd := rand.New(rand.NewSource(1))
+
+		detail, e := f.NodeRestartAction([]string{"worker"}, time.Second).Inject(ctx, rnd)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(detail).To(Equal("node worker"))
+		Expect(runtime.Calls()).To(Equal([]string{"stop worker", "start worker"}))
+		_, e = f.NodeRestartAction([]string{"not-existed"}, time.Second).Inject(ctx, rnd)
+		Expect(e).To(MatchError(e2e.ErrNoContainer))
+
+		ds := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "kube-system"}}
+		Expect(f.CreateResourceContext(ctx, ds)).To(Succeed())
+		detail, e = f.DaemonSetRolloutAction("agent", "kube-system", time.Second).Inject(ctx, rnd)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(detail).To(Equal("daemonset kube-system/agent"))
+		ds, e = f.GetDaemonSetContext(ctx, "agent", "kube-system")
+		Expect(e).NotTo(HaveOccurred())
+		Expect(ds.Spec.Template.Annotations).To(HaveKey(e2e.ChaosRestartedAtAnnotation))
+	})
+
+	It("throttle the service account for the hold time", func() {
+		action := f.APIThrottleAction([]string{"kube-system/agent"}, 500*time.Millisecond, time.Second)
+		done := make(chan error)
+		go func() {
+			_, e := action.Inject(ctx, rand.New(rand.NewSource(1)))
+			done <- e
+		}()
+
+		schemas := &flowcontrolv1.FlowSchemaList{}
+		Eventually(func() int {
+			Expect(f.ListResourceContext(ctx, schemas)).To(Succeed())
+			return len(schemas.Items)
+		}).WithContext(ctx).Should(Equal(1))
+		Expect(schemas.Items[0].Spec.Rules[0].Subjects[0].ServiceAccount.Name).To(Equal("agent"))
+		Expect(f.TrackedObjects()).To(HaveLen(2))
+
+		Expect(<-done).To(Succeed())
+		Expect(f.ListResourceContext(ctx, schemas)).To(Succeed())
+		Expect(schemas.Items).To(BeEmpty())
+		levels := &flowcontrolv1.PriorityLevelConfigurationList{}
+		Expect(f.ListResourceContext(ctx, levels)).To(Succeed())
+		Expect(levels.Items).To(BeEmpty())
+		Expect(f.TrackedObjects()).To(BeEmpty())
+
+		_, e := f.APIThrottleAction([]string{"agent"}, time.Second, time.Second).Inject(ctx, nil)
+		Expect(e).To(MatchError(e2e.ErrWrongInput))
+	})
+})
@@ -0,0 +1,289 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensions_v1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	api_errors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// E2E_CLUSTER_PROBE is one of the ClusterProbe* mode
+	E2E_CLUSTER_PROBE = "E2E_CLUSTER_PROBE"
+
+	// ClusterProbeOff does not probe the cluster
+	ClusterProbeOff = "off"
+	// ClusterProbeWarn logs the mismatch between ClusterInfo and the cluster
+	ClusterProbeWarn = "warn"
+	// ClusterProbeError fails NewFramework when any mismatch is found
+	ClusterProbeError = "error"
+	// ClusterProbeOverride replaces ClusterInfo with the detected values
+	ClusterProbeOverride = "override"
+
+	MultusCRDName       = "network-attachment-definitions.k8s.cni.cncf.io"
+	SpiderpoolCRDName   = "spiderippools.spiderpool.spidernet.io"
+	WhereaboutsCRDName  = "ippools.whereabouts.cni.cncf.io"
+	kubeadmConfigMap    = "kubeadm-config"
+	kubeadmConfigKey    = "ClusterConfiguration"
+	kubeSystemNamespace = "kube-system"
+	kindProviderPrefix  = "kind://"
+)
+
+// DetectedClusterInfo is what ProbeClusterInfo finds in the live cluster
+type DetectedClusterInfo struct {
+	// false when neither node pod CIDRs nor kubeadm-config are found, so the ip families are unknown
+	IpFamilyDetected bool
+	IpV4Enabled      bool
+	IpV6Enabled      bool
+	// the ip families of the service CIDRs, false for ServiceIpFamilyDetected when the service CIDRs are unknown
+	ServiceIpFamilyDetected bool
+	ServiceIpV4Enabled      bool
+	ServiceIpV6Enabled      bool
+	PodCIDRs                []string
+	ServiceCIDRs            []string
+	MultusEnabled           bool
+	SpiderIPAMEnabled       bool
+	WhereaboutIPAMEnabled   bool
+	// the names of all the nodes
+	NodeList []string
+	// the nodes of kind, found by the provider ID like "kind://docker/cluster/node", empty for the cluster not of kind
+	KindNodeList []string
+}
+
+// ClusterInfoMismatch is a field of ClusterInfo which disagrees with the live cluster
+type ClusterInfoMismatch struct {
+	Field    string
+	Declared string
+	Detected string
+}
+
+func (m ClusterInfoMismatch) String() string {
+	return fmt.Sprintf("%v: declared %v, but detected %v", m.Field, m.Declared, m.Detected)
+}
+
+// ProbeClusterInfo reads ip families from the node pod CIDRs and the kubeadm-config of kube-system,
+// the ip families of the pod CIDRs are preferred, and the ones of the service CIDRs are used without pod CIDRs,
+// checks the installed CRDs of multus, spiderpool and whereabouts, and lists the node names and the kind nodes
+func (f *Framework) ProbeClusterInfo(ctx context.Context) (*DetectedClusterInfo, error) {
+	d := &DetectedClusterInfo{}
+
+	nodes := &corev1.NodeList{}
+	if err := f.KClient.List(ctx, nodes); err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	for _, node := range nodes.Items {
+		d.NodeList = append(d.NodeList, node.Name)
+		if strings.HasPrefix(node.Spec.ProviderID, kindProviderPrefix) {
+			d.KindNodeList = append(d.KindNodeList, node.Name)
+		}
+		d.PodCIDRs = appendUnique(d.PodCIDRs, node.Spec.PodCIDRs...)
+		if node.Spec.PodCIDR != "" {
+			d.PodCIDRs = appendUnique(d.PodCIDRs, node.Spec.PodCIDR)
+		}
+	}
+	sort.Strings(d.NodeList)
+	sort.Strings(d.KindNodeList)
+
+	podSubnet, serviceSubnet, err := f.getKubeadmSubnets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(d.PodCIDRs) == 0 {
+		d.PodCIDRs = podSubnet
+	}
+	d.ServiceCIDRs = serviceSubnet
+
+	d.IpFamilyDetected, d.IpV4Enabled, d.IpV6Enabled = f.cidrFamilies("pod", d.PodCIDRs)
+	d.ServiceIpFamilyDetected, d.ServiceIpV4Enabled, d.ServiceIpV6Enabled = f.cidrFamilies("service", d.ServiceCIDRs)
+	if !d.IpFamilyDetected && d.ServiceIpFamilyDetected {
+		d.IpFamilyDetected, d.IpV4Enabled, d.IpV6Enabled = true, d.ServiceIpV4Enabled, d.ServiceIpV6Enabled
+	}
+
+	if d.MultusEnabled, err = f.CheckCRDInstalled(ctx, MultusCRDName); err != nil {
+		return nil, err
+	}
+	if d.SpiderIPAMEnabled, err = f.CheckCRDInstalled(ctx, SpiderpoolCRDName); err != nil {
+		return nil, err
+	}
+	if d.WhereaboutIPAMEnabled, err = f.CheckCRDInstalled(ctx, WhereaboutsCRDName); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (f *Framework) CheckCRDInstalled(ctx context.Context, crdName string) (bool, error) {
+	if crdName == "" {
+		return false, ErrWrongInput
+	}
+	crd := &apiextensions_v1.CustomResourceDefinition{}
+	err := f.KClient.Get(ctx, client.ObjectKey{Name: crdName}, crd)
+	if api_errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get crd %v: %w", crdName, err)
+	}
+	return true, nil
+}
+
+// getKubeadmSubnets returns nothing for the cluster not set up by kubeadm
+func (f *Framework) getKubeadmSubnets(ctx context.Context) (podSubnet, serviceSubnet []string, err error) {
+	cm := &corev1.ConfigMap{}
+	e := f.KClient.Get(ctx, client.ObjectKey{Namespace: kubeSystemNamespace, Name: kubeadmConfigMap}, cm)
+	if api_errors.IsNotFound(e) {
+		return nil, nil, nil
+	}
+	if e != nil {
+		return nil, nil, fmt.Errorf("failed to get configmap %v/%v: %w", kubeSystemNamespace, kubeadmConfigMap, e)
+	}
+
+	c := struct {
+		Networking struct {
+			PodSubnet     string `json:"podSubnet"`
+			ServiceSubnet string `json:"serviceSubnet"`
+		} `json:"networking"`
+	}{}
+	if e := yaml.Unmarshal([]byte(cm.Data[kubeadmConfigKey]), &c); e != nil {
+		return nil, nil, fmt.Errorf("failed to decode %v of configmap %v/%v: %w", kubeadmConfigKey, kubeSystemNamespace, kubeadmConfigMap, e)
+	}
+	return splitCIDRs(c.Networking.PodSubnet), splitCIDRs(c.Networking.ServiceSubnet), nil
+}
+
+func splitCIDRs(s string) []string {
+	var r []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			r = append(r, v)
+		}
+	}
+	return r
+}
+
+// cidrFamilies returns whether any valid cidr is found, and the ip families of them
+func (f *Framework) cidrFamilies(kind string, cidrs []string) (detected, v4, v6 bool) {
+	for _, cidr := range cidrs {
+		ip, _, err := net.ParseCIDR(cidr)
+		if err != nil {
+			f.Log("ignore invalid %v cidr %v: %v \n", kind, cidr, err)
+			continue
+		}
+		detected = true
+		if ip.To4() != nil {
+			v4 = true
+		} else {
+			v6 = true
+		}
+	}
+	return
+}
+
+func appendUnique(list []string, items ...string) []string {
+OUTER:
+	for _, item := range items {
+		for _, v := range list {
+			if v == item {
+				continue OUTER
+			}
+		}
+		list = append(list, item)
+	}
+	return list
+}
+
+// CompareClusterInfo returns the fields of info which disagree with d
+func CompareClusterInfo(info *ClusterInfo, d *DetectedClusterInfo) []ClusterInfoMismatch {
+	var r []ClusterInfoMismatch
+	check := func(field string, declared, detected bool) {
+		if declared != detected {
+			r = append(r, ClusterInfoMismatch{Field: field, Declared: fmt.Sprint(declared), Detected: fmt.Sprint(detected)})
+		}
+	}
+
+	if d.IpFamilyDetected {
+		check("IpV4Enabled", info.IpV4Enabled, d.IpV4Enabled)
+		check("IpV6Enabled", info.IpV6Enabled, d.IpV6Enabled)
+	}
+	// the service CIDRs should be of the same ip families as the pod CIDRs
+	if d.ServiceIpFamilyDetected {
+		check("ServiceIpV4Enabled", info.IpV4Enabled, d.ServiceIpV4Enabled)
+		check("ServiceIpV6Enabled", info.IpV6Enabled, d.ServiceIpV6Enabled)
+	}
+	check("MultusEnabled", info.MultusEnabled, d.MultusEnabled)
+	check("SpiderIPAMEnabled", info.SpiderIPAMEnabled, d.SpiderIPAMEnabled)
+	check("WhereaboutIPAMEnabled", info.WhereaboutIPAMEnabled, d.WhereaboutIPAMEnabled)
+
+	// KindNodeList could be part of the kind nodes, so only the node not of kind is the mismatch
+	if len(d.KindNodeList) > 0 && len(info.KindNodeList) > 0 {
+		declared := append([]string{}, info.KindNodeList...)
+		sort.Strings(declared)
+		for _, name := range declared {
+			if !slices.Contains(d.KindNodeList, name) {
+				r = append(r, ClusterInfoMismatch{Field: "KindNodeList", Declared: fmt.Sprint(declared), Detected: fmt.Sprint(d.KindNodeList)})
+				break
+			}
+		}
+	}
+	return r
+}
+
+// ApplyTo overrides the fields of info with the detected values, KindNodeList is set only for the cluster of kind
+func (d *DetectedClusterInfo) ApplyTo(info *ClusterInfo) {
+	if d.IpFamilyDetected {
+		info.IpV4Enabled = d.IpV4Enabled
+		info.IpV6Enabled = d.IpV6Enabled
+	}
+	info.MultusEnabled = d.MultusEnabled
+	info.SpiderIPAMEnabled = d.SpiderIPAMEnabled
+	info.WhereaboutIPAMEnabled = d.WhereaboutIPAMEnabled
+	if len(d.KindNodeList) > 0 {
+		info.KindNodeList = append([]string{}, d.KindNodeList...)
+		info.KindNodeListRaw = strings.Join(d.KindNodeList, ",")
+	}
+}
+
+// CheckClusterInfo probes the cluster and handles the mismatch of f.Info according to mode
+func (f *Framework) CheckClusterInfo(ctx context.Context, mode string) error {
+	switch mode {
+	case "", ClusterProbeOff:
+		return nil
+	case ClusterProbeWarn, ClusterProbeError, ClusterProbeOverride:
+	default:
+		return fmt.Errorf("%w: unknown cluster probe mode %q", ErrWrongInput, mode)
+	}
+
+	d, err := f.ProbeClusterInfo(ctx)
+	if err != nil {
+		return err
+	}
+	f.Log("detected ClusterInfo: %+v \n", *d)
+
+	mismatch := CompareClusterInfo(&f.Info, d)
+	if len(mismatch) == 0 {
+		return nil
+	}
+	var msg []string
+	for _, m := range mismatch {
+		msg = append(msg, m.String())
+	}
+
+	switch mode {
+	case ClusterProbeError:
+		return fmt.Errorf("ClusterInfo disagrees with the cluster: %v", strings.Join(msg, "; "))
+	case ClusterProbeOverride:
+		f.Log("warning, override ClusterInfo with the detected values: %v \n", strings.Join(msg, "; "))
+		d.ApplyTo(&f.Info)
+	default:
+		f.Log("warning, ClusterInfo disagrees with the cluster: %v \n", strings.Join(msg, "; "))
+	}
+	return nil
+}
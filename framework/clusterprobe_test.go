@@ -0,0 +1,158 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	e2e "github.com/spidernet-io/e2eframework/framework"
+	corev1 "k8s.io/api/core/v1"
+	apiextensions_v1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("test cluster probe", Label("clusterprobe"), func() {
+	var f *e2e.Framework
+	var ctx context.Context
+
+	BeforeEach(func() {
+		f = fakeFramework()
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+		DeferCleanup(cancel)
+
+		for _, name := range []string{"worker", "master"} {
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+			}
+			Expect(f.CreateResource(node)).To(Succeed())
+		}
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "kubeadm-config", Namespace: "kube-system"},
+			Data: map[string]string{
+				"ClusterConfiguration": "networking:\n  podSubnet: 172.40.0.0/16,fd40::/48\n  serviceSubnet: 172.41.0.0/16,fd41::/108\n",
+			},
+		}
+		Expect(f.CreateResource(cm)).To(Succeed())
+		crd := &apiextensions_v1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: e2e.MultusCRDName},
+		}
+		Expect(f.CreateResource(crd)).To(Succeed())
+	})
+
+	It("probe the cluster", func() {
+		d, e := f.ProbeClusterInfo(ctx)
+		Expect(e).NotTo(HaveOccurred())
+		GinkgoWriter.Printf("detected: %+v \n", *d)
+
+		Expect(d.IpFamilyDetected).To(BeTrue())
+		Expect(d.IpV4Enabled).To(BeTrue())
+		Expect(d.IpV6Enabled).To(BeTrue())
+		Expect(d.ServiceCIDRs).To(Equal([]string{"172.41.0.0/16", "fd41::/108"}))
+		Expect(d.ServiceIpFamilyDetected).To(BeTrue())
+		Expect(d.ServiceIpV4Enabled).To(BeTrue())
+		Expect(d.ServiceIpV6Enabled).To(BeTrue())
+		Expect(d.MultusEnabled).To(BeTrue())
+		Expect(d.SpiderIPAMEnabled).To(BeFalse())
+		Expect(d.WhereaboutIPAMEnabled).To(BeFalse())
+		Expect(d.NodeList).To(Equal([]string{"master", "worker"}))
+		Expect(d.KindNodeList).To(BeEmpty())
+
+		// fakeEnv declares spiderpool enabled
+		mismatch := e2e.CompareClusterInfo(&f.Info, d)
+		Expect(mismatch).To(HaveLen(1))
+		Expect(mismatch[0].Field).To(Equal("SpiderIPAMEnabled"))
+	})
+
+	It("prefer the node pod cidr", func() {
+		node := &corev1.Node{}
+		Expect(f.GetResource(client.ObjectKey{Name: "master"}, node)).To(Succeed())
+		node.Spec.PodCIDRs = []string{"fd00::/64"}
+		Expect(f.UpdateResource(node)).To(Succeed())
+
+		d, e := f.ProbeClusterInfo(ctx)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(d.PodCIDRs).To(Equal([]string{"fd00::/64"}))
+		Expect(d.IpV4Enabled).To(BeFalse())
+		Expect(d.IpV6Enabled).To(BeTrue())
+	})
+
+	It("detect the ip families of the service cidr", func() {
+		cm := &corev1.ConfigMap{}
+		Expect(f.GetResource(client.ObjectKey{Name: "kubeadm-config", Namespace: "kube-system"}, cm)).To(Succeed())
+		cm.Data["ClusterConfiguration"] = "networking:\n  podSubnet: 172.40.0.0/16,fd40::/48\n  serviceSubnet: 172.41.0.0/16\n"
+		Expect(f.UpdateResource(cm)).To(Succeed())
+
+		d, e := f.ProbeClusterInfo(ctx)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(d.IpV6Enabled).To(BeTrue())
+		Expect(d.ServiceIpV4Enabled).To(BeTrue())
+		Expect(d.ServiceIpV6Enabled).To(BeFalse())
+		info := f.Info
+		info.SpiderIPAMEnabled = false
+		mismatch := e2e.CompareClusterInfo(&info, d)
+		Expect(mismatch).To(HaveLen(1))
+		Expect(mismatch[0].Field).To(Equal("ServiceIpV6Enabled"))
+
+		// the service cidr tells the ip families without the pod cidr
+		cm.Data["ClusterConfiguration"] = "networking:\n  serviceSubnet: fd41::/108\n"
+		Expect(f.UpdateResource(cm)).To(Succeed())
+		d, e = f.ProbeClusterInfo(ctx)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(d.PodCIDRs).To(BeEmpty())
+		Expect(d.IpFamilyDetected).To(BeTrue())
+		Expect(d.IpV4Enabled).To(BeFalse())
+		Expect(d.IpV6Enabled).To(BeTrue())
+	})
+
+	It("handle the mismatch by mode", func() {
+		Expect(f.CheckClusterInfo(ctx, e2e.ClusterProbeOff)).To(Succeed())
+		Expect(f.CheckClusterInfo(ctx, e2e.ClusterProbeWarn)).To(Succeed())
+		Expect(f.Info.SpiderIPAMEnabled).To(BeTrue())
+		Expect(f.CheckClusterInfo(ctx, e2e.ClusterProbeError)).NotTo(Succeed())
+		Expect(f.CheckClusterInfo(ctx, "unknown")).To(MatchError(e2e.ErrWrongInput))
+
+		Expect(f.CheckClusterInfo(ctx, e2e.ClusterProbeOverride)).To(Succeed())
+		Expect(f.Info.SpiderIPAMEnabled).To(BeFalse())
+		Expect(f.CheckClusterInfo(ctx, e2e.ClusterProbeError)).To(Succeed())
+	})
+
+	It("compare the kind nodes only for the cluster of kind", func() {
+		node := &corev1.Node{}
+		Expect(f.GetResource(client.ObjectKey{Name: "worker"}, node)).To(Succeed())
+		node.Spec.ProviderID = "kind://docker/e2e/worker"
+		Expect(f.UpdateResource(node)).To(Succeed())
+		Expect(f.CreateResource(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "vm"}})).To(Succeed())
+
+		d, e := f.ProbeClusterInfo(ctx)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(d.NodeList).To(Equal([]string{"master", "vm", "worker"}))
+		Expect(d.KindNodeList).To(Equal([]string{"worker"}))
+
+		// part of the kind nodes is fine, but the node not of kind is the mismatch
+		info := f.Info
+		info.SpiderIPAMEnabled = false
+		info.KindNodeList = []string{"worker"}
+		Expect(e2e.CompareClusterInfo(&info, d)).To(BeEmpty())
+		info.KindNodeList = []string{"worker", "vm"}
+		mismatch := e2e.CompareClusterInfo(&info, d)
+		Expect(mismatch).To(HaveLen(1))
+		Expect(mismatch[0].Field).To(Equal("KindNodeList"))
+
+		d.ApplyTo(&info)
+		Expect(info.KindNodeList).To(Equal([]string{"worker"}))
+		Expect(info.KindNodeListRaw).To(Equal("worker"))
+
+		// the cluster not of kind keeps KindNodeList
+		d.KindNodeList = nil
+		info.KindNodeList = []string{"master"}
+		Expect(e2e.CompareClusterInfo(&info, d)).To(BeEmpty())
+		d.ApplyTo(&info)
+		Expect(info.KindNodeList).To(Equal([]string{"master"}))
+	})
+})
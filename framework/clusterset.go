@@ -0,0 +1,180 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ClusterSet holds one named Framework for each cluster, for the cross-cluster test
+type ClusterSet struct {
+	clusters map[string]*Framework
+	names    []string
+}
+
+// ClusterSetError holds the error of each failed cluster
+type ClusterSetError map[string]error
+
+func (e ClusterSetError) Error() string {
+	names := make([]string, 0, len(e))
+	for k := range e {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	msg := make([]string, 0, len(names))
+	for _, k := range names {
+		msg = append(msg, fmt.Sprintf("cluster %v: %v", k, e[k]))
+	}
+	return strings.Join(msg, "; ")
+}
+
+// NewClusterSet builds a Framework for each ClusterInfo, keyed by the ClusterName.
+// Clusters may share one KubeConfigPath with different KubeContext
+func NewClusterSet(t TestingT, infos []ClusterInfo, schemeRegisterList []func(*runtime.Scheme) error) (*ClusterSet, error) {
+	if t == nil || len(infos) == 0 {
+		return nil, ErrWrongInput
+	}
+	cs := &ClusterSet{}
+	for _, info := range infos {
+		if err := info.Validate(); err != nil {
+			return nil, fmt.Errorf("cluster %v: %w", info.ClusterName, err)
+		}
+		f, err := NewFrameworkWithClusterInfo(t, info, schemeRegisterList)
+		if err != nil {
+			return nil, fmt.Errorf("cluster %v: %w", info.ClusterName, err)
+		}
+		if err := cs.Add(f); err != nil {
+			return nil, err
+		}
+	}
+	return cs, nil
+}
+
+// Add puts an existing Framework into the set, keyed by f.Info.ClusterName
+func (cs *ClusterSet) Add(f *Framework) error {
+	if f == nil || f.Info.ClusterName == "" {
+		return ErrWrongInput
+	}
+	if cs.clusters == nil {
+		cs.clusters = map[string]*Framework{}
+	}
+	if _, ok := cs.clusters[f.Info.ClusterName]; ok {
+		return fmt.Errorf("%w: cluster %v", ErrAlreadyExisted, f.Info.ClusterName)
+	}
+	cs.clusters[f.Info.ClusterName] = f
+	cs.names = append(cs.names, f.Info.ClusterName)
+	return nil
+}
+
+// Get returns nil when the cluster is not in the set
+func (cs *ClusterSet) Get(name string) *Framework {
+	return cs.clusters[name]
+}
+
+// Names returns the cluster names in the order of adding
+func (cs *ClusterSet) Names() []string {
+	return append([]string{}, cs.names...)
+}
+
+// ForEach runs fn on all clusters in parallel, and returns a ClusterSetError for the failed clusters
+func (cs *ClusterSet) ForEach(ctx context.Context, fn func(ctx context.Context, name string, f *Framework) error) error {
+	if fn == nil {
+		return ErrWrongInput
+	}
+	errs := ClusterSetError{}
+	lock := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for _, name := range cs.names {
+		wg.Add(1)
+		go func(name string, f *Framework) {
+			defer wg.Done()
+			if err := fn(ctx, name, f); err != nil {
+				lock.Lock()
+				errs[name] = err
+				lock.Unlock()
+			}
+		}(name, cs.clusters[name])
+	}
+	wg.Wait()
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// ForEachPair runs fn on every ordered pair of different clusters in parallel, like the cross-cluster reachability.
+// The error is keyed by "src->dst"
+func (cs *ClusterSet) ForEachPair(ctx context.Context, fn func(ctx context.Context, src, dst *Framework) error) error {
+	if fn == nil {
+		return ErrWrongInput
+	}
+	errs := ClusterSetError{}
+	lock := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for _, src := range cs.names {
+		for _, dst := range cs.names {
+			if src == dst {
+				continue
+			}
+			wg.Add(1)
+			go func(src, dst string) {
+				defer wg.Done()
+				if err := fn(ctx, cs.clusters[src], cs.clusters[dst]); err != nil {
+					lock.Lock()
+					errs[src+"->"+dst] = err
+					lock.Unlock()
+				}
+			}(src, dst)
+		}
+	}
+	wg.Wait()
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// CollectClusterSet runs fn on all clusters in parallel and gathers the value of each succeeded cluster
+func CollectClusterSet[T any](ctx context.Context, cs *ClusterSet, fn func(ctx context.Context, name string, f *Framework) (T, error)) (map[string]T, error) {
+	if cs == nil || fn == nil {
+		return nil, ErrWrongInput
+	}
+	result := map[string]T{}
+	lock := sync.Mutex{}
+	err := cs.ForEach(ctx, func(ctx context.Context, name string, f *Framework) error {
+		v, err := fn(ctx, name, f)
+		if err != nil {
+			return err
+		}
+		lock.Lock()
+		result[name] = v
+		lock.Unlock()
+		return nil
+	})
+	return result, err
+}
+
+// CheckConsistent succeeds when fn returns the same value for all clusters, like the same spiderpool version
+func (cs *ClusterSet) CheckConsistent(ctx context.Context, fn func(ctx context.Context, name string, f *Framework) (string, error)) (string, error) {
+	result, err := CollectClusterSet(ctx, cs, fn)
+	if err != nil {
+		return "", err
+	}
+	value := ""
+	for n, name := range cs.names {
+		if n == 0 {
+			value = result[name]
+			continue
+		}
+		if result[name] != value {
+			return "", fmt.Errorf("clusters are not consistent: %v", result)
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,162 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	e2e "github.com/spidernet-io/e2eframework/framework"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const twoContextKubeConfig = `
+apiVersion: v1
+kind: Config
+clusters:
+- name: kind-a
+  cluster:
+    server: https://127.0.0.1:6443
+- name: kind-b
+  cluster:
+    server: https://127.0.0.1:6444
+contexts:
+- name: kind-a
+  context:
+    cluster: kind-a
+    user: admin
+- name: kind-b
+  context:
+    cluster: kind-b
+    user: admin
+current-context: kind-a
+users:
+- name: admin
+  user:
+    token: fake
+`
+
+var _ = Describe("test cluster set", Label("clusterset"), func() {
+	var cs *e2e.ClusterSet
+	var ctx context.Context
+
+	BeforeEach(func() {
+		// make sure ClusterInformation is loaded
+		fakeFramework()
+		cs = &e2e.ClusterSet{}
+		for _, name := range []string{"cluster-a", "cluster-b"} {
+			info := *e2e.ClusterInformation
+			info.ClusterName = name
+			f, e := e2e.NewFrameworkWithClusterInfo(GinkgoT(), info, nil, fakeClientSet())
+			Expect(e).NotTo(HaveOccurred())
+			Expect(cs.Add(f)).To(Succeed())
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+		DeferCleanup(cancel)
+	})
+
+	It("operate all clusters", func() {
+		Expect(cs.Names()).To(Equal([]string{"cluster-a", "cluster-b"}))
+		Expect(cs.Get("cluster-a")).NotTo(BeNil())
+		Expect(cs.Get("cluster-c")).To(BeNil())
+		Expect(cs.Add(cs.Get("cluster-a"))).To(MatchError(e2e.ErrAlreadyExisted))
+
+		e := cs.ForEach(ctx, func(ctx context.Context, name string, f *e2e.Framework) error {
+			return f.CreateNamespace("ns-" + name)
+		})
+		Expect(e).NotTo(HaveOccurred())
+
+		_, e = cs.Get("cluster-a").GetNamespace("ns-cluster-a")
+		Expect(e).NotTo(HaveOccurred())
+		_, e = cs.Get("cluster-a").GetNamespace("ns-cluster-b")
+		Expect(e).To(HaveOccurred())
+
+		e = cs.ForEach(ctx, func(ctx context.Context, name string, f *e2e.Framework) error {
+			if name == "cluster-b" {
+				return fmt.Errorf("failed")
+			}
+			return nil
+		})
+		var clusterErr e2e.ClusterSetError
+		Expect(errors.As(e, &clusterErr)).To(BeTrue())
+		Expect(clusterErr).To(HaveLen(1))
+		Expect(clusterErr).To(HaveKey("cluster-b"))
+	})
+
+	It("check across clusters", func() {
+		getVersion := func(ctx context.Context, name string, f *e2e.Framework) (string, error) {
+			cm, err := f.GetConfigmap("version", "default")
+			if err != nil {
+				return "", err
+			}
+			return cm.Data["version"], nil
+		}
+		createVersion := func(f *e2e.Framework, version string) {
+			cm := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "version", Namespace: "default"},
+				Data:       map[string]string{"version": version},
+			}
+			Expect(f.CreateConfigmap(cm)).To(Succeed())
+		}
+
+		_, e := cs.CheckConsistent(ctx, getVersion)
+		Expect(e).To(HaveOccurred())
+
+		createVersion(cs.Get("cluster-a"), "v1.0.0")
+		createVersion(cs.Get("cluster-b"), "v1.0.0")
+		v, e := cs.CheckConsistent(ctx, getVersion)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(v).To(Equal("v1.0.0"))
+
+		Expect(cs.Get("cluster-b").DeleteConfigmap("version", "default")).To(Succeed())
+		createVersion(cs.Get("cluster-b"), "v1.1.0")
+		_, e = cs.CheckConsistent(ctx, getVersion)
+		Expect(e).To(HaveOccurred())
+
+		pairs := []string{}
+		lock := sync.Mutex{}
+		e = cs.ForEachPair(ctx, func(ctx context.Context, src, dst *e2e.Framework) error {
+			lock.Lock()
+			defer lock.Unlock()
+			pairs = append(pairs, src.Info.ClusterName+"->"+dst.Info.ClusterName)
+			return nil
+		})
+		Expect(e).NotTo(HaveOccurred())
+		Expect(pairs).To(ConsistOf("cluster-a->cluster-b", "cluster-b->cluster-a"))
+	})
+
+	It("build clusters from kubeconfig contexts", func() {
+		file := fakeKubeConfig()
+		DeferCleanup(func() {
+			os.Remove(file.Name())
+		})
+		_, e := file.WriteString(twoContextKubeConfig)
+		Expect(e).NotTo(HaveOccurred())
+
+		infos := []e2e.ClusterInfo{}
+		for _, name := range []string{"kind-a", "kind-b"} {
+			info := *e2e.ClusterInformation
+			info.ClusterName = name
+			info.KubeConfigPath = file.Name()
+			info.KubeContext = name
+			infos = append(infos, info)
+		}
+		set, e := e2e.NewClusterSet(GinkgoT(), infos, nil)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(set.Get("kind-a").KConfig.Host).To(Equal("https://127.0.0.1:6443"))
+		Expect(set.Get("kind-b").KConfig.Host).To(Equal("https://127.0.0.1:6444"))
+
+		infos[1].KubeContext = "not-existed"
+		_, e = e2e.NewClusterSet(GinkgoT(), infos, nil)
+		Expect(e).To(HaveOccurred())
+	})
+})
@@ -3,39 +3,77 @@
 package framework
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"os/exec"
 )
 
 // operate node container, like shutdown, ssh to login
 func (f *Framework) ExecKubectl(command string, ctx context.Context) ([]byte, error) {
+	return f.ExecKubectlContext(ctx, command)
+}
+
+func (f *Framework) ExecKubectlContext(ctx context.Context, command string) ([]byte, error) {
 	args := fmt.Sprintf("kubectl --kubeconfig %s %s", f.Info.KubeConfigPath, command)
 	return exec.CommandContext(ctx, "sh", "-c", args).CombinedOutput()
 }
 
+// ExecCommandInPod needs the kubectl binary and mixes the stdout and stderr, ExecInPod runs over the API server instead
 func (f *Framework) ExecCommandInPod(podName, nameSpace, command string, ctx context.Context) ([]byte, error) {
+	return f.ExecCommandInPodContext(ctx, podName, nameSpace, command)
+}
+
+func (f *Framework) ExecCommandInPodContext(ctx context.Context, podName, nameSpace, command string) ([]byte, error) {
 	command = fmt.Sprintf("exec %s -n %s -- %s", podName, nameSpace, command)
-	return f.ExecKubectl(command, ctx)
+	return f.ExecKubectlContext(ctx, command)
 }
 
-// DockerExecCommand is eq to `docker exec $containerId $command `
+// runBinary runs the binary without the shell in dir, and returns the *ExecExitError for the non-zero exit code,
+// name is the command name in the error
+func runBinary(ctx context.Context, dir, name, binary string, args ...string) ([]byte, []byte, error) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Dir = dir
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && ctx.Err() == nil {
+		err = &ExecExitError{
+			Command: append([]string{name}, args...),
+			Code:    exitErr.ExitCode(),
+			Stderr:  stderr.Bytes(),
+		}
+	}
+	return stdout.Bytes(), stderr.Bytes(), err
+}
+
+// DockerExecCommand is eq to `docker exec $containerId $command `, the binary is the one of ContainerRuntime().
+//
+// Deprecated: the command is run by the shell, use ContainerRuntime().Exec instead
 func (f *Framework) DockerExecCommand(ctx context.Context, containerId string, command string) ([]byte, error) {
-	fullCommand := fmt.Sprintf("docker exec -i %s %s ", containerId, command)
+	fullCommand := fmt.Sprintf("%s exec -i %s %s ", f.runtimeBinary(), containerId, command)
 	f.t.Logf(fullCommand)
 	return exec.CommandContext(ctx, "/bin/sh", "-c", fullCommand).CombinedOutput()
 }
 
-// DockerRunCommand eq to `docker run $command`
+// DockerRunCommand eq to `docker run $command`.
+//
+// Deprecated: the command is run by the shell, use ContainerRuntime().Run instead
 func (f *Framework) DockerRunCommand(ctx context.Context, command string) ([]byte, error) {
-	fullCommand := fmt.Sprintf("docker run %s ", command)
+	fullCommand := fmt.Sprintf("%s run %s ", f.runtimeBinary(), command)
 	f.t.Logf(fullCommand)
 	return exec.CommandContext(ctx, "/bin/sh", "-c", fullCommand).CombinedOutput()
 }
 
-// DockerRMCommand is eq to `docker rm $containerId`
+// DockerRMCommand is eq to `docker rm $containerId`.
+//
+// Deprecated: the command is run by the shell, use ContainerRuntime().Remove instead
 func (f *Framework) DockerRMCommand(ctx context.Context, containerId string) ([]byte, error) {
-	fullCommand := fmt.Sprintf("docker rm -f %s", containerId)
+	fullCommand := fmt.Sprintf("%s rm -f %s", f.runtimeBinary(), containerId)
 	f.t.Logf(fullCommand)
-	return exec.CommandContext(ctx, "/bin/bash", "-c", fullCommand).CombinedOutput()
+	return exec.CommandContext(ctx, "/bin/sh", "-c", fullCommand).CombinedOutput()
 }
@@ -0,0 +1,279 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// E2E_CONFIG_FILE points to a yaml/json file in the ClusterConfigFile format
+	E2E_CONFIG_FILE = "E2E_CONFIG_FILE"
+	// E2E_CONFIG_PROFILE selects one of the profiles of E2E_CONFIG_FILE
+	E2E_CONFIG_PROFILE = "E2E_CONFIG_PROFILE"
+
+	ClusterConfigAPIVersion = "e2eframework.spidernet.io/v1alpha1"
+	ClusterConfigKind       = "ClusterConfig"
+)
+
+// ClusterConfigFile is the versioned config file for ClusterInfo and FConfig.
+// The top level Cluster and Framework are shared by all profiles,
+// the selected profile overrides them, and E2E_* ENV override both
+type ClusterConfigFile struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// the profile used when E2E_CONFIG_PROFILE is empty
+	Profile   string                    `json:"profile,omitempty"`
+	Cluster   ClusterConfig             `json:"cluster,omitempty"`
+	Framework FrameworkConfig           `json:"framework,omitempty"`
+	Profiles  map[string]ClusterProfile `json:"profiles,omitempty"`
+}
+
+type ClusterProfile struct {
+	Cluster   ClusterConfig   `json:"cluster,omitempty"`
+	Framework FrameworkConfig `json:"framework,omitempty"`
+}
+
+// ClusterConfig is the file form of ClusterInfo, nil means not set
+type ClusterConfig struct {
+	ClusterName           *string  `json:"clusterName,omitempty"`
+	KubeConfigPath        *string  `json:"kubeConfigPath,omitempty"`
+	KubeContext           *string  `json:"kubeContext,omitempty"`
+	IpV4Enabled           *bool    `json:"ipv4Enabled,omitempty"`
+	IpV6Enabled           *bool    `json:"ipv6Enabled,omitempty"`
+	MultusEnabled         *bool    `json:"multusEnabled,omitempty"`
+	SpiderIPAMEnabled     *bool    `json:"spiderIPAMEnabled,omitempty"`
+	WhereaboutIPAMEnabled *bool    `json:"whereaboutIPAMEnabled,omitempty"`
+	SpiderSubnetEnabled   *bool    `json:"spiderSubnetEnabled,omitempty"`
+	KindNodeList          []string `json:"kindNodeList,omitempty"`
+	MultusDefaultCni      *string  `json:"multusDefaultCni,omitempty"`
+	MultusAdditionalCni   *string  `json:"multusAdditionalCni,omitempty"`
+	VagrantDir            *string  `json:"vagrantDir,omitempty"`
+	// VM name to node name
+	VagrantNodeMap map[string]string `json:"vagrantNodeMap,omitempty"`
+}
+
+// FrameworkConfig is the file form of FConfig, nil means not set
+type FrameworkConfig struct {
+	ApiOperateTimeout     *metav1.Duration `json:"apiOperateTimeout,omitempty"`
+	ResourceDeleteTimeout *metav1.Duration `json:"resourceDeleteTimeout,omitempty"`
+	ClusterProbe          *string          `json:"clusterProbe,omitempty"`
+	ArtifactsDir          *string          `json:"artifactsDir,omitempty"`
+	ContainerRuntime      *string          `json:"containerRuntime,omitempty"`
+	NodeAccess            *string          `json:"nodeAccess,omitempty"`
+	SSHUser               *string          `json:"sshUser,omitempty"`
+	SSHKeyFile            *string          `json:"sshKeyFile,omitempty"`
+	SSHKnownHostsFile     *string          `json:"sshKnownHostsFile,omitempty"`
+	SSHInsecureHostKey    *bool            `json:"sshInsecureHostKey,omitempty"`
+}
+
+// ReadClusterConfigFile decodes a yaml or json config file, unknown fields are refused
+func ReadClusterConfigFile(path string) (*ClusterConfigFile, error) {
+	if path == "" {
+		return nil, ErrWrongInput
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %v: %w", path, err)
+	}
+
+	c := &ClusterConfigFile{}
+	if err := yaml.UnmarshalStrict(data, c); err != nil {
+		return nil, fmt.Errorf("failed to decode config file %v: %w", path, err)
+	}
+	if c.APIVersion != ClusterConfigAPIVersion || c.Kind != ClusterConfigKind {
+		return nil, fmt.Errorf("config file %v: unsupported apiVersion %q kind %q, expect %v %v",
+			path, c.APIVersion, c.Kind, ClusterConfigAPIVersion, ClusterConfigKind)
+	}
+	return c, nil
+}
+
+// Resolve merges the profile into the shared fields.
+// An empty profile falls back to c.Profile, then to the shared fields only
+func (c *ClusterConfigFile) Resolve(profile string) (ClusterConfig, FrameworkConfig, error) {
+	cluster := c.Cluster
+	fconfig := c.Framework
+
+	if profile == "" {
+		profile = c.Profile
+	}
+	if profile == "" {
+		return cluster, fconfig, nil
+	}
+	p, ok := c.Profiles[profile]
+	if !ok {
+		names := make([]string, 0, len(c.Profiles))
+		for k := range c.Profiles {
+			names = append(names, k)
+		}
+		return cluster, fconfig, fmt.Errorf("profile %q is not found in config file, available: %v", profile, names)
+	}
+	cluster.merge(&p.Cluster)
+	fconfig.merge(&p.Framework)
+	return cluster, fconfig, nil
+}
+
+func (c *ClusterConfig) merge(o *ClusterConfig) {
+	mergeValue(&c.ClusterName, o.ClusterName)
+	mergeValue(&c.KubeConfigPath, o.KubeConfigPath)
+	mergeValue(&c.KubeContext, o.KubeContext)
+	mergeValue(&c.IpV4Enabled, o.IpV4Enabled)
+	mergeValue(&c.IpV6Enabled, o.IpV6Enabled)
+	mergeValue(&c.MultusEnabled, o.MultusEnabled)
+	mergeValue(&c.SpiderIPAMEnabled, o.SpiderIPAMEnabled)
+	mergeValue(&c.WhereaboutIPAMEnabled, o.WhereaboutIPAMEnabled)
+	mergeValue(&c.SpiderSubnetEnabled, o.SpiderSubnetEnabled)
+	mergeValue(&c.MultusDefaultCni, o.MultusDefaultCni)
+	mergeValue(&c.MultusAdditionalCni, o.MultusAdditionalCni)
+	mergeValue(&c.VagrantDir, o.VagrantDir)
+	if o.KindNodeList != nil {
+		c.KindNodeList = o.KindNodeList
+	}
+	if o.VagrantNodeMap != nil {
+		c.VagrantNodeMap = o.VagrantNodeMap
+	}
+}
+
+func (c *FrameworkConfig) merge(o *FrameworkConfig) {
+	mergeValue(&c.ApiOperateTimeout, o.ApiOperateTimeout)
+	mergeValue(&c.ResourceDeleteTimeout, o.ResourceDeleteTimeout)
+	mergeValue(&c.ClusterProbe, o.ClusterProbe)
+	mergeValue(&c.ArtifactsDir, o.ArtifactsDir)
+	mergeValue(&c.ContainerRuntime, o.ContainerRuntime)
+	mergeValue(&c.NodeAccess, o.NodeAccess)
+	mergeValue(&c.SSHUser, o.SSHUser)
+	mergeValue(&c.SSHKeyFile, o.SSHKeyFile)
+	mergeValue(&c.SSHKnownHostsFile, o.SSHKnownHostsFile)
+	mergeValue(&c.SSHInsecureHostKey, o.SSHInsecureHostKey)
+}
+
+func mergeValue[T any](dst **T, src *T) {
+	if src != nil {
+		*dst = src
+	}
+}
+
+func (c *ClusterConfig) applyTo(info *ClusterInfo) {
+	applyValue(&info.ClusterName, c.ClusterName)
+	applyValue(&info.KubeConfigPath, c.KubeConfigPath)
+	applyValue(&info.KubeContext, c.KubeContext)
+	applyValue(&info.IpV4Enabled, c.IpV4Enabled)
+	applyValue(&info.IpV6Enabled, c.IpV6Enabled)
+	applyValue(&info.MultusEnabled, c.MultusEnabled)
+	applyValue(&info.SpiderIPAMEnabled, c.SpiderIPAMEnabled)
+	applyValue(&info.WhereaboutIPAMEnabled, c.WhereaboutIPAMEnabled)
+	applyValue(&info.SpiderSubnetEnabled, c.SpiderSubnetEnabled)
+	applyValue(&info.MultusDefaultCni, c.MultusDefaultCni)
+	applyValue(&info.MultusAdditionalCni, c.MultusAdditionalCni)
+	if c.KindNodeList != nil {
+		info.KindNodeList = append([]string{}, c.KindNodeList...)
+		info.KindNodeListRaw = strings.Join(c.KindNodeList, ",")
+	}
+	applyValue(&info.VagrantDir, c.VagrantDir)
+	if c.VagrantNodeMap != nil {
+		info.VagrantNodeMap = map[string]string{}
+		items := []string{}
+		for vm, node := range c.VagrantNodeMap {
+			info.VagrantNodeMap[vm] = node
+			items = append(items, vm+"="+node)
+		}
+		sort.Strings(items)
+		info.VagrantNodeMapRaw = strings.Join(items, ",")
+	}
+}
+
+func (c *FrameworkConfig) applyTo(config *FConfig) {
+	if c.ApiOperateTimeout != nil {
+		config.ApiOperateTimeout = c.ApiOperateTimeout.Duration
+	}
+	if c.ResourceDeleteTimeout != nil {
+		config.ResourceDeleteTimeout = c.ResourceDeleteTimeout.Duration
+	}
+	applyValue(&config.ClusterProbe, c.ClusterProbe)
+	applyValue(&config.ArtifactsDir, c.ArtifactsDir)
+	applyValue(&config.ContainerRuntime, c.ContainerRuntime)
+	applyValue(&config.NodeAccess, c.NodeAccess)
+	applyValue(&config.SSHUser, c.SSHUser)
+	applyValue(&config.SSHKeyFile, c.SSHKeyFile)
+	applyValue(&config.SSHKnownHostsFile, c.SSHKnownHostsFile)
+	applyValue(&config.SSHInsecureHostKey, c.SSHInsecureHostKey)
+}
+
+func applyValue[T any](dst *T, src *T) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+// LoadClusterConfig reads the config file and fills the fields it sets into info and config.
+// It does not validate the result, for ENV may still override it, call Validate() at last
+func LoadClusterConfig(path, profile string, info *ClusterInfo, config *FConfig) error {
+	if info == nil || config == nil {
+		return ErrWrongInput
+	}
+	c, err := ReadClusterConfigFile(path)
+	if err != nil {
+		return err
+	}
+	cluster, fconfig, err := c.Resolve(profile)
+	if err != nil {
+		return fmt.Errorf("config file %v: %w", path, err)
+	}
+	cluster.applyTo(info)
+	fconfig.applyTo(config)
+	return nil
+}
+
+// Validate reports all the invalid fields at once
+func (c *ClusterInfo) Validate() error {
+	var errs []error
+	if c.ClusterName == "" {
+		errs = append(errs, fmt.Errorf("miss cluster name, set it by ENV %v or the config file", E2E_CLUSTER_NAME))
+	}
+	if c.KubeConfigPath == "" {
+		errs = append(errs, fmt.Errorf("miss kubeconfig path, set it by ENV %v or the config file", E2E_KUBECONFIG_PATH))
+	}
+	if !c.IpV4Enabled && !c.IpV6Enabled {
+		errs = append(errs, fmt.Errorf("at least one of ipv4 and ipv6 should be enabled"))
+	}
+	for n, v := range c.KindNodeList {
+		name := strings.TrimSpace(v)
+		if name == "" || name == "true" || name == "false" {
+			errs = append(errs, fmt.Errorf("kind node list[%v] has an invalid node name %q", n, v))
+		}
+	}
+	if !c.MultusEnabled && (c.MultusDefaultCni != "" || c.MultusAdditionalCni != "") {
+		errs = append(errs, fmt.Errorf("multus cni is set while multus is disabled"))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate reports all the invalid fields at once
+func (c *FConfig) Validate() error {
+	var errs []error
+	if c.ApiOperateTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("apiOperateTimeout should be positive, but get %v", c.ApiOperateTimeout))
+	}
+	if c.ResourceDeleteTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("resourceDeleteTimeout should be positive, but get %v", c.ResourceDeleteTimeout))
+	}
+	switch c.ClusterProbe {
+	case "", ClusterProbeOff, ClusterProbeWarn, ClusterProbeError, ClusterProbeOverride:
+	default:
+		errs = append(errs, fmt.Errorf("unknown clusterProbe %q, set it by ENV %v or the config file", c.ClusterProbe, E2E_CLUSTER_PROBE))
+	}
+	if c.ContainerRuntime != "" && !isContainerRuntime(c.ContainerRuntime) {
+		errs = append(errs, fmt.Errorf("unknown containerRuntime %q, set it by ENV %v or the config file", c.ContainerRuntime, E2E_CONTAINER_RUNTIME))
+	}
+	if c.NodeAccess != "" && !isNodeAccess(c.NodeAccess) {
+		errs = append(errs, fmt.Errorf("unknown nodeAccess %q, set it by ENV %v or the config file", c.NodeAccess, E2E_NODE_ACCESS))
+	}
+	return errors.Join(errs...)
+}
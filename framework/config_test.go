@@ -0,0 +1,113 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework_test
+
+import (
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	e2e "github.com/spidernet-io/e2eframework/framework"
+)
+
+func writeConfigFile(content string) string {
+	file, err := os.CreateTemp("", "e2e-config-*.yaml")
+	Expect(err).NotTo(HaveOccurred())
+	_, err = file.WriteString(content)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(file.Close()).To(Succeed())
+	DeferCleanup(func() {
+		os.Remove(file.Name())
+	})
+	return file.Name()
+}
+
+const exampleConfigFile = `
+apiVersion: e2eframework.spidernet.io/v1alpha1
+kind: ClusterConfig
+profile: dual-stack
+cluster:
+  clusterName: testcluster
+  kubeConfigPath: /tmp/kubeconfig
+  kindNodeList: [master, worker]
+framework:
+  apiOperateTimeout: 30s
+profiles:
+  dual-stack:
+    cluster:
+      ipv4Enabled: true
+      ipv6Enabled: true
+  ipv4-only:
+    cluster:
+      ipv6Enabled: false
+    framework:
+      resourceDeleteTimeout: 2m
+`
+
+var _ = Describe("test config file", Label("config"), func() {
+
+	It("load the default profile", func() {
+		path := writeConfigFile(exampleConfigFile)
+		info := &e2e.ClusterInfo{IpV4Enabled: true, IpV6Enabled: false}
+		config := &e2e.FConfig{ApiOperateTimeout: time.Second, ResourceDeleteTimeout: time.Second}
+
+		Expect(e2e.LoadClusterConfig(path, "", info, config)).To(Succeed())
+		Expect(info.ClusterName).To(Equal("testcluster"))
+		Expect(info.KubeConfigPath).To(Equal("/tmp/kubeconfig"))
+		Expect(info.IpV6Enabled).To(BeTrue())
+		Expect(info.KindNodeList).To(Equal([]string{"master", "worker"}))
+		Expect(info.KindNodeListRaw).To(Equal("master,worker"))
+		Expect(config.ApiOperateTimeout).To(Equal(30 * time.Second))
+		Expect(config.ResourceDeleteTimeout).To(Equal(time.Second))
+		Expect(info.Validate()).To(Succeed())
+	})
+
+	It("load a named profile", func() {
+		path := writeConfigFile(exampleConfigFile)
+		info := &e2e.ClusterInfo{IpV4Enabled: true, IpV6Enabled: true}
+		config := &e2e.FConfig{}
+
+		Expect(e2e.LoadClusterConfig(path, "ipv4-only", info, config)).To(Succeed())
+		Expect(info.IpV4Enabled).To(BeTrue())
+		Expect(info.IpV6Enabled).To(BeFalse())
+		Expect(config.ResourceDeleteTimeout).To(Equal(2 * time.Minute))
+
+		e := e2e.LoadClusterConfig(path, "multus", info, config)
+		Expect(e).To(HaveOccurred())
+		GinkgoWriter.Printf("expected error: %v \n", e)
+	})
+
+	It("refuse bad files", func() {
+		info := &e2e.ClusterInfo{}
+		config := &e2e.FConfig{}
+
+		path := writeConfigFile("apiVersion: v1\nkind: ClusterConfig\n")
+		Expect(e2e.LoadClusterConfig(path, "", info, config)).NotTo(Succeed())
+
+		path = writeConfigFile("apiVersion: e2eframework.spidernet.io/v1alpha1\nkind: ClusterConfig\ncluster:\n  clusterNam: typo\n")
+		Expect(e2e.LoadClusterConfig(path, "", info, config)).NotTo(Succeed())
+
+		Expect(e2e.LoadClusterConfig("/tmp/not-existed-e2e-config", "", info, config)).NotTo(Succeed())
+		Expect(e2e.LoadClusterConfig(path, "", nil, config)).To(MatchError(e2e.ErrWrongInput))
+	})
+
+	It("report all invalid fields at once", func() {
+		info := &e2e.ClusterInfo{
+			KindNodeList:     []string{"false"},
+			MultusDefaultCni: "calico",
+		}
+		e := info.Validate()
+		Expect(e).To(HaveOccurred())
+		GinkgoWriter.Printf("expected error: %v \n", e)
+		Expect(e.Error()).To(ContainSubstring(e2e.E2E_CLUSTER_NAME))
+		Expect(e.Error()).To(ContainSubstring(e2e.E2E_KUBECONFIG_PATH))
+		Expect(e.Error()).To(ContainSubstring("ipv4 and ipv6"))
+		Expect(e.Error()).To(ContainSubstring("invalid node name"))
+		Expect(e.Error()).To(ContainSubstring("multus"))
+
+		config := &e2e.FConfig{}
+		Expect(config.Validate()).To(HaveOccurred())
+	})
+})
@@ -3,65 +3,40 @@
 package framework
 
 import (
-	"fmt"
-	"time"
+	"context"
 
-	"github.com/spidernet-io/e2eframework/tools"
 	corev1 "k8s.io/api/core/v1"
-	api_errors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	apitypes "k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func (f *Framework) GetConfigmap(name, namespace string) (*corev1.ConfigMap, error) {
+	return f.GetConfigmapContext(context.Background(), name, namespace)
+}
+
+func (f *Framework) GetConfigmapContext(ctx context.Context, name, namespace string) (*corev1.ConfigMap, error) {
 	if name == "" || namespace == "" {
 		return nil, ErrWrongInput
 	}
-
-	key := apitypes.NamespacedName{Namespace: namespace, Name: name}
-	existing := &corev1.ConfigMap{}
-	e := f.GetResource(key, existing)
-	if e != nil {
-		return nil, e
-	}
-	return existing, e
+	return Get[*corev1.ConfigMap](ctx, f, name, namespace)
 }
 
 func (f *Framework) CreateConfigmap(configMap *corev1.ConfigMap, opts ...client.CreateOption) error {
+	return f.CreateConfigmapContext(context.Background(), configMap, opts...)
+}
+
+func (f *Framework) CreateConfigmapContext(ctx context.Context, configMap *corev1.ConfigMap, opts ...client.CreateOption) error {
 	if configMap == nil {
 		return ErrWrongInput
 	}
-
-	fake := &corev1.ConfigMap{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: configMap.Namespace,
-			Name:      configMap.Name,
-		},
-	}
-	key := client.ObjectKeyFromObject(fake)
-	existing := &corev1.ConfigMap{}
-	e := f.GetResource(key, existing)
-	if e == nil && existing.DeletionTimestamp == nil {
-		return fmt.Errorf("%w: configmap '%s/%s'", ErrAlreadyExisted, existing.Namespace, existing.Name)
-	}
-	t := func() bool {
-		existing := &corev1.ConfigMap{}
-		e := f.GetResource(key, existing)
-		b := api_errors.IsNotFound(e)
-		if !b {
-			f.Log("waiting for a same configmap %v/%v to finish deleting \n", configMap.Namespace, configMap.Name)
-			return false
-		}
-		return true
-	}
-	if !tools.Eventually(t, f.Config.ResourceDeleteTimeout, time.Second) {
-		return ErrTimeOut
-	}
-	return f.CreateResource(configMap, opts...)
+	return Create(ctx, f, configMap, opts...)
 }
 
 func (f *Framework) DeleteConfigmap(name, namespace string, opts ...client.DeleteOption) error {
+	return f.DeleteConfigmapContext(context.Background(), name, namespace, opts...)
+}
+
+func (f *Framework) DeleteConfigmapContext(ctx context.Context, name, namespace string, opts ...client.DeleteOption) error {
 	if name == "" || namespace == "" {
 		return ErrWrongInput
 	}
@@ -71,5 +46,5 @@ func (f *Framework) DeleteConfigmap(name, namespace string, opts ...client.Delet
 			Namespace: namespace,
 		},
 	}
-	return f.DeleteResource(cm, opts...)
+	return f.DeleteResourceContext(ctx, cm, opts...)
 }
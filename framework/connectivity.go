@@ -0,0 +1,374 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// probe every pair of the source pods and the targets in the source pods, like:
+//   m, err := f.CheckConnectivity(ctx, &ConnectivityOptions{
+//   	Sources: clients.Items,
+//   	Targets: []ConnectivityTarget{PodTarget(&server, 80, 53), ServiceTarget(svc)},
+//   })
+//   GinkgoWriter.Print(m.String())
+//   Expect(m.AllReachable()).To(Succeed())
+// the source pod needs ping and nc, and the target answers the udp probe like an echo server
+
+const (
+	ProbeICMP = "icmp"
+	ProbeTCP  = "tcp"
+	ProbeUDP  = "udp"
+)
+
+var (
+	DefaultProbeTimeout     = 2 * time.Second
+	DefaultProbeConcurrency = 10
+)
+
+// DefaultProbeBlockedCodes is the exit code of ping, nc and grep without the reply
+var DefaultProbeBlockedCodes = []int{1}
+
+var probeProtocols = []string{ProbeICMP, ProbeTCP, ProbeUDP}
+
+// ConnectivityTarget is the pod or service to be probed
+type ConnectivityTarget struct {
+	// like "default/web" for the pod, or "service:default/web"
+	Name string
+	// the address of each family
+	IPs map[corev1.IPFamily]string
+	// 0 means no probe of the protocol
+	TCPPort int
+	UDPPort int
+	// NoICMP skips the icmp probe, like the service not answering ping
+	NoICMP bool
+}
+
+// ConnectivityOptions configures CheckConnectivity
+type ConnectivityOptions struct {
+	Sources []corev1.Pod
+	// the container of the source pods, empty means the only container
+	Container string
+	Targets   []ConnectivityTarget
+	// empty means ProbeICMP, ProbeTCP and ProbeUDP
+	Protocols []string
+	// empty means the families enabled by ClusterInfo
+	IPFamilies []corev1.IPFamily
+	// the timeout of each probe, 0 means DefaultProbeTimeout
+	Timeout time.Duration
+	// the probes running at the same time, 0 means DefaultProbeConcurrency
+	Concurrency int
+	// ProbeCommand returns the command of the probe, nil means DefaultProbeCommand
+	ProbeCommand func(protocol, ip string, port int, timeout time.Duration) []string
+	// Exec runs the probe in the source pod, nil means ExecInPod
+	Exec func(ctx context.Context, pod *corev1.Pod, command []string) (*ExecResult, error)
+	// the exit codes of the probe command meaning blocked, empty means DefaultProbeBlockedCodes
+	BlockedExitCodes []int
+}
+
+// ConnectivityCell is the result of one probe
+type ConnectivityCell struct {
+	Source   string
+	Target   string
+	Protocol string
+	IPFamily corev1.IPFamily
+	// like "10.6.0.10:80"
+	Address string
+	// the time of the probe, including the round trip of exec
+	Latency   time.Duration
+	Reachable bool
+	// Blocked means the probe runs and exits with the blocked exit code
+	Blocked bool
+	// the *ExecExitError for the failed probe, or the error running the probe
+	Err error
+}
+
+// ConnectivityMatrix is the probes of every pair, in the order of the source, target, protocol and family
+type ConnectivityMatrix struct {
+	Sources []string
+	Targets []string
+	Cells   []ConnectivityCell
+}
+
+// PodTarget returns the target of the pod IPs, 0 port means no probe of the protocol
+func PodTarget(pod *corev1.Pod, tcpPort, udpPort int) ConnectivityTarget {
+	t := ConnectivityTarget{
+		Name:    keyString(client.ObjectKeyFromObject(pod)),
+		IPs:     map[corev1.IPFamily]string{},
+		TCPPort: tcpPort,
+		UDPPort: udpPort,
+	}
+	for _, ip := range pod.Status.PodIPs {
+		t.IPs[ipFamilyOf(ip.IP)] = ip.IP
+	}
+	return t
+}
+
+// ServiceTarget returns the target of the cluster IPs and the first port of each protocol, without the icmp probe
+func ServiceTarget(svc *corev1.Service) ConnectivityTarget {
+	t := ConnectivityTarget{
+		Name:   "service:" + keyString(client.ObjectKeyFromObject(svc)),
+		IPs:    map[corev1.IPFamily]string{},
+		NoICMP: true,
+	}
+	ips := svc.Spec.ClusterIPs
+	if len(ips) == 0 && svc.Spec.ClusterIP != "" {
+		ips = []string{svc.Spec.ClusterIP}
+	}
+	for _, ip := range ips {
+		if net.ParseIP(ip) != nil {
+			t.IPs[ipFamilyOf(ip)] = ip
+		}
+	}
+	for _, p := range svc.Spec.Ports {
+		switch {
+		case (p.Protocol == corev1.ProtocolTCP || p.Protocol == "") && t.TCPPort == 0:
+			t.TCPPort = int(p.Port)
+		case p.Protocol == corev1.ProtocolUDP && t.UDPPort == 0:
+			t.UDPPort = int(p.Port)
+		}
+	}
+	return t
+}
+
+func ipFamilyOf(ip string) corev1.IPFamily {
+	if strings.Contains(ip, ":") {
+		return corev1.IPv6Protocol
+	}
+	return corev1.IPv4Protocol
+}
+
+// DefaultProbeCommand probes by ping and nc, the udp probe passes when the target replies
+func DefaultProbeCommand(protocol, ip string, port int, timeout time.Duration) []string {
+	seconds := strconv.Itoa(max(1, int(timeout.Round(time.Second)/time.Second)))
+	switch protocol {
+	case ProbeICMP:
+		return []string{"ping", "-c", "1", "-W", seconds, ip}
+	case ProbeTCP:
+		return []string{"nc", "-z", "-w", seconds, ip, strconv.Itoa(port)}
+	}
+	// nc exits 0 without the reply, so the reply is required
+	return []string{"sh", "-c", fmt.Sprintf("echo e2e-probe | nc -u -w %s %s %d | grep -q .", seconds, ip, port)}
+}
+
+// CheckConnectivity probes every pair of the sources and the targets, for each protocol and IP family.
+// The protocol without the target port is skipped, and the target without the IP of any family fails with ErrWrongInput.
+// It fails only for the invalid options, the failed probes are in the matrix
+func (f *Framework) CheckConnectivity(ctx context.Context, opts *ConnectivityOptions) (*ConnectivityMatrix, error) {
+	if opts == nil || len(opts.Sources) == 0 || len(opts.Targets) == 0 {
+		return nil, ErrWrongInput
+	}
+	protocols := opts.Protocols
+	if len(protocols) == 0 {
+		protocols = probeProtocols
+	}
+	for _, p := range protocols {
+		if !slices.Contains(probeProtocols, p) {
+			return nil, fmt.Errorf("%w: unknown probe protocol %q", ErrWrongInput, p)
+		}
+	}
+	families := opts.IPFamilies
+	if len(families) == 0 {
+		if f.Info.IpV4Enabled {
+			families = append(families, corev1.IPv4Protocol)
+		}
+		if f.Info.IpV6Enabled {
+			families = append(families, corev1.IPv6Protocol)
+		}
+	}
+	if len(families) == 0 {
+		return nil, fmt.Errorf("%w: no IP family to probe", ErrWrongInput)
+	}
+	for _, target := range opts.Targets {
+		for _, family := range families {
+			if target.IPs[family] == "" {
+				return nil, fmt.Errorf("%w: target %s has no %s address", ErrWrongInput, target.Name, family)
+			}
+		}
+	}
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = DefaultProbeTimeout
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultProbeConcurrency
+	}
+	command := opts.ProbeCommand
+	if command == nil {
+		command = DefaultProbeCommand
+	}
+	blockedCodes := opts.BlockedExitCodes
+	if len(blockedCodes) == 0 {
+		blockedCodes = DefaultProbeBlockedCodes
+	}
+	exec := opts.Exec
+	if exec == nil {
+		exec = func(ctx context.Context, pod *corev1.Pod, command []string) (*ExecResult, error) {
+			return f.ExecInPod(ctx, pod.Name, pod.Namespace, command, &ExecOptions{Container: opts.Container})
+		}
+	}
+
+	m := &ConnectivityMatrix{}
+	type probe struct {
+		pod     *corev1.Pod
+		command []string
+	}
+	probes := []probe{}
+	for n := range opts.Sources {
+		source := &opts.Sources[n]
+		m.Sources = append(m.Sources, keyString(client.ObjectKeyFromObject(source)))
+		for _, target := range opts.Targets {
+			for _, protocol := range protocols {
+				port := map[string]int{ProbeTCP: target.TCPPort, ProbeUDP: target.UDPPort}[protocol]
+				if (protocol == ProbeICMP && target.NoICMP) || (protocol != ProbeICMP && port == 0) {
+					continue
+				}
+				for _, family := range families {
+					ip := target.IPs[family]
+					address := ip
+					if protocol != ProbeICMP {
+						address = net.JoinHostPort(ip, strconv.Itoa(port))
+					}
+					m.Cells = append(m.Cells, ConnectivityCell{
+						Source:   m.Sources[n],
+						Target:   target.Name,
+						Protocol: protocol,
+						IPFamily: family,
+						Address:  address,
+					})
+					probes = append(probes, probe{pod: source, command: command(protocol, ip, port, timeout)})
+				}
+			}
+		}
+	}
+	for _, target := range opts.Targets {
+		m.Targets = append(m.Targets, target.Name)
+	}
+	f.Log("probing %d pairs of %d sources and %d targets \n", len(m.Cells), len(m.Sources), len(m.Targets))
+
+	limit := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+	for n := range probes {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			select {
+			case limit <- struct{}{}:
+			case <-ctx.Done():
+				m.Cells[n].Err = ctx.Err()
+				return
+			}
+			defer func() { <-limit }()
+
+			// the exec has its own round trip, so the probe could take a little longer than timeout
+			ctx, cancel := context.WithTimeout(ctx, timeout+f.Config.ApiOperateTimeout)
+			defer cancel()
+			start := time.Now()
+			_, err := exec(ctx, probes[n].pod, probes[n].command)
+			m.Cells[n].Latency = time.Since(start)
+			m.Cells[n].Reachable = err == nil
+			m.Cells[n].Blocked = probeBlocked(err, blockedCodes)
+			m.Cells[n].Err = err
+		}(n)
+	}
+	wg.Wait()
+	return m, nil
+}
+
+// probeBlocked tells the probe exits with the blocked code, rather than failing to run,
+// like the exit code 126 or 127 of the shell for the command not executable or not found
+func probeBlocked(err error, codes []int) bool {
+	var exitErr *ExecExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	if exitErr.Code == 126 || exitErr.Code == 127 || strings.Contains(string(exitErr.Stderr), "not found") {
+		return false
+	}
+	return slices.Contains(codes, exitErr.Code)
+}
+
+// AllReachable fails with ErrConnectivity for the cells not reachable, or no cell
+func (m *ConnectivityMatrix) AllReachable() error {
+	if len(m.Cells) == 0 {
+		return fmt.Errorf("%w: no probe in the matrix", ErrConnectivity)
+	}
+	failed := []string{}
+	for _, c := range m.Cells {
+		if !c.Reachable {
+			failed = append(failed, fmt.Sprintf("%s -> %s %s %s: %v", c.Source, c.Target, c.Protocol, c.Address, c.Err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%w: %d of %d probes are unreachable:\n%s", ErrConnectivity, len(failed), len(m.Cells), strings.Join(failed, "\n"))
+	}
+	return nil
+}
+
+// AllBlocked fails with ErrConnectivity for the cells reachable, the probes failing to run, or no cell
+func (m *ConnectivityMatrix) AllBlocked() error {
+	if len(m.Cells) == 0 {
+		return fmt.Errorf("%w: no probe in the matrix", ErrConnectivity)
+	}
+	failed := []string{}
+	for _, c := range m.Cells {
+		switch {
+		case c.Reachable:
+			failed = append(failed, fmt.Sprintf("%s -> %s %s %s: reachable", c.Source, c.Target, c.Protocol, c.Address))
+		case !c.Blocked:
+			failed = append(failed, fmt.Sprintf("%s -> %s %s %s: failed to probe: %v", c.Source, c.Target, c.Protocol, c.Address, c.Err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%w: %d of %d probes are not blocked:\n%s", ErrConnectivity, len(failed), len(m.Cells), strings.Join(failed, "\n"))
+	}
+	return nil
+}
+
+// String renders the matrix as a table, a row for each source and a column for each target,
+// the cell is like "tcp4 3ms tcp6 X" where X means unreachable
+func (m *ConnectivityMatrix) String() string {
+	b := &strings.Builder{}
+	w := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SOURCE\t"+strings.Join(m.Targets, "\t"))
+	for _, source := range m.Sources {
+		row := []string{source}
+		for _, target := range m.Targets {
+			results := []string{}
+			for _, c := range m.Cells {
+				if c.Source != source || c.Target != target {
+					continue
+				}
+				result := "X"
+				if c.Reachable {
+					result = c.Latency.Round(time.Millisecond).String()
+				}
+				family := "4"
+				if c.IPFamily == corev1.IPv6Protocol {
+					family = "6"
+				}
+				results = append(results, c.Protocol+family+" "+result)
+			}
+			if len(results) == 0 {
+				results = append(results, "-")
+			}
+			row = append(row, strings.Join(results, " "))
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+	return b.String()
+}
@@ -0,0 +1,149 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework_test
+
+import (
+	"context"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	e2e "github.com/spidernet-io/e2eframework/framework"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func connectivityPod(name string, ips ...string) corev1.Pod {
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+	for _, ip := range ips {
+		pod.Status.PodIPs = append(pod.Status.PodIPs, corev1.PodIP{IP: ip})
+	}
+	return pod
+}
+
+var _ = Describe("test connectivity", Label("connectivity"), func() {
+	var f *e2e.Framework
+	var ctx context.Context
+
+	BeforeEach(func() {
+		f = fakeFramework()
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 20*time.Second)
+		DeferCleanup(cancel)
+	})
+
+	It("probe every pair", func() {
+		server := connectivityPod("server", "10.6.0.10", "fd00::10")
+		svc := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "server", Namespace: "default"},
+			Spec: corev1.ServiceSpec{
+				ClusterIPs: []string{"10.233.0.10", "fd00::233:10"},
+				Ports:      []corev1.ServicePort{{Port: 80}, {Port: 53, Protocol: corev1.ProtocolUDP}},
+			},
+		}
+
+		var running, most atomic.Int32
+		opts := &e2e.ConnectivityOptions{
+			Sources:     []corev1.Pod{connectivityPod("client-1"), connectivityPod("client-2")},
+			Targets:     []e2e.ConnectivityTarget{e2e.PodTarget(&server, 80, 0), e2e.ServiceTarget(svc)},
+			Concurrency: 2,
+			Exec: func(ctx context.Context, pod *corev1.Pod, command []string) (*e2e.ExecResult, error) {
+				n := running.Add(1)
+				defer running.Add(-1)
+				for m := most.Load(); n > m && !most.CompareAndSwap(m, n); m = most.Load() {
+				}
+				time.Sleep(10 * time.Millisecond)
+				// the ipv6 of client-2 is blocked
+				if pod.Name == "client-2" && slices.Contains(command, "fd00::10") {
+					return nil, &e2e.ExecExitError{Command: command, Code: 1}
+				}
+				return &e2e.ExecResult{}, nil
+			},
+		}
+		m, e := f.CheckConnectivity(ctx, opts)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(most.Load()).To(BeNumerically("<=", 2))
+
+		// the pod: icmp and tcp of both families, the service: tcp and udp of both families
+		Expect(m.Cells).To(HaveLen(2 * (4 + 4)))
+		c := m.Cells[1]
+		Expect(c.Source).To(Equal("default/client-1"))
+		Expect(c.Target).To(Equal("default/server"))
+		Expect(c.Protocol).To(Equal(e2e.ProbeICMP))
+		Expect(c.IPFamily).To(Equal(corev1.IPv6Protocol))
+		Expect(c.Address).To(Equal("fd00::10"))
+		Expect(c.Reachable).To(BeTrue())
+		Expect(m.Cells[3].Address).To(Equal("[fd00::10]:80"))
+		Expect(m.Cells[6].Address).To(Equal("10.233.0.10:53"))
+		Expect(m.Cells[6].Latency).To(BeNumerically(">=", 10*time.Millisecond))
+
+		e = m.AllReachable()
+		Expect(e).To(MatchError(e2e.ErrConnectivity))
+		Expect(e.Error()).To(ContainSubstring("2 of 16 probes are unreachable"))
+		Expect(m.AllBlocked()).To(MatchError(e2e.ErrConnectivity))
+		Expect(m.String()).To(ContainSubstring("icmp6 X tcp4"))
+
+		opts.Sources = opts.Sources[1:]
+		opts.Targets = opts.Targets[:1]
+		opts.IPFamilies = []corev1.IPFamily{corev1.IPv6Protocol}
+		m, e = f.CheckConnectivity(ctx, opts)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(m.Cells).To(HaveLen(2))
+		Expect(m.AllBlocked()).To(Succeed())
+
+		// the probe fails to run, rather than blocked
+		opts.Exec = nil
+		m, e = f.CheckConnectivity(ctx, opts)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(m.Cells[0].Err).To(MatchError(e2e.ErrNoClientSet))
+		Expect(m.AllBlocked()).To(MatchError(e2e.ErrConnectivity))
+
+		// the probe tool is missing in the source pod
+		for _, err := range []error{
+			&e2e.ExecExitError{Code: 127},
+			&e2e.ExecExitError{Code: 126},
+			&e2e.ExecExitError{Code: 1, Stderr: []byte("sh: nc: not found")},
+			&e2e.ExecExitError{Code: 2},
+		} {
+			opts.Exec = func(ctx context.Context, pod *corev1.Pod, command []string) (*e2e.ExecResult, error) {
+				return nil, err
+			}
+			m, e = f.CheckConnectivity(ctx, opts)
+			Expect(e).NotTo(HaveOccurred())
+			Expect(m.Cells[0].Blocked).To(BeFalse())
+			Expect(m.AllBlocked()).To(MatchError(ContainSubstring("failed to probe")))
+		}
+		opts.BlockedExitCodes = []int{1, 2}
+		m, e = f.CheckConnectivity(ctx, opts)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(m.AllBlocked()).To(Succeed())
+
+		// no probe for the protocol without the port
+		opts.Protocols = []string{e2e.ProbeUDP}
+		m, e = f.CheckConnectivity(ctx, opts)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(m.Cells).To(BeEmpty())
+		Expect(m.AllReachable()).To(MatchError(e2e.ErrConnectivity))
+		Expect(m.AllBlocked()).To(MatchError(e2e.ErrConnectivity))
+
+		// the target without the ipv6 address
+		opts.Protocols = nil
+		v4 := connectivityPod("server", "10.6.0.10")
+		opts.Targets = []e2e.ConnectivityTarget{e2e.PodTarget(&v4, 80, 0)}
+		_, e = f.CheckConnectivity(ctx, opts)
+		Expect(e).To(MatchError(e2e.ErrWrongInput))
+
+		opts.Protocols = []string{"sctp"}
+		_, e = f.CheckConnectivity(ctx, opts)
+		Expect(e).To(MatchError(e2e.ErrWrongInput))
+	})
+
+	It("build the probe command", func() {
+		Expect(e2e.DefaultProbeCommand(e2e.ProbeICMP, "fd00::10", 0, 500*time.Millisecond)).To(Equal([]string{"ping", "-c", "1", "-W", "1", "fd00::10"}))
+		Expect(e2e.DefaultProbeCommand(e2e.ProbeTCP, "10.6.0.10", 80, 3*time.Second)).To(Equal([]string{"nc", "-z", "-w", "3", "10.6.0.10", "80"}))
+		Expect(e2e.DefaultProbeCommand(e2e.ProbeUDP, "10.6.0.10", 53, 2*time.Second)).To(Equal([]string{"sh", "-c", "echo e2e-probe | nc -u -w 2 10.6.0.10 53 | grep -q ."}))
+	})
+})
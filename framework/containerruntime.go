@@ -0,0 +1,433 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+)
+
+// the kind nodes are the containers of docker, podman or nerdctl, operated without the shell, like:
+//   r, err := f.ContainerRuntime()
+//   out, err := r.Exec(ctx, "kind-worker", []string{"ip", "-4", "route", "show", "default"})
+//   err = r.Stop(ctx, "kind-worker")
+// the unitest sets the fake one:
+//   f.Runtime = framework.NewFakeContainerRuntime()
+
+const (
+	// E2E_CONTAINER_RUNTIME is one of docker, podman and nerdctl, empty means the first one found in PATH
+	E2E_CONTAINER_RUNTIME = "E2E_CONTAINER_RUNTIME"
+
+	ContainerRuntimeDocker  = "docker"
+	ContainerRuntimePodman  = "podman"
+	ContainerRuntimeNerdctl = "nerdctl"
+)
+
+// the order of detecting
+var containerRuntimes = []string{ContainerRuntimeDocker, ContainerRuntimePodman, ContainerRuntimeNerdctl}
+
+// ContainerRuntime operates the containers, every argument is passed as it is.
+// The failed command returns the *ExecExitError, and the missing container returns ErrNoContainer
+type ContainerRuntime interface {
+	// Name is the name of the runtime, like docker
+	Name() string
+	// Exec runs command in the running container, and returns the stdout
+	Exec(ctx context.Context, container string, command []string) ([]byte, error)
+	// Run starts a detached container, and returns its id
+	Run(ctx context.Context, opts *ContainerRunOptions) (string, error)
+	// Remove removes the container by force
+	Remove(ctx context.Context, container string) error
+	Stop(ctx context.Context, container string) error
+	Start(ctx context.Context, container string) error
+	Inspect(ctx context.Context, container string) (*ContainerInfo, error)
+	// Logs returns the stdout and stderr of the container
+	Logs(ctx context.Context, container string) ([]byte, error)
+}
+
+// ContainerRunOptions is the options of ContainerRuntime.Run
+type ContainerRunOptions struct {
+	// empty means a name generated by the runtime
+	Name  string
+	Image string
+	// empty means the default command of the image
+	Command []string
+	// the extra flags of `run`, like []string{"--network", "kind", "--privileged"}
+	Flags []string
+}
+
+// ContainerInfo is the common part of the inspected container
+type ContainerInfo struct {
+	ID      string
+	Name    string
+	Image   string
+	Status  string
+	Running bool
+	Pid     int
+	// the networks by name
+	Networks map[string]ContainerNetwork
+}
+
+type ContainerNetwork struct {
+	IPv4 string
+	IPv6 string
+}
+
+// NewContainerRuntime returns the runtime of the name, empty name means the first one found in PATH
+func NewContainerRuntime(name string) (ContainerRuntime, error) {
+	names := containerRuntimes
+	if name != "" {
+		if !isContainerRuntime(name) {
+			return nil, fmt.Errorf("%w: unknown container runtime %q", ErrWrongInput, name)
+		}
+		names = []string{name}
+	}
+	for _, n := range names {
+		if binary, err := exec.LookPath(n); err == nil {
+			return &cliContainerRuntime{name: n, binary: binary}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: looked for %v in PATH", ErrNoContainerRuntime, names)
+}
+
+func isContainerRuntime(name string) bool {
+	for _, n := range containerRuntimes {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainerRuntime returns f.Runtime, which is set to the runtime of Config.ContainerRuntime for the first time
+func (f *Framework) ContainerRuntime() (ContainerRuntime, error) {
+	if f.Runtime != nil {
+		return f.Runtime, nil
+	}
+	r, err := NewContainerRuntime(f.Config.ContainerRuntime)
+	if err != nil {
+		return nil, err
+	}
+	f.Runtime = r
+	return r, nil
+}
+
+// runtimeBinary is for the shell commands of DockerExecCommand and so on
+func (f *Framework) runtimeBinary() string {
+	if r, err := f.ContainerRuntime(); err == nil {
+		if c, ok := r.(*cliContainerRuntime); ok {
+			return c.binary
+		}
+	}
+	return ContainerRuntimeDocker
+}
+
+// ------------- the runtime of the cli, the docker compatible commands of podman and nerdctl
+
+type cliContainerRuntime struct {
+	name   string
+	binary string
+}
+
+func (c *cliContainerRuntime) Name() string {
+	return c.name
+}
+
+// run runs the cli, and classifies the error of the missing container
+func (c *cliContainerRuntime) run(ctx context.Context, container string, args ...string) ([]byte, []byte, error) {
+	if container == "" {
+		return nil, nil, ErrWrongInput
+	}
+	stdout, stderr, err := c.command(ctx, args...)
+	var exitErr *ExecExitError
+	if errors.As(err, &exitErr) && strings.Contains(strings.ToLower(string(exitErr.Stderr)), "no such container") {
+		return stdout, stderr, fmt.Errorf("%w: %s: %w", ErrNoContainer, container, err)
+	}
+	return stdout, stderr, err
+}
+
+func (c *cliContainerRuntime) command(ctx context.Context, args ...string) ([]byte, []byte, error) {
+	return runBinary(ctx, "", c.name, c.binary, args...)
+}
+
+func (c *cliContainerRuntime) Exec(ctx context.Context, container string, command []string) ([]byte, error) {
+	if container == "" || len(command) == 0 {
+		return nil, ErrWrongInput
+	}
+	// the missing container is not classified, for the stderr belongs to the command
+	stdout, _, err := c.command(ctx, append([]string{"exec", "-i", container}, command...)...)
+	return stdout, err
+}
+
+func (c *cliContainerRuntime) Run(ctx context.Context, opts *ContainerRunOptions) (string, error) {
+	if opts == nil || opts.Image == "" {
+		return "", ErrWrongInput
+	}
+	args := []string{"run", "-d"}
+	if opts.Name != "" {
+		args = append(args, "--name", opts.Name)
+	}
+	args = append(args, opts.Flags...)
+	args = append(args, opts.Image)
+	args = append(args, opts.Command...)
+	stdout, _, err := c.command(ctx, args...)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(stdout)), nil
+}
+
+func (c *cliContainerRuntime) Remove(ctx context.Context, container string) error {
+	_, _, err := c.run(ctx, container, "rm", "-f", container)
+	return err
+}
+
+func (c *cliContainerRuntime) Stop(ctx context.Context, container string) error {
+	_, _, err := c.run(ctx, container, "stop", container)
+	return err
+}
+
+func (c *cliContainerRuntime) Start(ctx context.Context, container string) error {
+	_, _, err := c.run(ctx, container, "start", container)
+	return err
+}
+
+func (c *cliContainerRuntime) Logs(ctx context.Context, container string) ([]byte, error) {
+	stdout, stderr, err := c.run(ctx, container, "logs", container)
+	return append(stdout, stderr...), err
+}
+
+// the docker compatible fields of inspect, the podman has ImageName in addition
+type inspectedContainer struct {
+	ID        string `json:"Id"`
+	Name      string `json:"Name"`
+	Image     string `json:"Image"`
+	ImageName string `json:"ImageName"`
+	State     struct {
+		Status  string `json:"Status"`
+		Running bool   `json:"Running"`
+		Pid     int    `json:"Pid"`
+	} `json:"State"`
+	Config struct {
+		Image string `json:"Image"`
+	} `json:"Config"`
+	NetworkSettings struct {
+		Networks map[string]struct {
+			IPAddress         string `json:"IPAddress"`
+			GlobalIPv6Address string `json:"GlobalIPv6Address"`
+		} `json:"Networks"`
+	} `json:"NetworkSettings"`
+}
+
+func (c *cliContainerRuntime) Inspect(ctx context.Context, container string) (*ContainerInfo, error) {
+	stdout, _, err := c.run(ctx, container, "inspect", "--type", "container", container)
+	if err != nil {
+		return nil, err
+	}
+	list := []inspectedContainer{}
+	if err := json.Unmarshal(stdout, &list); err != nil {
+		return nil, fmt.Errorf("failed to decode the inspected container %s: %w", container, err)
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoContainer, container)
+	}
+
+	i := list[0]
+	info := &ContainerInfo{
+		ID:       i.ID,
+		Name:     strings.TrimPrefix(i.Name, "/"),
+		Image:    i.Config.Image,
+		Status:   i.State.Status,
+		Running:  i.State.Running,
+		Pid:      i.State.Pid,
+		Networks: map[string]ContainerNetwork{},
+	}
+	if info.Image == "" {
+		info.Image = i.ImageName
+	}
+	if info.Image == "" {
+		info.Image = i.Image
+	}
+	for name, n := range i.NetworkSettings.Networks {
+		info.Networks[name] = ContainerNetwork{IPv4: n.IPAddress, IPv6: n.GlobalIPv6Address}
+	}
+	return info, nil
+}
+
+// ------------- the fake runtime for the unitest
+
+// FakeContainerRuntime keeps the containers in memory
+type FakeContainerRuntime struct {
+	lock       sync.Mutex
+	containers map[string]*ContainerInfo
+	logs       map[string][]byte
+	calls      []string
+
+	// ExecFunc answers Exec, nil means the empty output
+	ExecFunc func(container string, command []string) ([]byte, error)
+}
+
+var _ ContainerRuntime = &FakeContainerRuntime{}
+
+// NewFakeContainerRuntime returns the fake runtime with the running containers
+func NewFakeContainerRuntime(containers ...string) *FakeContainerRuntime {
+	r := &FakeContainerRuntime{
+		containers: map[string]*ContainerInfo{},
+		logs:       map[string][]byte{},
+	}
+	for _, name := range containers {
+		r.AddContainer(&ContainerInfo{Name: name})
+	}
+	return r
+}
+
+// AddContainer adds or replaces the container, the empty ID and Status are filled
+func (r *FakeContainerRuntime) AddContainer(info *ContainerInfo) {
+	c := *info
+	if c.ID == "" {
+		c.ID = utilrand.String(12)
+	}
+	if c.Status == "" {
+		c.Status = "running"
+		c.Running = true
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.containers[c.Name] = &c
+}
+
+// SetLogs sets the output of Logs
+func (r *FakeContainerRuntime) SetLogs(container string, logs []byte) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.logs[container] = logs
+}
+
+// Calls returns every call like the cli, like "exec kind-worker ip link"
+func (r *FakeContainerRuntime) Calls() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]string{}, r.calls...)
+}
+
+func (r *FakeContainerRuntime) Name() string {
+	return "fake"
+}
+
+// get records the call and returns the container, with the lock held
+func (r *FakeContainerRuntime) get(container string, args ...string) (*ContainerInfo, error) {
+	r.calls = append(r.calls, strings.Join(args, " "))
+	if container == "" {
+		return nil, ErrWrongInput
+	}
+	c, ok := r.containers[container]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoContainer, container)
+	}
+	return c, nil
+}
+
+func (r *FakeContainerRuntime) Exec(ctx context.Context, container string, command []string) ([]byte, error) {
+	r.lock.Lock()
+	c, err := r.get(container, append([]string{"exec", container}, command...)...)
+	fn := r.ExecFunc
+	r.lock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if len(command) == 0 {
+		return nil, ErrWrongInput
+	}
+	if !c.Running {
+		return nil, &ExecExitError{Command: command, Code: 1, Stderr: []byte("container is not running")}
+	}
+	if fn == nil {
+		return nil, nil
+	}
+	return fn(container, command)
+}
+
+func (r *FakeContainerRuntime) Run(ctx context.Context, opts *ContainerRunOptions) (string, error) {
+	if opts == nil || opts.Image == "" {
+		return "", ErrWrongInput
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.calls = append(r.calls, strings.Join(append([]string{"run", opts.Name, opts.Image}, opts.Command...), " "))
+
+	id := utilrand.String(12)
+	name := opts.Name
+	if name == "" {
+		name = id
+	}
+	if _, ok := r.containers[name]; ok {
+		return "", fmt.Errorf("%w: container %s", ErrAlreadyExisted, name)
+	}
+	r.containers[name] = &ContainerInfo{ID: id, Name: name, Image: opts.Image, Status: "running", Running: true}
+	return id, nil
+}
+
+func (r *FakeContainerRuntime) Remove(ctx context.Context, container string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, err := r.get(container, "rm", container); err != nil {
+		return err
+	}
+	delete(r.containers, container)
+	return nil
+}
+
+func (r *FakeContainerRuntime) Stop(ctx context.Context, container string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	c, err := r.get(container, "stop", container)
+	if err != nil {
+		return err
+	}
+	c.Status = "exited"
+	c.Running = false
+	c.Pid = 0
+	return nil
+}
+
+func (r *FakeContainerRuntime) Start(ctx context.Context, container string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	c, err := r.get(container, "start", container)
+	if err != nil {
+		return err
+	}
+	c.Status = "running"
+	c.Running = true
+	return nil
+}
+
+func (r *FakeContainerRuntime) Inspect(ctx context.Context, container string) (*ContainerInfo, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	c, err := r.get(container, "inspect", container)
+	if err != nil {
+		return nil, err
+	}
+	copied := *c
+	copied.Networks = map[string]ContainerNetwork{}
+	for k, v := range c.Networks {
+		copied.Networks[k] = v
+	}
+	return &copied, nil
+}
+
+func (r *FakeContainerRuntime) Logs(ctx context.Context, container string) ([]byte, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, err := r.get(container, "logs", container); err != nil {
+		return nil, err
+	}
+	return append([]byte{}, r.logs[container]...), nil
+}
@@ -0,0 +1,142 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	e2e "github.com/spidernet-io/e2eframework/framework"
+)
+
+// the fake podman prints the argv one per line, like the real one for the command of exec
+const fakePodmanScript = `#!/bin/sh
+case "$1" in
+inspect)
+  echo '[{"Id":"abc","Name":"kind-worker","ImageName":"kindest/node:v1.30","State":{"Status":"running","Running":true,"Pid":10},"NetworkSettings":{"Networks":{"kind":{"IPAddress":"172.18.0.2","GlobalIPv6Address":"fc00::2"}}}}]'
+  ;;
+exec)
+  shift 3
+  [ "$1" = "false" ] && { echo "failed" >&2; exit 3; }
+  printf '%s\n' "$@"
+  ;;
+run)
+  echo "new-container-id"
+  ;;
+rm)
+  echo "Error: no such container $3" >&2
+  exit 1
+  ;;
+*)
+  echo "$@"
+  ;;
+esac
+`
+
+var _ = Describe("test container runtime", Label("containerruntime"), func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 20*time.Second)
+		DeferCleanup(cancel)
+	})
+
+	It("run the cli without the shell", func() {
+		dir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "podman"), []byte(fakePodmanScript), 0o700)).To(Succeed())
+		GinkgoT().Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+		_, e := e2e.NewContainerRuntime("crio")
+		Expect(e).To(MatchError(e2e.ErrWrongInput))
+		r, e := e2e.NewContainerRuntime(e2e.ContainerRuntimePodman)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(r.Name()).To(Equal(e2e.ContainerRuntimePodman))
+
+		// the argument with the space and quote is kept
+		out, e := r.Exec(ctx, "kind-worker", []string{"sh", "-c", `echo "a b"; ls`})
+		Expect(e).NotTo(HaveOccurred())
+		Expect(strings.Split(strings.TrimSpace(string(out)), "\n")).To(Equal([]string{"sh", "-c", `echo "a b"; ls`}))
+
+		_, e = r.Exec(ctx, "kind-worker", []string{"false"})
+		var exitErr *e2e.ExecExitError
+		Expect(errors.As(e, &exitErr)).To(BeTrue())
+		Expect(exitErr.Code).To(Equal(3))
+		Expect(string(exitErr.Stderr)).To(ContainSubstring("failed"))
+
+		id, e := r.Run(ctx, &e2e.ContainerRunOptions{Name: "test", Image: "busybox", Command: []string{"sleep", "1d"}})
+		Expect(e).NotTo(HaveOccurred())
+		Expect(id).To(Equal("new-container-id"))
+
+		info, e := r.Inspect(ctx, "kind-worker")
+		Expect(e).NotTo(HaveOccurred())
+		Expect(info.Name).To(Equal("kind-worker"))
+		Expect(info.Image).To(Equal("kindest/node:v1.30"))
+		Expect(info.Running).To(BeTrue())
+		Expect(info.Networks).To(HaveKeyWithValue("kind", e2e.ContainerNetwork{IPv4: "172.18.0.2", IPv6: "fc00::2"}))
+
+		logs, e := r.Logs(ctx, "kind-worker")
+		Expect(e).NotTo(HaveOccurred())
+		Expect(string(logs)).To(Equal("logs kind-worker\n"))
+
+		Expect(r.Remove(ctx, "not-existed")).To(MatchError(e2e.ErrNoContainer))
+		Expect(r.Stop(ctx, "")).To(MatchError(e2e.ErrWrongInput))
+
+		// the config selects the runtime
+		f := fakeFramework()
+		f.Config.ContainerRuntime = e2e.ContainerRuntimePodman
+		r, e = f.ContainerRuntime()
+		Expect(e).NotTo(HaveOccurred())
+		Expect(r.Name()).To(Equal(e2e.ContainerRuntimePodman))
+		f.Config.ContainerRuntime = "crio"
+		Expect(f.Config.Validate()).To(HaveOccurred())
+	})
+
+	It("fake the runtime", func() {
+		r := e2e.NewFakeContainerRuntime("kind-control-plane", "kind-worker")
+		r.ExecFunc = func(container string, command []string) ([]byte, error) {
+			return []byte(container + ": " + strings.Join(command, " ")), nil
+		}
+		f := fakeFramework()
+		f.Runtime = r
+		runtime, e := f.ContainerRuntime()
+		Expect(e).NotTo(HaveOccurred())
+		Expect(runtime).To(BeIdenticalTo(r))
+
+		out, e := runtime.Exec(ctx, "kind-worker", []string{"ip", "link"})
+		Expect(e).NotTo(HaveOccurred())
+		Expect(string(out)).To(Equal("kind-worker: ip link"))
+
+		Expect(runtime.Stop(ctx, "kind-worker")).To(Succeed())
+		info, e := runtime.Inspect(ctx, "kind-worker")
+		Expect(e).NotTo(HaveOccurred())
+		Expect(info.Running).To(BeFalse())
+		_, e = runtime.Exec(ctx, "kind-worker", []string{"ip", "link"})
+		var exitErr *e2e.ExecExitError
+		Expect(errors.As(e, &exitErr)).To(BeTrue())
+		Expect(runtime.Start(ctx, "kind-worker")).To(Succeed())
+
+		_, e = runtime.Run(ctx, &e2e.ContainerRunOptions{Name: "kind-worker", Image: "busybox"})
+		Expect(e).To(MatchError(e2e.ErrAlreadyExisted))
+		id, e := runtime.Run(ctx, &e2e.ContainerRunOptions{Name: "client", Image: "busybox"})
+		Expect(e).NotTo(HaveOccurred())
+		Expect(id).NotTo(BeEmpty())
+
+		r.SetLogs("client", []byte("hello"))
+		logs, e := runtime.Logs(ctx, "client")
+		Expect(e).NotTo(HaveOccurred())
+		Expect(string(logs)).To(Equal("hello"))
+
+		Expect(runtime.Remove(ctx, "client")).To(Succeed())
+		Expect(runtime.Remove(ctx, "client")).To(MatchError(e2e.ErrNoContainer))
+
+		Expect(r.Calls()).To(ContainElements("exec kind-worker ip link", "stop kind-worker", "rm client"))
+	})
+})
@@ -0,0 +1,78 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	e2e "github.com/spidernet-io/e2eframework/framework"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("test context first api", Label("context"), func() {
+	var f *e2e.Framework
+	var namespace string
+
+	BeforeEach(func() {
+		f = fakeFramework()
+		namespace = "ns-context"
+	})
+
+	It("operate with SpecContext", func(ctx SpecContext) {
+		Expect(f.CreateNamespaceContext(ctx, namespace)).To(Succeed())
+		ns, e := f.GetNamespaceContext(ctx, namespace)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(ns.Name).To(Equal(namespace))
+
+		pod := generateExamplePodYaml("pod-context", namespace, map[string]string{"app": "context"}, corev1.PodRunning)
+		Expect(f.CreatePodContext(ctx, pod)).To(Succeed())
+		_, e = f.WaitPodStartedContext(ctx, pod.Name, namespace)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(f.WaitPodListRunningContext(ctx, pod.Labels, 1)).To(Succeed())
+
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm-context", Namespace: namespace}}
+		Expect(f.CreateConfigmapContext(ctx, cm)).To(Succeed())
+		Expect(f.DeleteConfigmapContext(ctx, cm.Name, namespace)).To(Succeed())
+
+		Expect(f.DeletePodUntilFinishContext(ctx, pod.Name, namespace)).To(Succeed())
+		Expect(f.DeleteNamespaceUntilFinishContext(ctx, namespace)).To(Succeed())
+	}, NodeTimeout(20*time.Second))
+
+	It("stop waiting when ctx is cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(time.Second)
+			cancel()
+		}()
+
+		start := time.Now()
+		_, e := f.WaitPodStartedContext(ctx, "not-existed", namespace)
+		Expect(e).To(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically("<", 3*time.Second))
+
+		_, e = f.GetPodContext(ctx, "not-existed", namespace)
+		Expect(e).To(HaveOccurred())
+
+		_, _, e = f.WaitJobFinishedContext(ctx, "not-existed", namespace)
+		Expect(e).To(HaveOccurred())
+	})
+
+	It("stop the create-after-delete waiting when ctx is cancelled", func() {
+		pod := generateExamplePodYaml("pod-deleting", "default", nil, corev1.PodRunning)
+		pod.Finalizers = []string{"e2e.spidernet.io/test"}
+		Expect(f.CreatePod(pod)).To(Succeed())
+		Expect(f.DeletePod(pod.Name, pod.Namespace)).To(Succeed())
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		start := time.Now()
+		e := f.CreatePodContext(ctx, generateExamplePodYaml("pod-deleting", "default", nil, corev1.PodRunning))
+		Expect(e).To(MatchError(e2e.ErrTimeOut))
+		Expect(time.Since(start)).To(BeNumerically("<", f.Config.ResourceDeleteTimeout))
+	})
+})
@@ -0,0 +1,216 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// the copy streams a tar archive over ExecInPod like "kubectl cp", so the container needs the tar binary.
+// The destination is the path of the copied file or directory, rather than its parent, like:
+//   err := f.CopyToPod(ctx, "./testdata/check.sh", name, namespace, "/tmp/check.sh", nil)
+//   err := f.CopyFromPod(ctx, name, namespace, "/var/log/cni", "./artifacts/cni", &framework.CopyOptions{Container: "agent"})
+// The file modes are kept, the symbolic link is copied only when it does not point out of the copied directory
+
+// CopyOptions is the options of CopyToPod and CopyFromPod, nil means the only container of the pod
+type CopyOptions struct {
+	// empty means the only container of the pod
+	Container string
+}
+
+// CopyToPod copies the local file or directory to remotePath in the container,
+// the parent directory of remotePath is created if missing
+func (f *Framework) CopyToPod(ctx context.Context, localPath, name, namespace, remotePath string, opts *CopyOptions) error {
+	if localPath == "" || name == "" || namespace == "" || !path.IsAbs(remotePath) || path.Clean(remotePath) == "/" {
+		return ErrWrongInput
+	}
+	if opts == nil {
+		opts = &CopyOptions{}
+	}
+	if _, err := os.Lstat(localPath); err != nil {
+		return err
+	}
+	remotePath = path.Clean(remotePath)
+	remoteDir, remoteBase := path.Split(remotePath)
+
+	if _, err := f.ExecInPod(ctx, name, namespace, []string{"mkdir", "-p", remoteDir}, &ExecOptions{Container: opts.Container}); err != nil {
+		return fmt.Errorf("failed to create directory %v in %v/%v: %w", remoteDir, namespace, name, err)
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(writeTar(writer, localPath, remoteBase))
+	}()
+	command := []string{"tar", "-xmf", "-", "-C", remoteDir}
+	_, err := f.ExecInPod(ctx, name, namespace, command, &ExecOptions{Container: opts.Container, Stdin: reader})
+	// stop the writer if tar exits early
+	reader.CloseWithError(io.ErrClosedPipe)
+	if err != nil {
+		return copyError(err, namespace, name)
+	}
+	f.Log("copied %v to %v/%v:%v \n", localPath, namespace, name, remotePath)
+	return nil
+}
+
+// CopyFromPod copies the file or directory remotePath in the container to localPath,
+// the parent directory of localPath is created if missing
+func (f *Framework) CopyFromPod(ctx context.Context, name, namespace, remotePath, localPath string, opts *CopyOptions) error {
+	if localPath == "" || name == "" || namespace == "" || !path.IsAbs(remotePath) || path.Clean(remotePath) == "/" {
+		return ErrWrongInput
+	}
+	if opts == nil {
+		opts = &CopyOptions{}
+	}
+	remotePath = path.Clean(remotePath)
+	remoteDir, remoteBase := path.Split(remotePath)
+
+	reader, writer := io.Pipe()
+	execErr := make(chan error, 1)
+	go func() {
+		command := []string{"tar", "-cf", "-", "-C", remoteDir, remoteBase}
+		_, err := f.ExecInPod(ctx, name, namespace, command, &ExecOptions{Container: opts.Container, Stdout: writer})
+		writer.Close()
+		execErr <- err
+	}()
+	untarErr := readTar(reader, remoteBase, localPath)
+	if untarErr == nil {
+		// the padding after the end of the archive
+		_, _ = io.Copy(io.Discard, reader)
+	}
+	// let the exec finish if the archive is broken
+	reader.CloseWithError(io.ErrClosedPipe)
+	if err := <-execErr; err != nil {
+		return copyError(err, namespace, name)
+	}
+	if untarErr != nil {
+		return fmt.Errorf("failed to extract %v/%v:%v to %v: %w", namespace, name, remotePath, localPath, untarErr)
+	}
+	f.Log("copied %v/%v:%v to %v \n", namespace, name, remotePath, localPath)
+	return nil
+}
+
+// copyError reports ErrNoTar when the container fails to start tar
+func copyError(err error, namespace, name string) error {
+	var exitErr *ExecExitError
+	if errors.As(err, &exitErr) && exitErr.Code != 126 && exitErr.Code != 127 {
+		return err
+	}
+	msg := err.Error()
+	if (exitErr != nil && exitErr.Command[0] == "tar") || strings.Contains(msg, `"tar": executable file not found`) {
+		return fmt.Errorf("%w: %v/%v: %v", ErrNoTar, namespace, name, msg)
+	}
+	return err
+}
+
+// writeTar archives src with the name prefix
+func writeTar(w io.Writer, src, prefix string) error {
+	tw := tar.NewWriter(w)
+	src = filepath.Clean(src)
+	err := filepath.Walk(src, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, file)
+		if err != nil {
+			return err
+		}
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(file); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = path.Join(prefix, filepath.ToSlash(rel))
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		// the files belong to the user of the container
+		header.Uid, header.Gid, header.Uname, header.Gname = 0, 0, "", ""
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		fd, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer fd.Close()
+		_, err = io.Copy(tw, fd)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// readTar extracts the entries under the name prefix to dest
+func readTar(r io.Reader, prefix, dest string) error {
+	tr := tar.NewReader(r)
+	dest = filepath.Clean(dest)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := path.Clean(header.Name)
+		if name != prefix && !strings.HasPrefix(name, prefix+"/") {
+			return fmt.Errorf("unexpected entry %q in the archive", header.Name)
+		}
+		target := filepath.Join(dest, filepath.FromSlash(strings.TrimPrefix(name, prefix)))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		mode := os.FileMode(header.Mode).Perm()
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, mode); err != nil {
+				return err
+			}
+			if err := os.Chmod(target, mode); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			fd, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(fd, tr)
+			fd.Close()
+			if err != nil {
+				return err
+			}
+			if err := os.Chmod(target, mode); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			// skip the link out of the copied directory
+			linkTarget := filepath.Join(filepath.Dir(target), header.Linkname)
+			if filepath.IsAbs(header.Linkname) || (linkTarget != dest && !strings.HasPrefix(linkTarget, dest+string(filepath.Separator))) {
+				continue
+			}
+			_ = os.Remove(target)
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		}
+	}
+}
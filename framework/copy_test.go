@@ -0,0 +1,76 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	e2e "github.com/spidernet-io/e2eframework/framework"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+var _ = Describe("test copy files with pod", Label("copy"), func() {
+	var f *e2e.Framework
+	var ctx context.Context
+	var localFile string
+
+	BeforeEach(func() {
+		f = fakeFramework()
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 20*time.Second)
+		DeferCleanup(cancel)
+
+		localFile = filepath.Join(GinkgoT().TempDir(), "check.sh")
+		Expect(os.WriteFile(localFile, []byte("#!/bin/sh\necho ok\n"), 0755)).To(Succeed())
+	})
+
+	It("check the input", func() {
+		Expect(f.CopyToPod(ctx, localFile, "pod", "default", "/tmp/check.sh", nil)).To(MatchError(e2e.ErrNoClientSet))
+		Expect(f.CopyFromPod(ctx, "pod", "default", "/tmp/check.sh", GinkgoT().TempDir(), nil)).To(MatchError(e2e.ErrNoClientSet))
+
+		Expect(f.CopyToPod(ctx, localFile, "pod", "default", "tmp/check.sh", nil)).To(MatchError(e2e.ErrWrongInput))
+		Expect(f.CopyToPod(ctx, localFile, "pod", "default", "/", nil)).To(MatchError(e2e.ErrWrongInput))
+		Expect(f.CopyFromPod(ctx, "pod", "", "/tmp/check.sh", "/tmp", nil)).To(MatchError(e2e.ErrWrongInput))
+
+		e := f.CopyToPod(ctx, filepath.Join(GinkgoT().TempDir(), "missing"), "pod", "default", "/tmp/check.sh", nil)
+		Expect(os.IsNotExist(e)).To(BeTrue())
+	})
+
+	It("exec in the chosen container", func() {
+		lock := sync.Mutex{}
+		var commands, containers []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			defer lock.Unlock()
+			commands = append(commands, r.URL.Query()["command"]...)
+			containers = append(containers, r.URL.Query()["container"]...)
+			http.Error(w, "exec is not supported", http.StatusForbidden)
+		}))
+		DeferCleanup(server.Close)
+
+		var e error
+		f.KConfig = &rest.Config{Host: server.URL}
+		f.KClientSet, e = kubernetes.NewForConfig(f.KConfig)
+		Expect(e).NotTo(HaveOccurred())
+
+		Expect(f.CopyToPod(ctx, localFile, "pod", "default", "/tmp/e2e/check.sh", &e2e.CopyOptions{Container: "agent"})).NotTo(Succeed())
+		e = f.CopyFromPod(ctx, "pod", "default", "/var/log/cni", GinkgoT().TempDir(), &e2e.CopyOptions{Container: "agent"})
+		Expect(e).To(HaveOccurred())
+		Expect(e).NotTo(MatchError(e2e.ErrNoTar))
+
+		lock.Lock()
+		defer lock.Unlock()
+		Expect(commands).To(ContainElements("mkdir", "/tmp/e2e/", "tar", "-C", "/var/log/", "cni"))
+		Expect(containers).To(HaveEach("agent"))
+	})
+})
@@ -4,53 +4,30 @@ package framework
 
 import (
 	"context"
-	"fmt"
-	"time"
 
-	"github.com/spidernet-io/e2eframework/tools"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	api_errors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/apimachinery/pkg/watch"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func (f *Framework) CreateDaemonSet(ds *appsv1.DaemonSet, opts ...client.CreateOption) error {
-	// try to wait for finish last deleting
-	fake := &appsv1.DaemonSet{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: ds.Namespace,
-			Name:      ds.Name,
-		},
-	}
+	return f.CreateDaemonSetContext(context.Background(), ds, opts...)
+}
 
-	key := client.ObjectKeyFromObject(fake)
-	existing := &appsv1.DaemonSet{}
-	e := f.GetResource(key, existing)
-	if e == nil && existing.DeletionTimestamp == nil {
-		return fmt.Errorf("%w: daemonset '%s/%s'", ErrAlreadyExisted, existing.Namespace, existing.Name)
-	}
-	t := func() bool {
-		existing := &appsv1.DaemonSet{}
-		e := f.GetResource(key, existing)
-		b := api_errors.IsNotFound(e)
-		if !b {
-			f.Log("waiting for a same DaemonSet %v/%v to finish deleting \n", ds.Name, ds.Namespace)
-			return false
-		}
-
-		return true
-	}
-	if !tools.Eventually(t, f.Config.ResourceDeleteTimeout, time.Second) {
-		return ErrTimeOut
+func (f *Framework) CreateDaemonSetContext(ctx context.Context, ds *appsv1.DaemonSet, opts ...client.CreateOption) error {
+	if ds == nil {
+		return ErrWrongInput
 	}
-	return f.CreateResource(ds, opts...)
+	return Create(ctx, f, ds, opts...)
 }
 
 func (f *Framework) DeleteDaemonSet(name, namespace string, opts ...client.DeleteOption) error {
+	return f.DeleteDaemonSetContext(context.Background(), name, namespace, opts...)
+}
+
+func (f *Framework) DeleteDaemonSetContext(ctx context.Context, name, namespace string, opts ...client.DeleteOption) error {
 
 	if name == "" || namespace == "" {
 		return ErrWrongInput
@@ -61,30 +38,25 @@ func (f *Framework) DeleteDaemonSet(name, namespace string, opts ...client.Delet
 			Name:      name,
 		},
 	}
-	return f.DeleteResource(ds, opts...)
+	return f.DeleteResourceContext(ctx, ds, opts...)
 }
 
 func (f *Framework) GetDaemonSet(name, namespace string) (*appsv1.DaemonSet, error) {
+	return f.GetDaemonSetContext(context.Background(), name, namespace)
+}
 
+func (f *Framework) GetDaemonSetContext(ctx context.Context, name, namespace string) (*appsv1.DaemonSet, error) {
 	if name == "" || namespace == "" {
 		return nil, ErrWrongInput
 	}
-	ds := &appsv1.DaemonSet{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: namespace,
-			Name:      name,
-		},
-	}
-	key := client.ObjectKeyFromObject(ds)
-	existing := &appsv1.DaemonSet{}
-	e := f.GetResource(key, existing)
-	if e != nil {
-		return nil, e
-	}
-	return existing, e
+	return Get[*appsv1.DaemonSet](ctx, f, name, namespace)
 }
 
 func (f *Framework) GetDaemonSetPodList(ds *appsv1.DaemonSet) (*corev1.PodList, error) {
+	return f.GetDaemonSetPodListContext(context.Background(), ds)
+}
+
+func (f *Framework) GetDaemonSetPodListContext(ctx context.Context, ds *appsv1.DaemonSet) (*corev1.PodList, error) {
 	if ds == nil {
 		return nil, ErrWrongInput
 	}
@@ -94,7 +66,7 @@ func (f *Framework) GetDaemonSetPodList(ds *appsv1.DaemonSet) (*corev1.PodList,
 			Selector: labels.SelectorFromSet(ds.Spec.Selector.MatchLabels),
 		},
 	}
-	e := f.ListResource(pods, ops...)
+	e := f.ListResourceContext(ctx, pods, ops...)
 	if e != nil {
 		return nil, e
 	}
@@ -102,53 +74,15 @@ func (f *Framework) GetDaemonSetPodList(ds *appsv1.DaemonSet) (*corev1.PodList,
 }
 
 func (f *Framework) WaitDaemonSetReady(name, namespace string, ctx context.Context) (*appsv1.DaemonSet, error) {
+	return f.WaitDaemonSetReadyContext(ctx, name, namespace)
+}
 
+func (f *Framework) WaitDaemonSetReadyContext(ctx context.Context, name, namespace string) (*appsv1.DaemonSet, error) {
 	if name == "" || namespace == "" {
 		return nil, ErrWrongInput
 	}
+	return waitReady(ctx, f, name, namespace, newObject[*appsv1.DaemonSet])
 
-	l := &client.ListOptions{
-		Namespace:     namespace,
-		FieldSelector: fields.OneTermEqualSelector("metadata.name", name),
-	}
-	watchInterface, err := f.KClient.Watch(ctx, &appsv1.DaemonSetList{}, l)
-	if err != nil {
-		return nil, ErrWatch
-	}
-	defer watchInterface.Stop()
-
-	for {
-		select {
-		// if ds not exist , got no event
-		case event, ok := <-watchInterface.ResultChan():
-			if !ok {
-				return nil, ErrChanelClosed
-			}
-			f.Log("DaemonSet %v/%v %v event \n", namespace, name, event.Type)
-
-			switch event.Type {
-			case watch.Error:
-				return nil, ErrEvent
-			case watch.Deleted:
-				return nil, ErrResDel
-			default:
-				ds, ok := event.Object.(*appsv1.DaemonSet)
-				if !ok {
-					return nil, ErrGetObj
-				}
-
-				if ds.Status.NumberReady == 0 {
-					break
-
-				} else if ds.Status.NumberReady == ds.Status.DesiredNumberScheduled {
-
-					return ds, nil
-				}
-			}
-		case <-ctx.Done():
-			return nil, ErrTimeOut
-		}
-	}
 }
 
 // Create Daemonset and wait for ready and check that the IP of the Pod is assigned correctly
@@ -157,31 +91,20 @@ func (f *Framework) CreateDaemonsetUntilReady(ctx context.Context, dsObj *appsv1
 		return nil, ErrWrongInput
 	}
 
-	err := f.CreateDaemonSet(dsObj, opts...)
+	err := f.CreateDaemonSetContext(ctx, dsObj, opts...)
 	if err != nil {
 		return nil, err
 	}
-	ds, err := f.WaitDaemonSetReady(dsObj.Name, dsObj.Namespace, ctx)
+	ds, err := f.WaitDaemonSetReadyContext(ctx, dsObj.Name, dsObj.Namespace)
 	if err != nil {
 		return nil, err
 	}
 	// Assignment of IPv4 or IPv6 address successful
-OUTER:
-	for {
-		time.Sleep(time.Second)
-		select {
-		case <-ctx.Done():
-			return nil, ErrTimeOut
-		default:
-			podList, err := f.GetPodListByLabel(ds.Spec.Selector.MatchLabels)
-			if err != nil {
-				return nil, err
-			}
-			err = f.CheckPodListIpReady(podList)
-			if err != nil {
-				continue OUTER
-			}
-			return podList, nil
-		}
+	podList, err := WaitList(ctx, f, func(podList *corev1.PodList) (bool, error) {
+		return f.CheckPodListIpReady(podList) == nil, nil
+	}, client.MatchingLabels(ds.Spec.Selector.MatchLabels))
+	if err != nil {
+		return nil, err
 	}
+	return podList, nil
 }
@@ -51,6 +51,9 @@ func generateExampleDaemonSetYaml(dsName, namespace string, numberReady, desired
 		// the fake clientset will not schedule daemonset replicaset， so mock the number
 		Status: appsv1.DaemonSetStatus{
 			NumberReady:            numberReady,
+			NumberAvailable:        numberReady,
+			CurrentNumberScheduled: desiredNumberScheduled,
+			UpdatedNumberScheduled: desiredNumberScheduled,
 			DesiredNumberScheduled: desiredNumberScheduled,
 		},
 	}
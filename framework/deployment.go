@@ -5,53 +5,33 @@ package framework
 import (
 	"context"
 	"errors"
-	"fmt"
 	"time"
 
-	"github.com/spidernet-io/e2eframework/tools"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	api_errors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
-	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func (f *Framework) CreateDeployment(dpm *appsv1.Deployment, opts ...client.CreateOption) error {
-	// try to wait for finish last deleting
-	fake := &appsv1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: dpm.Namespace,
-			Name:      dpm.Name,
-		},
-	}
-	key := client.ObjectKeyFromObject(fake)
-	existing := &appsv1.Deployment{}
-	e := f.GetResource(key, existing)
-	if e == nil && existing.DeletionTimestamp == nil {
-		return fmt.Errorf("%w: deployment '%s/%s'", ErrAlreadyExisted, existing.Namespace, existing.Name)
-	}
-	t := func() bool {
-		existing := &appsv1.Deployment{}
-		e := f.GetResource(key, existing)
-		b := api_errors.IsNotFound(e)
-		if !b {
-			f.Log("waiting for a same deployment %v/%v to finish deleting \n", dpm.Namespace, dpm.Name)
-			return false
-		}
-		return true
-	}
-	if !tools.Eventually(t, f.Config.ResourceDeleteTimeout, time.Second) {
-		return ErrTimeOut
+	return f.CreateDeploymentContext(context.Background(), dpm, opts...)
+}
+
+func (f *Framework) CreateDeploymentContext(ctx context.Context, dpm *appsv1.Deployment, opts ...client.CreateOption) error {
+	if dpm == nil {
+		return ErrWrongInput
 	}
-	return f.CreateResource(dpm, opts...)
+	return Create(ctx, f, dpm, opts...)
 }
 
 func (f *Framework) DeleteDeployment(name, namespace string, opts ...client.DeleteOption) error {
+	return f.DeleteDeploymentContext(context.Background(), name, namespace, opts...)
+}
+
+func (f *Framework) DeleteDeploymentContext(ctx context.Context, name, namespace string, opts ...client.DeleteOption) error {
 
 	if name == "" || namespace == "" {
 		return ErrWrongInput
@@ -63,31 +43,25 @@ func (f *Framework) DeleteDeployment(name, namespace string, opts ...client.Dele
 			Name:      name,
 		},
 	}
-	return f.DeleteResource(pod, opts...)
+	return f.DeleteResourceContext(ctx, pod, opts...)
 }
 
 func (f *Framework) GetDeployment(name, namespace string) (*appsv1.Deployment, error) {
+	return f.GetDeploymentContext(context.Background(), name, namespace)
+}
 
+func (f *Framework) GetDeploymentContext(ctx context.Context, name, namespace string) (*appsv1.Deployment, error) {
 	if name == "" || namespace == "" {
 		return nil, ErrWrongInput
 	}
-
-	dpm := &appsv1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: namespace,
-			Name:      name,
-		},
-	}
-	key := client.ObjectKeyFromObject(dpm)
-	existing := &appsv1.Deployment{}
-	e := f.GetResource(key, existing)
-	if e != nil {
-		return nil, e
-	}
-	return existing, e
+	return Get[*appsv1.Deployment](ctx, f, name, namespace)
 }
 
 func (f *Framework) GetDeploymentPodList(dpm *appsv1.Deployment) (*corev1.PodList, error) {
+	return f.GetDeploymentPodListContext(context.Background(), dpm)
+}
+
+func (f *Framework) GetDeploymentPodListContext(ctx context.Context, dpm *appsv1.Deployment) (*corev1.PodList, error) {
 
 	if dpm == nil {
 		return nil, ErrWrongInput
@@ -99,7 +73,7 @@ func (f *Framework) GetDeploymentPodList(dpm *appsv1.Deployment) (*corev1.PodLis
 			Selector: labels.SelectorFromSet(dpm.Spec.Selector.MatchLabels),
 		},
 	}
-	e := f.ListResource(pods, opts...)
+	e := f.ListResourceContext(ctx, pods, opts...)
 	if e != nil {
 		return nil, e
 	}
@@ -107,12 +81,16 @@ func (f *Framework) GetDeploymentPodList(dpm *appsv1.Deployment) (*corev1.PodLis
 }
 
 func (f *Framework) ScaleDeployment(dpm *appsv1.Deployment, replicas int32) (*appsv1.Deployment, error) {
+	return f.ScaleDeploymentContext(context.Background(), dpm, replicas)
+}
+
+func (f *Framework) ScaleDeploymentContext(ctx context.Context, dpm *appsv1.Deployment, replicas int32) (*appsv1.Deployment, error) {
 	if dpm == nil {
 		return nil, ErrWrongInput
 	}
 
 	dpm.Spec.Replicas = ptr.To(replicas)
-	err := f.UpdateResource(dpm)
+	err := f.UpdateResourceContext(ctx, dpm)
 	if err != nil {
 		return nil, err
 	}
@@ -120,65 +98,36 @@ func (f *Framework) ScaleDeployment(dpm *appsv1.Deployment, replicas int32) (*ap
 }
 
 func (f *Framework) WaitDeploymentReady(name, namespace string, ctx context.Context) (*appsv1.Deployment, error) {
+	return f.WaitDeploymentReadyContext(ctx, name, namespace)
+}
 
+func (f *Framework) WaitDeploymentReadyContext(ctx context.Context, name, namespace string) (*appsv1.Deployment, error) {
 	if name == "" || namespace == "" {
 		return nil, ErrWrongInput
 	}
+	return waitReady(ctx, f, name, namespace, newObject[*appsv1.Deployment])
 
-	l := &client.ListOptions{
-		Namespace:     namespace,
-		FieldSelector: fields.OneTermEqualSelector("metadata.name", name),
-	}
-	watchInterface, err := f.KClient.Watch(ctx, &appsv1.DeploymentList{}, l)
-	if err != nil {
-		return nil, ErrWatch
-	}
-	defer watchInterface.Stop()
-
-	for {
-		select {
-		case event, ok := <-watchInterface.ResultChan():
-			f.Log("deployment %v/%v\n", event, ok)
-			if !ok {
-				return nil, ErrChanelClosed
-			}
-			f.Log("deployment %v/%v %v event \n", namespace, name, event.Type)
-			switch event.Type {
-			case watch.Error:
-				return nil, ErrEvent
-			case watch.Deleted:
-				return nil, ErrResDel
-			default:
-				dpm, ok := event.Object.(*appsv1.Deployment)
-				if !ok {
-					return nil, ErrGetObj
-				}
-				f.Log("deployment %v/%v readyReplicas=%+v\n", namespace, name, dpm.Status.ReadyReplicas)
-				if dpm.Status.ReadyReplicas == *(dpm.Spec.Replicas) {
-					return dpm, nil
-				}
-			}
-		case <-ctx.Done():
-			return nil, ErrTimeOut
-		}
-	}
 }
 
 func (f *Framework) CreateDeploymentUntilReady(deployObj *appsv1.Deployment, timeOut time.Duration, opts ...client.CreateOption) (*appsv1.Deployment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	return f.CreateDeploymentUntilReadyContext(ctx, deployObj, opts...)
+}
+
+func (f *Framework) CreateDeploymentUntilReadyContext(ctx context.Context, deployObj *appsv1.Deployment, opts ...client.CreateOption) (*appsv1.Deployment, error) {
 	if deployObj == nil {
 		return nil, ErrWrongInput
 	}
 
 	// create deployment
-	err := f.CreateDeployment(deployObj, opts...)
+	err := f.CreateDeploymentContext(ctx, deployObj, opts...)
 	if err != nil {
 		return nil, err
 	}
 
 	// wait deployment ready
-	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
-	defer cancel()
-	deploy, e := f.WaitDeploymentReady(deployObj.Name, deployObj.Namespace, ctx)
+	deploy, e := f.WaitDeploymentReadyContext(ctx, deployObj.Name, deployObj.Namespace)
 	if e != nil {
 		return nil, e
 	}
@@ -186,58 +135,48 @@ func (f *Framework) CreateDeploymentUntilReady(deployObj *appsv1.Deployment, tim
 }
 
 func (f *Framework) DeleteDeploymentUntilFinish(deployName, namespace string, timeOut time.Duration, opts ...client.DeleteOption) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	return f.DeleteDeploymentUntilFinishContext(ctx, deployName, namespace, opts...)
+}
+
+func (f *Framework) DeleteDeploymentUntilFinishContext(ctx context.Context, deployName, namespace string, opts ...client.DeleteOption) error {
 	if deployName == "" || namespace == "" {
 		return ErrWrongInput
 	}
 	// get deployment
-	deployment, err1 := f.GetDeployment(deployName, namespace)
+	deployment, err1 := f.GetDeploymentContext(ctx, deployName, namespace)
 	if err1 != nil {
 		return err1
 	}
 	// delete deployment
-	err := f.DeleteDeployment(deployment.Name, deployment.Namespace, opts...)
+	err := f.DeleteDeploymentContext(ctx, deployment.Name, deployment.Namespace, opts...)
 	if err != nil {
 		return err
 	}
 	// check delete deployment successfully
-	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
-	defer cancel()
-	b, e := func() (bool, error) {
-		for {
-			select {
-			case <-ctx.Done():
-				return false, ErrTimeOut
-			default:
-				deployment, _ := f.GetDeployment(deployment.Name, deployment.Namespace)
-				if deployment == nil {
-					return true, nil
-				}
-				time.Sleep(time.Second)
-			}
-		}
-	}()
-	if b {
-		// check PodList not exists by label
-		err := f.WaitPodListDeleted(deployment.Namespace, deployment.Spec.Selector.MatchLabels, ctx)
-		if err != nil {
-			return err
-		}
-		return nil
+	if err := waitGone(ctx, f, deployment); err != nil {
+		return err
 	}
-	return e
+	// check PodList not exists by label
+	return f.WaitPodListDeletedContext(ctx, deployment.Namespace, deployment.Spec.Selector.MatchLabels)
 }
 
 func (f *Framework) WaitDeploymentReadyAndCheckIP(depName string, nsName string, timeout time.Duration) (*corev1.PodList, error) {
-	// waiting for Deployment replicas to complete
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
-	dep, e := f.WaitDeploymentReady(depName, nsName, ctx)
+	return f.WaitDeploymentReadyAndCheckIPContext(ctx, depName, nsName)
+}
+
+func (f *Framework) WaitDeploymentReadyAndCheckIPContext(ctx context.Context, depName string, nsName string) (*corev1.PodList, error) {
+	// waiting for Deployment replicas to complete
+	dep, e := f.WaitDeploymentReadyContext(ctx, depName, nsName)
 	if e != nil {
 		return nil, e
 	}
 
 	// check pods created by Deployment，its assign ipv4 and ipv6 addresses success
-	podlist, err := f.GetDeploymentPodList(dep)
+	podlist, err := f.GetDeploymentPodListContext(ctx, dep)
 	if err != nil {
 		return nil, err
 	}
@@ -251,18 +190,24 @@ func (f *Framework) WaitDeploymentReadyAndCheckIP(depName string, nsName string,
 }
 
 func (f *Framework) RestartDeploymentPodUntilReady(deployName, namespace string, timeOut time.Duration, opts ...client.DeleteOption) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	return f.RestartDeploymentPodUntilReadyContext(ctx, deployName, namespace, opts...)
+}
+
+func (f *Framework) RestartDeploymentPodUntilReadyContext(ctx context.Context, deployName, namespace string, opts ...client.DeleteOption) error {
 	if deployName == "" || namespace == "" {
 		return ErrWrongInput
 	}
 
-	deployment, err := f.GetDeployment(deployName, namespace)
+	deployment, err := f.GetDeploymentContext(ctx, deployName, namespace)
 	if deployment == nil {
 		return errors.New("failed to get deployment")
 	}
 	if err != nil {
 		return err
 	}
-	podList, err := f.GetDeploymentPodList(deployment)
+	podList, err := f.GetDeploymentPodListContext(ctx, deployment)
 
 	if len(podList.Items) == 0 {
 		return errors.New("failed to get podList")
@@ -270,7 +215,7 @@ func (f *Framework) RestartDeploymentPodUntilReady(deployName, namespace string,
 	if err != nil {
 		return err
 	}
-	_, err = f.DeletePodListUntilReady(podList, timeOut, opts...)
+	_, err = f.DeletePodListUntilReadyContext(ctx, podList, opts...)
 	if err != nil {
 		return err
 	}
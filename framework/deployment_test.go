@@ -52,8 +52,10 @@ func GenerateExampleDeploymentYaml(dpmName, namespace string, replica, readyRepl
 		},
 		//the fake clientset will not schedule deployment replicaset,so mock the number
 		Status: appsv1.DeploymentStatus{
-			ReadyReplicas: readyReplica,
-			Replicas:      replica,
+			ReadyReplicas:     readyReplica,
+			AvailableReplicas: readyReplica,
+			UpdatedReplicas:   replica,
+			Replicas:          replica,
 		},
 	}
 }
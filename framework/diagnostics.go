@@ -0,0 +1,410 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	nadv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	"github.com/onsi/ginkgo/v2/types"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/yaml"
+)
+
+// the diagnostics of a failed spec are written to the artifacts directory, like:
+//   <ArtifactsDir>/<spec>/nodes.txt
+//   <ArtifactsDir>/<spec>/namespaces/<namespace>/pods.yaml
+//   <ArtifactsDir>/<spec>/namespaces/<namespace>/describe/<pod>.txt
+//   <ArtifactsDir>/<spec>/namespaces/<namespace>/logs/<pod>_<container>.log
+//   <ArtifactsDir>/<spec>/spiderpool/spiderippools.yaml
+// CreateTestNamespace dumps its namespace before deleting it when the spec fails,
+// and other namespaces could be dumped in the ReportAfterEach, like:
+//   ReportAfterEach(func(ctx SpecContext, r SpecReport) {
+//       f.DumpDiagnosticsOnFailure(ctx, r, "kube-system")
+//   })
+
+const (
+	E2E_ARTIFACTS_DIR = "E2E_ARTIFACTS_DIR"
+
+	// the tail lines of each container log in the diagnostics
+	DiagnosticsLogTailLines int64 = 2000
+)
+
+var (
+	SpiderIPPoolGVK   = schema.GroupVersionKind{Group: "spiderpool.spidernet.io", Version: "v2beta1", Kind: "SpiderIPPool"}
+	SpiderEndpointGVK = schema.GroupVersionKind{Group: "spiderpool.spidernet.io", Version: "v2beta1", Kind: "SpiderEndpoint"}
+)
+
+// the failed optional interface of TestingT, implemented by GinkgoT() and testing.T
+type failedT interface {
+	Failed() bool
+}
+
+// the max length of the readable part of the spec directory
+const artifactsDirNameLength = 100
+
+// ArtifactsDirFor returns the diagnostics directory of a spec under Config.ArtifactsDir, or "" if it is not set.
+// The directory is the spec text with a short hash of it, like "test_dump_1a2b3c4d", so the specs never share it
+func (f *Framework) ArtifactsDirFor(spec string) string {
+	if f.Config.ArtifactsDir == "" {
+		return ""
+	}
+	name := strings.Trim(invalidLabelValueChar.ReplaceAllString(spec, "_"), "_.-")
+	if len(name) > artifactsDirNameLength {
+		name = name[:artifactsDirNameLength]
+	}
+	if name == "" {
+		name = "unknown"
+	}
+	sum := sha256.Sum256([]byte(spec))
+	return filepath.Join(f.Config.ArtifactsDir, name+"_"+hex.EncodeToString(sum[:4]))
+}
+
+// DumpDiagnosticsOnFailure dumps the namespaces into the directory of the report by ArtifactsDirFor, for ReportAfterEach.
+// Nothing is dumped for the spec not failed, or without Config.ArtifactsDir
+func (f *Framework) DumpDiagnosticsOnFailure(ctx context.Context, report types.SpecReport, namespaces ...string) error {
+	if !report.Failed() || f.Config.ArtifactsDir == "" {
+		return nil
+	}
+	return f.DumpDiagnostics(ctx, f.ArtifactsDirFor(report.FullText()), namespaces...)
+}
+
+// DumpDiagnostics writes the state of the namespaces and the cluster into dir.
+// It dumps as much as possible, and returns all the errors at once
+func (f *Framework) DumpDiagnostics(ctx context.Context, dir string, namespaces ...string) error {
+	if dir == "" {
+		return ErrWrongInput
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f.Log("dump diagnostics to %v \n", dir)
+
+	var errs []error
+	errs = append(errs, f.dumpNodes(ctx, dir))
+	for _, ns := range namespaces {
+		if ns == "" {
+			continue
+		}
+		errs = append(errs, f.dumpNamespace(ctx, filepath.Join(dir, "namespaces", ns), ns))
+	}
+	if f.Info.SpiderIPAMEnabled {
+		errs = append(errs, f.dumpSpiderpool(ctx, filepath.Join(dir, "spiderpool"), namespaces))
+	}
+	err := errors.Join(errs...)
+	if err != nil {
+		_ = os.WriteFile(filepath.Join(dir, "errors.txt"), []byte(err.Error()+"\n"), 0644)
+	}
+	return err
+}
+
+func (f *Framework) dumpNodes(ctx context.Context, dir string) error {
+	nodes := &corev1.NodeList{}
+	if err := f.ListResourceContext(ctx, nodes); err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+	var b strings.Builder
+	for _, node := range nodes.Items {
+		fmt.Fprintf(&b, "Node: %v\n", node.Name)
+		fmt.Fprintf(&b, "  PodCIDRs: %v\n", node.Spec.PodCIDRs)
+		fmt.Fprintf(&b, "  Unschedulable: %v\n", node.Spec.Unschedulable)
+		for _, t := range node.Spec.Taints {
+			fmt.Fprintf(&b, "  Taint: %v\n", t.ToString())
+		}
+		for _, addr := range node.Status.Addresses {
+			fmt.Fprintf(&b, "  Address: %v=%v\n", addr.Type, addr.Address)
+		}
+		for _, c := range node.Status.Conditions {
+			fmt.Fprintf(&b, "  Condition: %v=%v reason=%v message=%v\n", c.Type, c.Status, c.Reason, c.Message)
+		}
+	}
+	return os.WriteFile(filepath.Join(dir, "nodes.txt"), []byte(b.String()), 0644)
+}
+
+// DiagnosticsFallbackResources are dumped for every namespace when the discovery is not available
+var DiagnosticsFallbackResources = map[string]schema.GroupVersionKind{
+	"services":                          corev1.SchemeGroupVersion.WithKind("Service"),
+	"endpoints":                         corev1.SchemeGroupVersion.WithKind("Endpoints"),
+	"configmaps":                        corev1.SchemeGroupVersion.WithKind("ConfigMap"),
+	"secrets":                           corev1.SchemeGroupVersion.WithKind("Secret"),
+	"serviceaccounts":                   corev1.SchemeGroupVersion.WithKind("ServiceAccount"),
+	"persistentvolumeclaims":            corev1.SchemeGroupVersion.WithKind("PersistentVolumeClaim"),
+	"deployments.apps":                  appsv1.SchemeGroupVersion.WithKind("Deployment"),
+	"replicasets.apps":                  appsv1.SchemeGroupVersion.WithKind("ReplicaSet"),
+	"statefulsets.apps":                 appsv1.SchemeGroupVersion.WithKind("StatefulSet"),
+	"daemonsets.apps":                   appsv1.SchemeGroupVersion.WithKind("DaemonSet"),
+	"jobs.batch":                        batchv1.SchemeGroupVersion.WithKind("Job"),
+	"networkpolicies.networking.k8s.io": networkingv1.SchemeGroupVersion.WithKind("NetworkPolicy"),
+	"ingresses.networking.k8s.io":       networkingv1.SchemeGroupVersion.WithKind("Ingress"),
+	"network-attachment-definitions.k8s.cni.cncf.io": nadv1.SchemeGroupVersion.WithKind("NetworkAttachmentDefinition"),
+}
+
+func (f *Framework) dumpNamespace(ctx context.Context, dir, namespace string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	var errs []error
+	pods := &corev1.PodList{}
+	if err := f.ListResourceContext(ctx, pods, client.InNamespace(namespace)); err != nil {
+		errs = append(errs, fmt.Errorf("failed to list pods in %v: %w", namespace, err))
+	} else {
+		errs = append(errs, f.writeYaml(filepath.Join(dir, "pods.yaml"), pods))
+	}
+
+	resources, err := f.namespacedResources()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to discover the namespaced resources: %w", err))
+	}
+	for name, gvk := range resources {
+		list, err := f.ListUnstructured(ctx, gvk, client.InNamespace(namespace))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to list %v in %v: %w", name, namespace, err))
+			continue
+		}
+		if len(list.Items) == 0 {
+			continue
+		}
+		if gvk.GroupKind() == corev1.SchemeGroupVersion.WithKind("Secret").GroupKind() {
+			// only the metadata of the secrets
+			for n := range list.Items {
+				unstructured.RemoveNestedField(list.Items[n].Object, "data")
+				unstructured.RemoveNestedField(list.Items[n].Object, "stringData")
+			}
+		}
+		errs = append(errs, f.writeYaml(filepath.Join(dir, name+".yaml"), list))
+	}
+
+	events := &corev1.EventList{}
+	if err := f.ListResourceContext(ctx, events, client.InNamespace(namespace)); err != nil {
+		errs = append(errs, fmt.Errorf("failed to list events in %v: %w", namespace, err))
+	} else {
+		errs = append(errs, os.WriteFile(filepath.Join(dir, "events.txt"), []byte(formatEvents(events.Items)), 0644))
+	}
+
+	for n := range pods.Items {
+		pod := &pods.Items[n]
+		errs = append(errs, writeFile(filepath.Join(dir, "describe", pod.Name+".txt"), describePod(pod, events.Items)))
+		errs = append(errs, f.dumpPodLogs(ctx, filepath.Join(dir, "logs"), pod))
+	}
+	return errors.Join(errs...)
+}
+
+// namespacedResources returns the listable namespaced resources named like "deployments.apps",
+// except the pods and events which are dumped on their own.
+// The resources of the groups failing the discovery are skipped, with the error returned
+func (f *Framework) namespacedResources() (map[string]schema.GroupVersionKind, error) {
+	if f.KClientSet == nil {
+		resources := map[string]schema.GroupVersionKind{}
+		for name, gvk := range DiagnosticsFallbackResources {
+			if gvk.Group == nadv1.SchemeGroupVersion.Group && !f.Info.MultusEnabled {
+				continue
+			}
+			resources[name] = gvk
+		}
+		return resources, nil
+	}
+	lists, err := f.KClientSet.Discovery().ServerPreferredNamespacedResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, err
+	}
+	resources := map[string]schema.GroupVersionKind{}
+	for _, l := range lists {
+		gv, e := schema.ParseGroupVersion(l.GroupVersion)
+		if e != nil {
+			continue
+		}
+		for _, r := range l.APIResources {
+			if strings.Contains(r.Name, "/") || r.Name == "pods" || r.Name == "events" || !slices.Contains(r.Verbs, "list") {
+				continue
+			}
+			name := r.Name
+			if gv.Group != "" {
+				name += "." + gv.Group
+			}
+			resources[name] = gv.WithKind(r.Kind)
+		}
+	}
+	return resources, err
+}
+
+func (f *Framework) dumpPodLogs(ctx context.Context, dir string, pod *corev1.Pod) error {
+	if f.KClientSet == nil {
+		return nil
+	}
+	var errs []error
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, s := range statuses {
+		if s.State.Waiting == nil || s.RestartCount > 0 {
+			errs = append(errs, f.dumpContainerLog(ctx, filepath.Join(dir, pod.Name+"_"+s.Name+".log"), pod, s.Name, false))
+		}
+		if s.RestartCount > 0 {
+			errs = append(errs, f.dumpContainerLog(ctx, filepath.Join(dir, pod.Name+"_"+s.Name+".previous.log"), pod, s.Name, true))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (f *Framework) dumpContainerLog(ctx context.Context, path string, pod *corev1.Pod, container string, previous bool) error {
+	tail := DiagnosticsLogTailLines
+	logs, err := f.GetPodLogs(ctx, pod.Name, pod.Namespace, &corev1.PodLogOptions{
+		Container: container,
+		Previous:  previous,
+		TailLines: &tail,
+	})
+	if err != nil {
+		return err
+	}
+	return writeFile(path, logs)
+}
+
+func (f *Framework) dumpSpiderpool(ctx context.Context, dir string, namespaces []string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	var errs []error
+	pools, err := f.ListUnstructured(ctx, SpiderIPPoolGVK)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to list spiderippools: %w", err))
+	} else {
+		errs = append(errs, f.writeYaml(filepath.Join(dir, "spiderippools.yaml"), pools))
+	}
+	endpoints := &unstructured.UnstructuredList{Object: map[string]interface{}{"kind": "List", "apiVersion": "v1"}}
+	for _, ns := range namespaces {
+		if ns == "" {
+			continue
+		}
+		l, err := f.ListUnstructured(ctx, SpiderEndpointGVK, client.InNamespace(ns))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to list spiderendpoints in %v: %w", ns, err))
+			continue
+		}
+		endpoints.Items = append(endpoints.Items, l.Items...)
+	}
+	errs = append(errs, f.writeYaml(filepath.Join(dir, "spiderendpoints.yaml"), endpoints))
+	return errors.Join(errs...)
+}
+
+// writeYaml fills the apiVersion and kind of the typed items, which the client leaves empty
+func (f *Framework) writeYaml(path string, list client.ObjectList) error {
+	if _, ok := list.(*unstructured.UnstructuredList); !ok {
+		if gvk, err := apiutil.GVKForObject(list, f.KClient.Scheme()); err == nil {
+			list.GetObjectKind().SetGroupVersionKind(gvk)
+			itemGVK := gvk.GroupVersion().WithKind(strings.TrimSuffix(gvk.Kind, "List"))
+			items, _ := meta.ExtractList(list)
+			for _, item := range items {
+				item.GetObjectKind().SetGroupVersionKind(itemGVK)
+			}
+		}
+	}
+	data, err := yaml.Marshal(list)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, string(data))
+}
+
+func writeFile(path, data string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(data), 0644)
+}
+
+// describePod returns the summary of pod like "kubectl describe pod"
+func describePod(pod *corev1.Pod, events []corev1.Event) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Name: %v\n", pod.Name)
+	fmt.Fprintf(&b, "Namespace: %v\n", pod.Namespace)
+	fmt.Fprintf(&b, "Node: %v\n", pod.Spec.NodeName)
+	fmt.Fprintf(&b, "Phase: %v\n", pod.Status.Phase)
+	if pod.Status.Reason != "" {
+		fmt.Fprintf(&b, "Reason: %v\n", pod.Status.Reason)
+		fmt.Fprintf(&b, "Message: %v\n", pod.Status.Message)
+	}
+	fmt.Fprintf(&b, "Labels: %v\n", pod.Labels)
+	fmt.Fprintf(&b, "Annotations: %v\n", pod.Annotations)
+	ips := []string{}
+	for _, ip := range pod.Status.PodIPs {
+		ips = append(ips, ip.IP)
+	}
+	fmt.Fprintf(&b, "IPs: %v\n", ips)
+	if pod.DeletionTimestamp != nil {
+		fmt.Fprintf(&b, "Terminating: since %v\n", pod.DeletionTimestamp)
+	}
+	fmt.Fprintf(&b, "Conditions:\n")
+	for _, c := range pod.Status.Conditions {
+		fmt.Fprintf(&b, "  %v=%v reason=%v message=%v\n", c.Type, c.Status, c.Reason, c.Message)
+	}
+	fmt.Fprintf(&b, "Containers:\n")
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, s := range statuses {
+		fmt.Fprintf(&b, "  %v: image=%v ready=%v restarts=%v\n", s.Name, s.Image, s.Ready, s.RestartCount)
+		fmt.Fprintf(&b, "    State: %v\n", containerState(s.State))
+		if s.RestartCount > 0 {
+			fmt.Fprintf(&b, "    Last State: %v\n", containerState(s.LastTerminationState))
+		}
+	}
+	fmt.Fprintf(&b, "Events:\n")
+	podEvents := []corev1.Event{}
+	for _, e := range events {
+		if e.InvolvedObject.Kind == "Pod" && e.InvolvedObject.Name == pod.Name {
+			podEvents = append(podEvents, e)
+		}
+	}
+	b.WriteString(formatEvents(podEvents))
+	return b.String()
+}
+
+func containerState(s corev1.ContainerState) string {
+	switch {
+	case s.Running != nil:
+		return fmt.Sprintf("Running since %v", s.Running.StartedAt)
+	case s.Waiting != nil:
+		return fmt.Sprintf("Waiting reason=%v message=%v", s.Waiting.Reason, s.Waiting.Message)
+	case s.Terminated != nil:
+		return fmt.Sprintf("Terminated reason=%v exitCode=%v message=%v", s.Terminated.Reason, s.Terminated.ExitCode, s.Terminated.Message)
+	}
+	return "Unknown"
+}
+
+// formatEvents sorts the events by the last time
+func formatEvents(events []corev1.Event) string {
+	sort.SliceStable(events, func(i, j int) bool {
+		return eventTime(&events[i]).Before(eventTime(&events[j]))
+	})
+	var b strings.Builder
+	for _, e := range events {
+		fmt.Fprintf(&b, "  %v %v %v/%v %v: %v (x%v)\n", eventTime(&e).Format("15:04:05"), e.Type, e.InvolvedObject.Kind, e.InvolvedObject.Name, e.Reason, e.Message, e.Count)
+	}
+	return b.String()
+}
+
+// eventTime is the EventTime of the events.k8s.io events, or the LastTimestamp, FirstTimestamp
+// and CreationTimestamp of the core events, whichever is set first
+func eventTime(e *corev1.Event) time.Time {
+	for _, t := range []time.Time{e.EventTime.Time, e.LastTimestamp.Time, e.FirstTimestamp.Time} {
+		if !t.IsZero() {
+			return t
+		}
+	}
+	return e.CreationTimestamp.Time
+}
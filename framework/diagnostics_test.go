@@ -0,0 +1,187 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	"github.com/onsi/ginkgo/v2/types"
+	. "github.com/onsi/gomega"
+
+	e2e "github.com/spidernet-io/e2eframework/framework"
+	spiderv2beta1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v2beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+// fakePreferredDiscovery serves the resources as the preferred ones, which the FakeDiscovery leaves empty
+type fakePreferredDiscovery struct {
+	*fakediscovery.FakeDiscovery
+}
+
+func (d *fakePreferredDiscovery) ServerPreferredNamespacedResources() ([]*metav1.APIResourceList, error) {
+	return d.Resources, nil
+}
+
+type fakeDiscoveryClientSet struct {
+	kubernetes.Interface
+	discovery discovery.DiscoveryInterface
+}
+
+func (c *fakeDiscoveryClientSet) Discovery() discovery.DiscoveryInterface {
+	return c.discovery
+}
+
+var _ = Describe("test diagnostics", Label("diagnostics"), func() {
+	var f *e2e.Framework
+	var ctx context.Context
+	var dir string
+
+	BeforeEach(func() {
+		f = fakeFramework()
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 20*time.Second)
+		DeferCleanup(cancel)
+		dir = GinkgoT().TempDir()
+		f.Config.ArtifactsDir = dir
+	})
+
+	It("dump the namespace and cluster", func() {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "master"}}
+		node.Status.Conditions = []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}}
+		Expect(f.CreateResource(node)).To(Succeed())
+
+		pod := generateExamplePodYaml("pod-diag", "default", nil, corev1.PodPending)
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name:         "test",
+			RestartCount: 2,
+			State: corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"},
+			},
+		}}
+		Expect(f.CreatePod(pod)).To(Succeed())
+		event := &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "pod-diag.1", Namespace: "default"},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "pod-diag", Namespace: "default"},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "FailedCreatePodSandBox",
+			Message:        "failed to allocate ip",
+		}
+		Expect(f.CreateResource(event)).To(Succeed())
+		// the events.k8s.io event only has the EventTime
+		later := &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "pod-diag.2", Namespace: "default"},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "pod-diag", Namespace: "default"},
+			Type:           corev1.EventTypeNormal,
+			Reason:         "Killing",
+			EventTime:      metav1.NewMicroTime(time.Now().Add(time.Hour)),
+		}
+		event.LastTimestamp = metav1.Now()
+		Expect(f.UpdateResource(event)).To(Succeed())
+		Expect(f.CreateResource(later)).To(Succeed())
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "secret-diag", Namespace: "default"},
+			Data:       map[string][]byte{"token": []byte("secret-value")},
+		}
+		Expect(f.CreateResource(secret)).To(Succeed())
+		pool := &spiderv2beta1.SpiderIPPool{ObjectMeta: metav1.ObjectMeta{Name: "pool-diag"}}
+		Expect(f.CreateResource(pool)).To(Succeed())
+
+		clientSet := k8sfake.NewSimpleClientset()
+		clientSet.Resources = []*metav1.APIResourceList{
+			{GroupVersion: "v1", APIResources: []metav1.APIResource{
+				{Name: "pods", Kind: "Pod", Namespaced: true, Verbs: metav1.Verbs{"list"}},
+				{Name: "pods/log", Kind: "Pod", Namespaced: true, Verbs: metav1.Verbs{"get"}},
+				{Name: "secrets", Kind: "Secret", Namespaced: true, Verbs: metav1.Verbs{"list"}},
+				{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: metav1.Verbs{"list"}},
+				{Name: "events", Kind: "Event", Namespaced: true, Verbs: metav1.Verbs{"list"}},
+			}},
+			{GroupVersion: "spiderpool.spidernet.io/v2beta1", APIResources: []metav1.APIResource{
+				{Name: "spiderendpoints", Kind: "SpiderEndpoint", Namespaced: true, Verbs: metav1.Verbs{"list"}},
+			}},
+		}
+		f.KClientSet = &fakeDiscoveryClientSet{
+			Interface: clientSet,
+			discovery: &fakePreferredDiscovery{FakeDiscovery: clientSet.Discovery().(*fakediscovery.FakeDiscovery)},
+		}
+		specDir := f.ArtifactsDirFor("test diagnostics dump")
+		Expect(specDir).To(HavePrefix(filepath.Join(dir, "test_diagnostics_dump_")))
+		Expect(f.DumpDiagnostics(ctx, specDir, "default")).To(Succeed())
+
+		data, e := os.ReadFile(filepath.Join(specDir, "nodes.txt"))
+		Expect(e).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("Condition: Ready=True"))
+
+		data, e = os.ReadFile(filepath.Join(specDir, "namespaces", "default", "pods.yaml"))
+		Expect(e).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("name: pod-diag"))
+		Expect(string(data)).To(ContainSubstring("kind: Pod"))
+
+		data, e = os.ReadFile(filepath.Join(specDir, "namespaces", "default", "describe", "pod-diag.txt"))
+		Expect(e).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("CrashLoopBackOff"))
+		Expect(string(data)).To(ContainSubstring("failed to allocate ip"))
+
+		data, e = os.ReadFile(filepath.Join(specDir, "namespaces", "default", "logs", "pod-diag_test.previous.log"))
+		Expect(e).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("fake logs"))
+
+		data, e = os.ReadFile(filepath.Join(specDir, "namespaces", "default", "events.txt"))
+		Expect(e).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("FailedCreatePodSandBox"))
+		Expect(strings.Index(string(data), "FailedCreatePodSandBox")).To(BeNumerically("<", strings.Index(string(data), "Killing")))
+
+		data, e = os.ReadFile(filepath.Join(specDir, "namespaces", "default", "secrets.yaml"))
+		Expect(e).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("name: secret-diag"))
+		Expect(string(data)).NotTo(ContainSubstring("token"))
+		_, e = os.Stat(filepath.Join(specDir, "namespaces", "default", "events.yaml"))
+		Expect(os.IsNotExist(e)).To(BeTrue())
+		_, e = os.Stat(filepath.Join(specDir, "namespaces", "default", "spiderendpoints.spiderpool.spidernet.io.yaml"))
+		Expect(os.IsNotExist(e)).To(BeTrue())
+
+		data, e = os.ReadFile(filepath.Join(specDir, "spiderpool", "spiderippools.yaml"))
+		Expect(e).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("name: pool-diag"))
+		_, e = os.Stat(filepath.Join(specDir, "spiderpool", "spiderendpoints.yaml"))
+		Expect(e).NotTo(HaveOccurred())
+
+		Expect(f.DumpDiagnostics(ctx, "")).To(MatchError(e2e.ErrWrongInput))
+	})
+
+	It("never share the spec directory", func() {
+		prefix := strings.Repeat("a long spec text ", 10)
+		a, b := f.ArtifactsDirFor(prefix+"one"), f.ArtifactsDirFor(prefix+"two")
+		Expect(a).NotTo(Equal(b))
+		Expect(f.ArtifactsDirFor("a b")).NotTo(Equal(f.ArtifactsDirFor("a_b")))
+		Expect(f.ArtifactsDirFor(prefix + "one")).To(Equal(a))
+		Expect(len(filepath.Base(a))).To(BeNumerically("<", 255))
+	})
+
+	It("dump only for the failed spec", func() {
+		report := types.SpecReport{ContainerHierarchyTexts: []string{"test diagnostics"}, LeafNodeText: "report", State: types.SpecStatePassed}
+		Expect(f.DumpDiagnosticsOnFailure(ctx, report)).To(Succeed())
+		_, e := os.Stat(f.ArtifactsDirFor(report.FullText()))
+		Expect(os.IsNotExist(e)).To(BeTrue())
+
+		report.State = types.SpecStateFailed
+		Expect(f.DumpDiagnosticsOnFailure(ctx, report)).To(Succeed())
+		_, e = os.Stat(filepath.Join(f.ArtifactsDirFor("test diagnostics report"), "nodes.txt"))
+		Expect(e).NotTo(HaveOccurred())
+	})
+
+	It("no artifacts directory", func() {
+		f.Config.ArtifactsDir = ""
+		Expect(f.ArtifactsDirFor("spec")).To(BeEmpty())
+		Expect(f.DumpDiagnosticsOnFailure(ctx, types.SpecReport{State: types.SpecStateFailed})).To(Succeed())
+	})
+})
@@ -0,0 +1,192 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	api_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// move the pods away from the node like `kubectl drain`, like:
+//   pods, err := f.DrainNode(ctx, "worker", &DrainOptions{Timeout: 3 * time.Minute})
+// the node is cordoned and restored when the spec finishes, see SaveNodeState
+
+// DefaultEvictionRetryInterval is the interval of retrying the eviction refused by the PodDisruptionBudget
+var DefaultEvictionRetryInterval = time.Second
+
+// PodNodeNameField is the field selector of the pods on the node
+const PodNodeNameField = "spec.nodeName"
+
+// DrainOptions configures DrainNode and EvictPod
+type DrainOptions struct {
+	// the grace period of the evicted pod, nil means the one of the pod
+	GracePeriodSeconds *int64
+	// the timeout of the whole drain, 0 means Config.ResourceDeleteTimeout
+	Timeout time.Duration
+	// the interval of retrying the eviction refused by the PodDisruptionBudget, 0 means DefaultEvictionRetryInterval
+	RetryInterval time.Duration
+	// PodSelector selects the evicted pods, nil means all
+	PodSelector map[string]string
+}
+
+// CordonNode marks the node unschedulable, after saving the node state
+func (f *Framework) CordonNode(ctx context.Context, name string) error {
+	return f.setNodeUnschedulable(ctx, name, true)
+}
+
+// UncordonNode marks the node schedulable, after saving the node state
+func (f *Framework) UncordonNode(ctx context.Context, name string) error {
+	return f.setNodeUnschedulable(ctx, name, false)
+}
+
+func (f *Framework) setNodeUnschedulable(ctx context.Context, name string, unschedulable bool) error {
+	if name == "" {
+		return ErrWrongInput
+	}
+	if err := f.SaveNodeState(ctx, name); err != nil {
+		return err
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		node, err := f.GetNodeContext(ctx, name)
+		if err != nil {
+			return err
+		}
+		if node.Spec.Unschedulable == unschedulable {
+			return nil
+		}
+		old := node.DeepCopy()
+		patch := client.MergeFromWithOptions(old, client.MergeFromWithOptimisticLock{})
+		node.Spec.Unschedulable = unschedulable
+		f.recordNodeChanges(old, node)
+		return f.PatchResourceContext(ctx, node, patch)
+	})
+}
+
+// DrainNode cordons the node, then evicts the pods on it in parallel, and waits for them to be gone.
+// The DaemonSet pods and the mirror pods are skipped, the eviction refused by the PodDisruptionBudget is retried
+// until the timeout. It returns the evicted pods
+func (f *Framework) DrainNode(ctx context.Context, name string, opts *DrainOptions) ([]corev1.Pod, error) {
+	if name == "" {
+		return nil, ErrWrongInput
+	}
+	if opts == nil {
+		opts = &DrainOptions{}
+	}
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = f.Config.ResourceDeleteTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := f.CordonNode(ctx, name); err != nil {
+		return nil, err
+	}
+
+	podList := &corev1.PodList{}
+	listOpts := []client.ListOption{client.MatchingFields{PodNodeNameField: name}}
+	if opts.PodSelector != nil {
+		listOpts = append(listOpts, client.MatchingLabels(opts.PodSelector))
+	}
+	if err := f.ListResourceContext(ctx, podList, listOpts...); err != nil {
+		return nil, err
+	}
+	pods := []corev1.Pod{}
+	for _, pod := range podList.Items {
+		if isDaemonSetPod(&pod) || isMirrorPod(&pod) {
+			continue
+		}
+		pods = append(pods, pod)
+	}
+	f.Log("draining %d pods on node %s \n", len(pods), name)
+
+	errs := make([]error, len(pods))
+	wg := sync.WaitGroup{}
+	for n := range pods {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			pod := &pods[n]
+			if err := f.EvictPod(ctx, pod, opts); err != nil {
+				errs[n] = err
+				return
+			}
+			errs[n] = f.waitPodReplaced(ctx, pod)
+		}(n)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return pods, fmt.Errorf("failed to drain node %s: %w", name, err)
+	}
+	return pods, nil
+}
+
+// EvictPod evicts the pod by the eviction sub resource, and retries the eviction refused by the PodDisruptionBudget
+// until ctx is done. The pod not found is taken as evicted
+func (f *Framework) EvictPod(ctx context.Context, pod *corev1.Pod, opts *DrainOptions) error {
+	if pod == nil || pod.Name == "" {
+		return ErrWrongInput
+	}
+	if opts == nil {
+		opts = &DrainOptions{}
+	}
+	interval := opts.RetryInterval
+	if interval == 0 {
+		interval = DefaultEvictionRetryInterval
+	}
+	eviction := &policyv1.Eviction{
+		ObjectMeta:    metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+		DeleteOptions: &metav1.DeleteOptions{GracePeriodSeconds: opts.GracePeriodSeconds},
+	}
+	key := keyString(client.ObjectKeyFromObject(pod))
+
+	for {
+		ctx1, cancel1 := context.WithTimeout(ctx, f.Config.ApiOperateTimeout)
+		// the pod is copied, the fake client writes it back
+		err := f.KClient.SubResource("eviction").Create(ctx1, pod.DeepCopy(), eviction)
+		cancel1()
+		switch {
+		case err == nil:
+			f.Log("evicted pod %s \n", key)
+			return nil
+		case api_errors.IsNotFound(err):
+			return nil
+		case !api_errors.IsTooManyRequests(err):
+			return fmt.Errorf("failed to evict pod %s: %w", key, err)
+		}
+
+		f.Log("eviction of pod %s is refused, retry: %v \n", key, err)
+		sleepContext(ctx, interval)
+		if ctx.Err() != nil {
+			return fmt.Errorf("%w: eviction of pod %s is refused: %v", ErrTimeOut, key, err)
+		}
+	}
+}
+
+// waitPodReplaced waits until the pod is gone, or replaced by the pod of another uid
+func (f *Framework) waitPodReplaced(ctx context.Context, pod *corev1.Pod) error {
+	_, err := waitObject(ctx, f, client.ObjectKeyFromObject(pod), func() *corev1.Pod { return &corev1.Pod{} }, "deleted", func(p *corev1.Pod, found bool) (bool, error) {
+		return !found || p.UID != pod.UID, nil
+	})
+	return err
+}
+
+func isDaemonSetPod(pod *corev1.Pod) bool {
+	owner := metav1.GetControllerOf(pod)
+	return owner != nil && owner.Kind == "DaemonSet"
+}
+
+func isMirrorPod(pod *corev1.Pod) bool {
+	_, ok := pod.Annotations[corev1.MirrorPodAnnotationKey]
+	return ok
+}
@@ -0,0 +1,114 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework_test
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	e2e "github.com/spidernet-io/e2eframework/framework"
+	corev1 "k8s.io/api/core/v1"
+	api_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// fakeDrainClient indexes the pods by the node, and refuses the eviction of the pod in refused like the PodDisruptionBudget
+func fakeDrainClient(refused map[string]*atomic.Int32) client.WithWatch {
+	scheme, err := e2e.NewScheme(nil)
+	Expect(err).NotTo(HaveOccurred())
+	return fake.NewClientBuilder().WithScheme(scheme).
+		WithIndex(&corev1.Pod{}, e2e.PodNodeNameField, func(obj client.Object) []string {
+			return []string{obj.(*corev1.Pod).Spec.NodeName}
+		}).
+		WithInterceptorFuncs(interceptor.Funcs{
+			SubResourceCreate: func(ctx context.Context, c client.Client, subResource string, obj client.Object, sub client.Object, opts ...client.SubResourceCreateOption) error {
+				if n, ok := refused[obj.GetName()]; ok && n.Add(-1) >= 0 {
+					return api_errors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+				}
+				return c.SubResource(subResource).Create(ctx, obj, sub, opts...)
+			},
+		}).Build()
+}
+
+func drainPod(name, node string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": name}},
+		Spec:       corev1.PodSpec{NodeName: node},
+	}
+}
+
+var _ = Describe("test drain", Label("drain"), func() {
+	var f *e2e.Framework
+	var ctx context.Context
+	var refused map[string]*atomic.Int32
+
+	BeforeEach(func() {
+		fakeEnv("/tmp/nokubeconfigfile")
+		refused = map[string]*atomic.Int32{"db": {}}
+		var e error
+		f, e = e2e.NewFramework(GinkgoT(), nil, fakeDrainClient(refused))
+		Expect(e).NotTo(HaveOccurred())
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 20*time.Second)
+		DeferCleanup(cancel)
+
+		Expect(f.CreateResourceContext(ctx, exampleNode("worker"))).To(Succeed())
+		ds := drainPod("agent", "worker")
+		ds.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "DaemonSet", Name: "agent", UID: "1", Controller: ptr.To(true)}}
+		mirror := drainPod("etcd", "worker")
+		mirror.Annotations = map[string]string{corev1.MirrorPodAnnotationKey: "abc"}
+		for _, pod := range []*corev1.Pod{drainPod("web", "worker"), drainPod("db", "worker"), drainPod("other", "master"), ds, mirror} {
+			Expect(f.CreateResourceContext(ctx, pod)).To(Succeed())
+		}
+	})
+
+	It("drain the node with the pod disruption budget", func() {
+		refused["db"].Store(2)
+		pods, e := f.DrainNode(ctx, "worker", &e2e.DrainOptions{RetryInterval: 100 * time.Millisecond, GracePeriodSeconds: ptr.To[int64](0)})
+		Expect(e).NotTo(HaveOccurred())
+		names := []string{}
+		for _, pod := range pods {
+			names = append(names, pod.Name)
+		}
+		Expect(names).To(ConsistOf("web", "db"))
+		Expect(refused["db"].Load()).To(BeNumerically("<", 0))
+
+		podList := &corev1.PodList{}
+		Expect(f.ListResourceContext(ctx, podList)).To(Succeed())
+		names = []string{}
+		for _, pod := range podList.Items {
+			names = append(names, pod.Name)
+		}
+		Expect(names).To(ConsistOf("other", "agent", "etcd"))
+
+		node, e := f.GetNodeContext(ctx, "worker")
+		Expect(e).NotTo(HaveOccurred())
+		Expect(node.Spec.Unschedulable).To(BeTrue())
+		Expect(f.SavedNodeStates()).To(HaveLen(1))
+
+		Expect(f.UncordonNode(ctx, "worker")).To(Succeed())
+		node, e = f.GetNodeContext(ctx, "worker")
+		Expect(e).NotTo(HaveOccurred())
+		Expect(node.Spec.Unschedulable).To(BeFalse())
+	})
+
+	It("time out for the refused eviction", func() {
+		refused["db"].Store(1000)
+		pods, e := f.DrainNode(ctx, "worker", &e2e.DrainOptions{Timeout: time.Second, RetryInterval: 100 * time.Millisecond, PodSelector: map[string]string{"app": "db"}})
+		Expect(e).To(MatchError(e2e.ErrTimeOut))
+		Expect(pods).To(HaveLen(1))
+
+		_, e = f.DrainNode(ctx, "not-existed", nil)
+		Expect(api_errors.IsNotFound(e)).To(BeTrue())
+		Expect(f.EvictPod(ctx, drainPod("not-existed", "worker"), nil)).To(Succeed())
+	})
+})
@@ -4,26 +4,28 @@
 package framework
 
 import (
-	"fmt"
+	"context"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func (f *Framework) GetEndpoint(name, namespace string) (*corev1.Endpoints, error) {
-	key := types.NamespacedName{
-		Name:      name,
-		Namespace: namespace,
-	}
-	ep := &corev1.Endpoints{}
-	return ep, f.GetResource(key, ep)
+	return f.GetEndpointContext(context.Background(), name, namespace)
+}
+
+func (f *Framework) GetEndpointContext(ctx context.Context, name, namespace string) (*corev1.Endpoints, error) {
+	return Get[*corev1.Endpoints](ctx, f, name, namespace)
 }
 
 func (f *Framework) ListEndpoint(options ...client.ListOption) (*corev1.EndpointsList, error) {
+	return f.ListEndpointContext(context.Background(), options...)
+}
+
+func (f *Framework) ListEndpointContext(ctx context.Context, options ...client.ListOption) (*corev1.EndpointsList, error) {
 	eps := &corev1.EndpointsList{}
-	err := f.ListResource(eps, options...)
+	err := f.ListResourceContext(ctx, eps, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -32,27 +34,26 @@ func (f *Framework) ListEndpoint(options ...client.ListOption) (*corev1.Endpoint
 
 // CreateEndpoint create an endpoint to testing GetEndpoint/ListEndpoint
 func (f *Framework) CreateEndpoint(ep *corev1.Endpoints, opts ...client.CreateOption) error {
-	key := &corev1.Endpoints{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      ep.Name,
-			Namespace: ep.Namespace,
-		},
-	}
-	fake := client.ObjectKeyFromObject(key)
-	existing := &corev1.Endpoints{}
-	e := f.GetResource(fake, existing)
-	if e == nil && existing.DeletionTimestamp == nil {
-		return fmt.Errorf("failed to create , a same endpoint %v/%v exists", ep.Namespace, ep.Name)
+	return f.CreateEndpointContext(context.Background(), ep, opts...)
+}
+
+func (f *Framework) CreateEndpointContext(ctx context.Context, ep *corev1.Endpoints, opts ...client.CreateOption) error {
+	if ep == nil {
+		return ErrWrongInput
 	}
-	return f.CreateResource(ep, opts...)
+	return Create(ctx, f, ep, opts...)
 }
 
 func (f *Framework) DeleteEndpoint(name, namespace string, opts ...client.DeleteOption) error {
+	return f.DeleteEndpointContext(context.Background(), name, namespace, opts...)
+}
+
+func (f *Framework) DeleteEndpointContext(ctx context.Context, name, namespace string, opts ...client.DeleteOption) error {
 	ep := &corev1.Endpoints{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: namespace,
 			Name:      name,
 		},
 	}
-	return f.DeleteResource(ep, opts...)
+	return f.DeleteResourceContext(ctx, ep, opts...)
 }
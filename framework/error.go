@@ -12,3 +12,10 @@ var ErrEvent = errors.New("received error event")
 var ErrResDel = errors.New("resource is deleted")
 var ErrGetObj = errors.New("failed to get metaObject")
 var ErrAlreadyExisted = errors.New("resource already exists")
+var ErrNoClientSet = errors.New("kubernetes clientset is not available")
+var ErrNoTar = errors.New("tar is not found in the container")
+var ErrResFailed = errors.New("resource failed")
+var ErrNoContainerRuntime = errors.New("no container runtime is found")
+var ErrNoContainer = errors.New("container is not found")
+var ErrLeftover = errors.New("resource is left over")
+var ErrConnectivity = errors.New("connectivity is not expected")
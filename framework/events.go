@@ -9,7 +9,6 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/watch"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -19,40 +18,24 @@ func (f *Framework) WaitExceptEventOccurred(ctx context.Context, eventKind, objN
 		return ErrWrongInput
 	}
 	l := &client.ListOptions{
+		Namespace: objNamespace,
 		Raw: &metav1.ListOptions{
-			TypeMeta:      metav1.TypeMeta{Kind: eventKind},
 			FieldSelector: fmt.Sprintf("involvedObject.name=%s,involvedObject.namespace=%s", objName, objNamespace),
 		},
 	}
-	watchInterface, err := f.KClient.Watch(ctx, &corev1.EventList{}, l)
-	if err != nil {
-		return ErrWatch
-	}
-	defer watchInterface.Stop()
-	for {
-		select {
-		case <-ctx.Done():
-			return ErrTimeOut
-		case event, ok := <-watchInterface.ResultChan():
-			if !ok {
-				return ErrChanelClosed
+	_, err := WaitList(ctx, f, func(events *corev1.EventList) (bool, error) {
+		for _, event := range events.Items {
+			if event.InvolvedObject.Kind != eventKind || event.InvolvedObject.Name != objName || event.InvolvedObject.Namespace != objNamespace {
+				continue
 			}
-			f.Log("watch event object %v", event.Object)
-			switch event.Type {
-			case watch.Deleted:
-				return ErrResDel
-			default:
-				event, ok := event.Object.(*corev1.Event)
-				if !ok {
-					return ErrGetObj
-				}
-				f.Log("Event occurred message is %s/%v \n", event.Type, event.Message)
-				if strings.Contains(event.Message, message) {
-					return nil
-				}
+			f.Log("Event occurred message is %s/%v \n", event.Type, event.Message)
+			if strings.Contains(event.Message, message) {
+				return true, nil
 			}
 		}
-	}
+		return false, nil
+	}, l)
+	return err
 }
 
 func (f *Framework) GetEvents(ctx context.Context, eventKind, objName, objNamespace string) (*corev1.EventList, error) {
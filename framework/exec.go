@@ -0,0 +1,132 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+// ExecInPod runs the command in the container over the API server, without the kubectl binary.
+// The argv is passed as it is, so the argument could have the space or quote, like:
+//   r, err := f.ExecInPod(ctx, name, namespace, []string{"sh", "-c", "ip addr show eth0"}, nil)
+//   var exitErr *framework.ExecExitError
+//   if errors.As(err, &exitErr) { exitErr.Code ... }
+
+// ExecOptions is the options of ExecInPod, nil means the only container of the pod without stdin
+type ExecOptions struct {
+	// empty means the only container of the pod
+	Container string
+	Stdin     io.Reader
+	// with TTY, the stderr is merged into the stdout
+	TTY bool
+	// optional writers, which get the output in real time rather than ExecResult
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// ExecResult holds the output of ExecInPod, empty for the output written to ExecOptions.Stdout or Stderr
+type ExecResult struct {
+	Stdout []byte
+	Stderr []byte
+}
+
+// ExecExitError means the command runs, but exits with a non-zero code
+type ExecExitError struct {
+	Command []string
+	Code    int
+	Stderr  []byte
+}
+
+func (e *ExecExitError) Error() string {
+	msg := fmt.Sprintf("command %q exited with code %d", strings.Join(e.Command, " "), e.Code)
+	if len(e.Stderr) > 0 {
+		msg += ": " + strings.TrimSpace(string(e.Stderr))
+	}
+	return msg
+}
+
+// ExecInPod runs command and waits for it to finish or ctx is done.
+// The ExecResult is returned even with the ExecExitError
+func (f *Framework) ExecInPod(ctx context.Context, name, namespace string, command []string, opts *ExecOptions) (*ExecResult, error) {
+	if name == "" || namespace == "" || len(command) == 0 {
+		return nil, ErrWrongInput
+	}
+	if f.KConfig == nil || f.KClientSet == nil {
+		return nil, ErrNoClientSet
+	}
+	if opts == nil {
+		opts = &ExecOptions{}
+	}
+
+	req := f.KClientSet.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(name).
+		Namespace(namespace).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: opts.Container,
+			Command:   command,
+			Stdin:     opts.Stdin != nil,
+			Stdout:    true,
+			Stderr:    !opts.TTY,
+			TTY:       opts.TTY,
+		}, scheme.ParameterCodec)
+
+	// prefer the websocket, and fall back to the spdy for the old API server
+	wsExec, err := remotecommand.NewWebSocketExecutor(f.KConfig, "GET", req.URL().String())
+	if err != nil {
+		return nil, err
+	}
+	spdyExec, err := remotecommand.NewSPDYExecutor(f.KConfig, "POST", req.URL())
+	if err != nil {
+		return nil, err
+	}
+	executor, err := remotecommand.NewFallbackExecutor(wsExec, spdyExec, func(err error) bool {
+		return httpstream.IsUpgradeFailure(err) || httpstream.IsHTTPSProxyError(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	stream := remotecommand.StreamOptions{
+		Stdin:  opts.Stdin,
+		Stdout: stdout,
+		Stderr: stderr,
+		Tty:    opts.TTY,
+	}
+	if opts.Stdout != nil {
+		stream.Stdout = opts.Stdout
+	}
+	if opts.Stderr != nil {
+		stream.Stderr = opts.Stderr
+	}
+	if opts.TTY {
+		stream.Stderr = nil
+	}
+
+	err = executor.StreamWithContext(ctx, stream)
+	result := &ExecResult{Stdout: stdout.Bytes(), Stderr: stderr.Bytes()}
+	if err == nil {
+		return result, nil
+	}
+	var exitErr utilexec.ExitError
+	if errors.As(err, &exitErr) && exitErr.Exited() {
+		return result, &ExecExitError{Command: command, Code: exitErr.ExitStatus(), Stderr: result.Stderr}
+	}
+	if ctx.Err() != nil {
+		return result, fmt.Errorf("%w: exec %q in %v/%v: %v", ErrTimeOut, strings.Join(command, " "), namespace, name, err)
+	}
+	return result, fmt.Errorf("failed to exec %q in %v/%v: %w", strings.Join(command, " "), namespace, name, err)
+}
@@ -0,0 +1,75 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	e2e "github.com/spidernet-io/e2eframework/framework"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+var _ = Describe("test exec in pod", Label("exec"), func() {
+	var f *e2e.Framework
+	var ctx context.Context
+
+	BeforeEach(func() {
+		f = fakeFramework()
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 20*time.Second)
+		DeferCleanup(cancel)
+	})
+
+	It("check the input", func() {
+		_, e := f.ExecInPod(ctx, "pod", "default", []string{"ls"}, nil)
+		Expect(e).To(MatchError(e2e.ErrNoClientSet))
+		_, e = f.ExecInPod(ctx, "pod", "default", nil, nil)
+		Expect(e).To(MatchError(e2e.ErrWrongInput))
+	})
+
+	It("pass the argv and container to the API server", func() {
+		lock := sync.Mutex{}
+		var commands, containers []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			defer lock.Unlock()
+			commands = r.URL.Query()["command"]
+			containers = r.URL.Query()["container"]
+			http.Error(w, "exec is not supported", http.StatusForbidden)
+		}))
+		DeferCleanup(server.Close)
+
+		var e error
+		f.KConfig = &rest.Config{Host: server.URL}
+		f.KClientSet, e = kubernetes.NewForConfig(f.KConfig)
+		Expect(e).NotTo(HaveOccurred())
+
+		command := []string{"sh", "-c", "echo 'hello world'"}
+		_, e = f.ExecInPod(ctx, "pod", "default", command, &e2e.ExecOptions{Container: "agent"})
+		Expect(e).To(HaveOccurred())
+		var exitErr *e2e.ExecExitError
+		Expect(errors.As(e, &exitErr)).To(BeFalse())
+
+		lock.Lock()
+		defer lock.Unlock()
+		Expect(commands).To(Equal(command))
+		Expect(containers).To(Equal([]string{"agent"}))
+	})
+
+	It("report the exit code", func() {
+		var e error = &e2e.ExecExitError{Command: []string{"ls", "/x"}, Code: 2, Stderr: []byte("no such file\n")}
+		var exitErr *e2e.ExecExitError
+		Expect(errors.As(e, &exitErr)).To(BeTrue())
+		Expect(exitErr.Code).To(Equal(2))
+		Expect(e.Error()).To(Equal(`command "ls /x" exited with code 2: no such file`))
+	})
+})
@@ -0,0 +1,433 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	api_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// inject the network fault on the node, which runs by ExecOnNode, so the kind node container by default, like:
+//   fault, err := f.InjectNetem(ctx, "worker", &NetemOptions{Delay: 100 * time.Millisecond, Loss: 1})
+//   fault, err := f.PartitionFromAPIServer(ctx, "worker")
+//   fault, err := f.PartitionNodes(ctx, "worker")
+//   fault, err := f.BlackholeCIDRs(ctx, "worker", "10.233.0.0/16", "fd00:10:233::/64")
+// every fault is rolled back when the spec finishes if f.t is CleanupT, even the spec fails,
+// or by fault.Rollback(ctx) and f.RollbackFaults(ctx), and in AfterSuite:
+//   Expect(f.CheckLeftoverFaults(ctx)).To(Succeed())
+
+const (
+	FaultNetem              = "netem"
+	FaultPartitionAPIServer = "partition-apiserver"
+	FaultPartitionNodes     = "partition-nodes"
+	FaultBlackhole          = "blackhole"
+
+	// FaultRuleComment is the comment of the iptables rules of the faults, for CheckLeftoverFaults
+	FaultRuleComment = "e2eframework-fault"
+	// DefaultFaultInterface is the interface of the kind node
+	DefaultFaultInterface = "eth0"
+)
+
+// Fault is an injected fault on the node
+type Fault struct {
+	// one of Fault*
+	Kind string
+	Node string
+	// like "delay 100ms loss 1%", or the partitioned addresses
+	Detail     string
+	InjectedAt time.Time
+
+	f    *Framework
+	node *corev1.Node
+	// the applied steps, rolled back in the reverse order
+	steps []faultStep
+
+	lock       sync.Mutex
+	rolledBack bool
+}
+
+// faultStep is a command injecting the fault and the command removing it
+type faultStep struct {
+	do   []string
+	undo []string
+}
+
+type faultTracker struct {
+	lock   sync.Mutex
+	faults []*Fault
+}
+
+func (fault *Fault) String() string {
+	return fmt.Sprintf("%s on node %s: %s", fault.Kind, fault.Node, fault.Detail)
+}
+
+// Rollback removes the fault, it does nothing for the fault rolled back
+func (fault *Fault) Rollback(ctx context.Context) error {
+	fault.lock.Lock()
+	defer fault.lock.Unlock()
+	if fault.rolledBack {
+		return nil
+	}
+	// the failed undo would fail again, so it is not retried
+	fault.rolledBack = true
+	fault.f.untrackFault(fault)
+
+	var errs []error
+	for n := len(fault.steps) - 1; n >= 0; n-- {
+		if _, err := fault.f.ExecOnNode(ctx, fault.node, fault.steps[n].undo); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to roll back %v: %w", fault, errors.Join(errs...))
+	}
+	fault.f.Log("rolled back %v \n", fault)
+	return nil
+}
+
+// Faults returns the faults not rolled back, in the order of injecting
+func (f *Framework) Faults() []*Fault {
+	if f.faults == nil {
+		return nil
+	}
+	f.faults.lock.Lock()
+	defer f.faults.lock.Unlock()
+	return append([]*Fault{}, f.faults.faults...)
+}
+
+// RollbackFaults rolls back all the faults in the reverse order of injecting
+func (f *Framework) RollbackFaults(ctx context.Context) error {
+	faults := f.Faults()
+	var errs []error
+	for n := len(faults) - 1; n >= 0; n-- {
+		if err := faults[n].Rollback(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CheckLeftoverFaults fails with ErrLeftover for the nodes of the cluster which still have the iptables rules
+// of FaultRuleComment or the netem qdisc, for the crash, the failed rollback or the fault of another framework
+func (f *Framework) CheckLeftoverFaults(ctx context.Context) error {
+	nodes, err := f.GetNodeListContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	leftover := []string{}
+	var errs []error
+	for n := range nodes.Items {
+		node := &nodes.Items[n]
+		found := []string{}
+		for _, binary := range []string{"iptables-save", "ip6tables-save"} {
+			r, err := f.ExecOnNode(ctx, node, []string{binary})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to check the faults on node %s: %w", node.Name, err))
+				continue
+			}
+			for _, line := range strings.Split(string(r.Stdout), "\n") {
+				if strings.Contains(line, FaultRuleComment) {
+					found = append(found, strings.TrimSpace(line))
+				}
+			}
+		}
+		r, err := f.ExecOnNode(ctx, node, []string{"tc", "qdisc", "show"})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to check the faults on node %s: %w", node.Name, err))
+		} else {
+			for _, line := range strings.Split(string(r.Stdout), "\n") {
+				if strings.HasPrefix(line, "qdisc netem ") {
+					found = append(found, strings.TrimSpace(line))
+				}
+			}
+		}
+		if len(found) > 0 {
+			leftover = append(leftover, fmt.Sprintf("%s: %s", node.Name, strings.Join(found, ", ")))
+		}
+	}
+	if len(leftover) > 0 {
+		errs = append([]error{fmt.Errorf("%w: faults of the framework, %s", ErrLeftover, strings.Join(leftover, "; "))}, errs...)
+	}
+	return errors.Join(errs...)
+}
+
+func (f *Framework) untrackFault(fault *Fault) {
+	if f.faults == nil {
+		return
+	}
+	f.faults.lock.Lock()
+	defer f.faults.lock.Unlock()
+	for n, t := range f.faults.faults {
+		if t == fault {
+			f.faults.faults = append(f.faults.faults[:n], f.faults.faults[n+1:]...)
+			return
+		}
+	}
+}
+
+// injectFault runs the steps on the node, the applied steps are rolled back when one fails
+func (f *Framework) injectFault(ctx context.Context, kind, nodeName, detail string, steps []faultStep) (*Fault, error) {
+	node, err := f.faultNode(ctx, nodeName)
+	if err != nil {
+		return nil, err
+	}
+	fault := &Fault{Kind: kind, Node: nodeName, Detail: detail, f: f, node: node}
+
+	for _, step := range steps {
+		if _, err := f.ExecOnNode(ctx, node, step.do); err != nil {
+			err = fmt.Errorf("failed to inject %v: %w", fault, err)
+			if e := fault.Rollback(ctx); e != nil {
+				err = errors.Join(err, e)
+			}
+			return nil, err
+		}
+		fault.steps = append(fault.steps, step)
+	}
+	fault.InjectedAt = time.Now()
+
+	if f.faults != nil {
+		f.faults.lock.Lock()
+		f.faults.faults = append(f.faults.faults, fault)
+		f.faults.lock.Unlock()
+	}
+	if t, ok := f.t.(CleanupT); ok {
+		t.Cleanup(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), f.Config.ApiOperateTimeout)
+			defer cancel()
+			if err := fault.Rollback(ctx); err != nil {
+				t.Errorf("%v", err)
+			}
+		})
+	}
+	f.Log("injected %v \n", fault)
+	return fault, nil
+}
+
+// faultNode returns the node object, the kind node container may be not a node yet
+func (f *Framework) faultNode(ctx context.Context, name string) (*corev1.Node, error) {
+	if name == "" {
+		return nil, ErrWrongInput
+	}
+	node, err := f.GetNodeContext(ctx, name)
+	if api_errors.IsNotFound(err) {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}, nil
+	}
+	return node, err
+}
+
+// ------------- netem
+
+// NetemOptions is the netem qdisc on the root of the interface, one of Delay, Loss and Reorder is required
+type NetemOptions struct {
+	// empty means DefaultFaultInterface
+	Interface string
+	Delay     time.Duration
+	// the random variation of Delay
+	Jitter time.Duration
+	// the percent of the lost packets, like 1.5 for 1.5%
+	Loss float64
+	// the percent of the packets sent immediately, the others are delayed, it requires Delay
+	Reorder float64
+}
+
+// InjectNetem adds the netem qdisc to the interface of the node, it fails for the interface with a root qdisc already
+func (f *Framework) InjectNetem(ctx context.Context, node string, opts *NetemOptions) (*Fault, error) {
+	if opts == nil {
+		return nil, ErrWrongInput
+	}
+	dev := opts.Interface
+	if dev == "" {
+		dev = DefaultFaultInterface
+	}
+
+	args := []string{}
+	if opts.Delay > 0 {
+		args = append(args, "delay", tcTime(opts.Delay))
+		if opts.Jitter > 0 {
+			args = append(args, tcTime(opts.Jitter))
+		}
+	}
+	for _, p := range []struct {
+		name    string
+		percent float64
+	}{{"loss", opts.Loss}, {"reorder", opts.Reorder}} {
+		if p.percent < 0 || p.percent > 100 {
+			return nil, fmt.Errorf("%w: netem %s %v is not a percent", ErrWrongInput, p.name, p.percent)
+		}
+		if p.percent > 0 {
+			args = append(args, p.name, strconv.FormatFloat(p.percent, 'f', -1, 64)+"%")
+		}
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%w: netem needs delay, loss or reorder", ErrWrongInput)
+	}
+	if opts.Reorder > 0 && opts.Delay <= 0 {
+		return nil, fmt.Errorf("%w: netem reorder needs delay", ErrWrongInput)
+	}
+
+	step := faultStep{
+		do:   append([]string{"tc", "qdisc", "add", "dev", dev, "root", "netem"}, args...),
+		undo: []string{"tc", "qdisc", "del", "dev", dev, "root"},
+	}
+	return f.injectFault(ctx, FaultNetem, node, dev+" "+strings.Join(args, " "), []faultStep{step})
+}
+
+// tcTime formats d for tc, like 100ms or 1500us
+func tcTime(d time.Duration) string {
+	if d%time.Millisecond == 0 {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+	return fmt.Sprintf("%dus", d.Microseconds())
+}
+
+// ------------- iptables
+
+// dropRule returns the step inserting the DROP rule to chain, and the one deleting it.
+// address is the IP or CIDR, which selects iptables or ip6tables
+func dropRule(chain, address string, match ...string) (faultStep, error) {
+	binary, err := iptablesOf(address)
+	if err != nil {
+		return faultStep{}, err
+	}
+	rule := append([]string{chain}, match...)
+	rule = append(rule, "-m", "comment", "--comment", FaultRuleComment, "-j", "DROP")
+	return faultStep{
+		do:   append([]string{binary, "-w", "-I"}, rule...),
+		undo: append([]string{binary, "-w", "-D"}, rule...),
+	}, nil
+}
+
+func iptablesOf(address string) (string, error) {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		var err error
+		if ip, _, err = net.ParseCIDR(address); err != nil {
+			return "", fmt.Errorf("%w: %q is not an IP or CIDR", ErrWrongInput, address)
+		}
+	}
+	if ip.To4() != nil {
+		return "iptables", nil
+	}
+	return "ip6tables", nil
+}
+
+// PartitionFromAPIServer drops the traffic from the node and its pods to the API server,
+// the addresses are the endpoints of the kubernetes service in the default namespace
+func (f *Framework) PartitionFromAPIServer(ctx context.Context, node string) (*Fault, error) {
+	ep, err := f.GetEndpointContext(ctx, "kubernetes", corev1.NamespaceDefault)
+	if err != nil {
+		return nil, err
+	}
+
+	steps := []faultStep{}
+	addresses := []string{}
+	for _, subset := range ep.Subsets {
+		for _, a := range subset.Addresses {
+			for _, p := range subset.Ports {
+				port := strconv.Itoa(int(p.Port))
+				for _, chain := range []string{"OUTPUT", "FORWARD"} {
+					step, err := dropRule(chain, a.IP, "-d", a.IP, "-p", "tcp", "--dport", port)
+					if err != nil {
+						return nil, err
+					}
+					steps = append(steps, step)
+				}
+				addresses = append(addresses, net.JoinHostPort(a.IP, port))
+			}
+		}
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("%w: no endpoint of the API server", ErrGetObj)
+	}
+	return f.injectFault(ctx, FaultPartitionAPIServer, node, strings.Join(addresses, ","), steps)
+}
+
+// PartitionNodes drops the traffic between the node and the peers, empty peers means all the other nodes.
+// The InternalIP and the PodCIDRs of the peers are dropped, so the tunnel and the routed pod traffic are cut
+func (f *Framework) PartitionNodes(ctx context.Context, node string, peers ...string) (*Fault, error) {
+	if node == "" {
+		return nil, ErrWrongInput
+	}
+	if len(peers) == 0 {
+		nodes, err := f.GetNodeListContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nodes.Items {
+			if n.Name != node {
+				peers = append(peers, n.Name)
+			}
+		}
+	}
+	if len(peers) == 0 {
+		return nil, fmt.Errorf("%w: no peer of node %s", ErrWrongInput, node)
+	}
+
+	addresses := []string{}
+	for _, peer := range peers {
+		if peer == node {
+			return nil, fmt.Errorf("%w: node %s is its own peer", ErrWrongInput, node)
+		}
+		n, err := f.GetNodeContext(ctx, peer)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range n.Status.Addresses {
+			if a.Type == corev1.NodeInternalIP {
+				addresses = append(addresses, a.Address)
+			}
+		}
+		addresses = append(addresses, n.Spec.PodCIDRs...)
+	}
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("%w: no InternalIP of the peers %v", ErrWrongInput, peers)
+	}
+
+	steps := []faultStep{}
+	for _, a := range addresses {
+		for _, rule := range [][]string{{"INPUT", "-s"}, {"OUTPUT", "-d"}, {"FORWARD", "-s"}, {"FORWARD", "-d"}} {
+			step, err := dropRule(rule[0], a, rule[1], a)
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, step)
+		}
+	}
+	return f.injectFault(ctx, FaultPartitionNodes, node, strings.Join(addresses, ","), steps)
+}
+
+// ------------- blackhole
+
+// BlackholeCIDRs adds the blackhole routes of the cidrs on the node, the more specific routes still win
+func (f *Framework) BlackholeCIDRs(ctx context.Context, node string, cidrs ...string) (*Fault, error) {
+	if len(cidrs) == 0 {
+		return nil, ErrWrongInput
+	}
+	steps := []faultStep{}
+	for _, cidr := range cidrs {
+		ip, _, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrWrongInput, err)
+		}
+		family := "-4"
+		if ip.To4() == nil {
+			family = "-6"
+		}
+		steps = append(steps, faultStep{
+			do:   []string{"ip", family, "route", "add", "blackhole", cidr},
+			undo: []string{"ip", family, "route", "del", "blackhole", cidr},
+		})
+	}
+	return f.injectFault(ctx, FaultBlackhole, node, strings.Join(cidrs, ","), steps)
+}
@@ -0,0 +1,218 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	e2e "github.com/spidernet-io/e2eframework/framework"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// cleanupT records the cleanups, which are called by the spec
+type cleanupT struct {
+	cleanups []func()
+	errs     []string
+}
+
+func (t *cleanupT) Logf(format string, args ...interface{}) {
+	GinkgoWriter.Printf(format, args...)
+}
+
+func (t *cleanupT) Cleanup(fn func()) {
+	t.cleanups = append(t.cleanups, fn)
+}
+
+func (t *cleanupT) Errorf(format string, args ...interface{}) {
+	t.errs = append(t.errs, format)
+}
+
+func (t *cleanupT) runCleanups() {
+	for n := len(t.cleanups) - 1; n >= 0; n-- {
+		t.cleanups[n]()
+	}
+	t.cleanups = nil
+}
+
+var _ = Describe("test fault", Label("fault"), func() {
+	var f *e2e.Framework
+	var runtime *e2e.FakeContainerRuntime
+	var t *cleanupT
+	var ctx context.Context
+
+	BeforeEach(func() {
+		fakeFramework()
+		t = &cleanupT{}
+		var e error
+		f, e = e2e.NewFramework(t, nil, fakeClientSet())
+		Expect(e).NotTo(HaveOccurred())
+		runtime = e2e.NewFakeContainerRuntime("master", "worker")
+		f.Runtime = runtime
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 20*time.Second)
+		DeferCleanup(cancel)
+	})
+
+	It("inject netem and roll back", func() {
+		fault, e := f.InjectNetem(ctx, "worker", &e2e.NetemOptions{
+			Delay:   100 * time.Millisecond,
+			Jitter:  1500 * time.Microsecond,
+			Loss:    1.5,
+			Reorder: 25,
+		})
+		Expect(e).NotTo(HaveOccurred())
+		Expect(fault.Kind).To(Equal(e2e.FaultNetem))
+		Expect(fault.Detail).To(Equal("eth0 delay 100ms 1500us loss 1.5% reorder 25%"))
+		Expect(runtime.Calls()).To(Equal([]string{"exec worker tc qdisc add dev eth0 root netem delay 100ms 1500us loss 1.5% reorder 25%"}))
+		Expect(f.Faults()).To(ConsistOf(fault))
+
+		Expect(fault.Rollback(ctx)).To(Succeed())
+		Expect(fault.Rollback(ctx)).To(Succeed())
+		Expect(runtime.Calls()).To(HaveLen(2))
+		Expect(runtime.Calls()[1]).To(Equal("exec worker tc qdisc del dev eth0 root"))
+		Expect(f.Faults()).To(BeEmpty())
+
+		_, e = f.InjectNetem(ctx, "worker", &e2e.NetemOptions{})
+		Expect(e).To(MatchError(e2e.ErrWrongInput))
+		_, e = f.InjectNetem(ctx, "worker", &e2e.NetemOptions{Reorder: 10})
+		Expect(e).To(MatchError(e2e.ErrWrongInput))
+		_, e = f.InjectNetem(ctx, "worker", &e2e.NetemOptions{Loss: 120})
+		Expect(e).To(MatchError(e2e.ErrWrongInput))
+
+		// the cleanup of the spec does nothing for the fault rolled back
+		t.runCleanups()
+		Expect(runtime.Calls()).To(HaveLen(2))
+		Expect(t.errs).To(BeEmpty())
+	})
+
+	It("partition the node and roll back at the end of the spec", func() {
+		Expect(f.CreateResourceContext(ctx, &corev1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{Name: "kubernetes", Namespace: corev1.NamespaceDefault},
+			Subsets: []corev1.EndpointSubset{{
+				Addresses: []corev1.EndpointAddress{{IP: "172.18.0.2"}},
+				Ports:     []corev1.EndpointPort{{Port: 6443}},
+			}},
+		})).To(Succeed())
+		Expect(f.CreateResourceContext(ctx, &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "master"},
+			Spec:       corev1.NodeSpec{PodCIDRs: []string{"10.244.0.0/24"}},
+			Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "fc00::2"},
+			}},
+		})).To(Succeed())
+
+		_, e := f.PartitionFromAPIServer(ctx, "worker")
+		Expect(e).NotTo(HaveOccurred())
+		_, e = f.PartitionNodes(ctx, "worker", "master")
+		Expect(e).NotTo(HaveOccurred())
+		_, e = f.BlackholeCIDRs(ctx, "worker", "10.233.0.0/16")
+		Expect(e).NotTo(HaveOccurred())
+		Expect(f.Faults()).To(HaveLen(3))
+
+		calls := runtime.Calls()
+		Expect(calls).To(HaveLen(2 + 8 + 1))
+		Expect(calls[0]).To(Equal("exec worker iptables -w -I OUTPUT -d 172.18.0.2 -p tcp --dport 6443 -m comment --comment e2eframework-fault -j DROP"))
+		Expect(calls).To(ContainElements(
+			"exec worker ip6tables -w -I INPUT -s fc00::2 -m comment --comment e2eframework-fault -j DROP",
+			"exec worker iptables -w -I FORWARD -d 10.244.0.0/24 -m comment --comment e2eframework-fault -j DROP",
+		))
+		Expect(calls[10]).To(Equal("exec worker ip -4 route add blackhole 10.233.0.0/16"))
+
+		// the spec finishes
+		t.runCleanups()
+		Expect(t.errs).To(BeEmpty())
+		Expect(f.Faults()).To(BeEmpty())
+		undo := runtime.Calls()[11:]
+		Expect(undo).To(HaveLen(11))
+		Expect(undo[0]).To(Equal("exec worker ip -4 route del blackhole 10.233.0.0/16"))
+		Expect(undo[10]).To(Equal("exec worker iptables -w -D OUTPUT -d 172.18.0.2 -p tcp --dport 6443 -m comment --comment e2eframework-fault -j DROP"))
+
+		_, e = f.PartitionNodes(ctx, "worker", "worker")
+		Expect(e).To(MatchError(e2e.ErrWrongInput))
+		_, e = f.BlackholeCIDRs(ctx, "worker", "10.233.0.0")
+		Expect(e).To(MatchError(e2e.ErrWrongInput))
+	})
+
+	It("undo the applied steps when one fails", func() {
+		runtime.ExecFunc = func(container string, command []string) ([]byte, error) {
+			if strings.Contains(strings.Join(command, " "), "fd00::/64") && command[3] == "add" {
+				return nil, &e2e.ExecExitError{Command: command, Code: 2, Stderr: []byte("RTNETLINK answers: File exists")}
+			}
+			return nil, nil
+		}
+
+		_, e := f.BlackholeCIDRs(ctx, "worker", "10.233.0.0/16", "fd00::/64")
+		var exitErr *e2e.ExecExitError
+		Expect(errors.As(e, &exitErr)).To(BeTrue())
+		Expect(runtime.Calls()).To(Equal([]string{
+			"exec worker ip -4 route add blackhole 10.233.0.0/16",
+			"exec worker ip -6 route add blackhole fd00::/64",
+			"exec worker ip -4 route del blackhole 10.233.0.0/16",
+		}))
+		Expect(f.Faults()).To(BeEmpty())
+
+		// the failed rollback is reported by the spec
+		_, e = f.BlackholeCIDRs(ctx, "worker", "10.233.0.0/16")
+		Expect(e).NotTo(HaveOccurred())
+		Expect(runtime.Stop(ctx, "worker")).To(Succeed())
+		t.runCleanups()
+		Expect(t.errs).To(HaveLen(1))
+		Expect(f.Faults()).To(BeEmpty())
+	})
+
+	It("find the leftover faults on the nodes", func() {
+		for _, name := range []string{"master", "worker"} {
+			Expect(f.CreateResourceContext(ctx, exampleNode(name))).To(Succeed())
+		}
+		Expect(f.CheckLeftoverFaults(ctx)).To(Succeed())
+		fault, e := f.InjectNetem(ctx, "worker", &e2e.NetemOptions{Delay: 100 * time.Millisecond})
+		Expect(e).NotTo(HaveOccurred())
+
+		// the rollback is lost, like the crash
+		runtime.ExecFunc = func(container string, command []string) ([]byte, error) {
+			if container != "worker" {
+				return nil, nil
+			}
+			switch command[0] {
+			case "iptables-save":
+				return []byte("*filter\n-A OUTPUT -d 172.18.0.2/32 -m comment --comment e2eframework-fault -j DROP\nCOMMIT\n"), nil
+			case "ip6tables-save":
+				return []byte("*filter\nCOMMIT\n"), nil
+			case "tc":
+				return []byte("qdisc netem 8001: dev eth0 root refcnt 2 limit 1000 delay 100ms\nqdisc noqueue 0: dev lo root refcnt 2\n"), nil
+			}
+			return nil, nil
+		}
+		e = f.CheckLeftoverFaults(ctx)
+		Expect(e).To(MatchError(e2e.ErrLeftover))
+		Expect(e.Error()).To(ContainSubstring("worker: -A OUTPUT -d 172.18.0.2/32 -m comment --comment e2eframework-fault -j DROP, qdisc netem 8001: dev eth0"))
+		Expect(runtime.Calls()).To(ContainElements("exec worker iptables-save", "exec worker ip6tables-save", "exec worker tc qdisc show"))
+		// every node is checked, even without the fault of this framework
+		Expect(runtime.Calls()).To(ContainElements("exec master iptables-save", "exec master ip6tables-save", "exec master tc qdisc show"))
+		Expect(e.Error()).NotTo(ContainSubstring("master:"))
+
+		// the framework of AfterSuite finds the fault of another one
+		other, e := e2e.NewFramework(t, nil, f.KClient)
+		Expect(e).NotTo(HaveOccurred())
+		other.Runtime = runtime
+		Expect(other.CheckLeftoverFaults(ctx)).To(MatchError(e2e.ErrLeftover))
+
+		runtime.ExecFunc = nil
+		Expect(fault.Rollback(ctx)).To(Succeed())
+		Expect(f.CheckLeftoverFaults(ctx)).To(Succeed())
+
+		// the node failing the check
+		Expect(runtime.Stop(ctx, "worker")).To(Succeed())
+		e = f.CheckLeftoverFaults(ctx)
+		Expect(e).To(HaveOccurred())
+		Expect(e).NotTo(MatchError(e2e.ErrLeftover))
+	})
+})
@@ -0,0 +1,119 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// the fixture is a Go template of YAML, rendered with FixtureData, so one file covers the ipv4, ipv6 and dual stack, like:
+//   metadata:
+//     name: {{ randomName "nginx" }}
+//     namespace: {{ .Namespace }}
+//     annotations:
+//   {{- if and .Info.IpV4Enabled .Info.IpV6Enabled }}
+//       ipam.spidernet.io/ippools: '[{"ipv4":["{{ .Values.v4pool }}"],"ipv6":["{{ .Values.v6pool }}"]}]'
+//   {{- end }}
+// then:
+//   deploy, err := framework.LoadFixture[*appsv1.Deployment](f, framework.ManifestFromFile("testdata/deploy.yaml"), map[string]interface{}{"v4pool": v4pool, "v6pool": v6pool})
+
+// FixtureData is the data of the fixture template
+type FixtureData struct {
+	Info ClusterInfo
+	// the namespace of the current spec, see CreateTestNamespace
+	Namespace string
+	// the values given by the spec
+	Values map[string]interface{}
+}
+
+// RenderFixture renders the templates of source, then decodes them to the typed objects in order.
+// The kind not registered in the scheme, the unknown field or the missing value is refused,
+// and the optional value is got by index, like: {{ default "1" (index .Values "replicas") }}.
+// Besides toJson and quote, the templates have randomName "prefix", which returns "prefix-xxxxx"
+// and the same name for the same prefix in one rendering, and default "value" v for the nil or empty v
+func (f *Framework) RenderFixture(source ManifestSource, values map[string]interface{}) ([]client.Object, error) {
+	if source == nil {
+		return nil, ErrWrongInput
+	}
+	files, err := source()
+	if err != nil {
+		return nil, err
+	}
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+	data := FixtureData{
+		Info:      f.Info,
+		Namespace: f.Namespace,
+		Values:    values,
+	}
+
+	names := map[string]string{}
+	funcs := template.FuncMap{
+		"randomName": func(prefix string) string {
+			if _, ok := names[prefix]; !ok {
+				names[prefix] = prefix + "-" + utilrand.String(5)
+			}
+			return names[prefix]
+		},
+		"toJson": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+		"default": func(d interface{}, v ...interface{}) interface{} {
+			if len(v) == 0 || v[0] == nil || fmt.Sprint(v[0]) == "" {
+				return d
+			}
+			return v[0]
+		},
+		"quote": func(v interface{}) string {
+			return fmt.Sprintf("%q", fmt.Sprint(v))
+		},
+	}
+
+	rendered := make([]ManifestFile, 0, len(files))
+	for _, file := range files {
+		t, err := template.New(file.Name).Funcs(funcs).Option("missingkey=error").Parse(string(file.Data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrWrongInput, err)
+		}
+		buf := &bytes.Buffer{}
+		if err := t.Execute(buf, data); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrWrongInput, err)
+		}
+		rendered = append(rendered, ManifestFile{Name: file.Name, Data: buf.Bytes()})
+	}
+	items, err := f.decodeManifestFiles(rendered, true)
+	if err != nil {
+		return nil, err
+	}
+	return manifestObjects(items), nil
+}
+
+// LoadFixture renders source by RenderFixture, which must have exactly one object of T
+func LoadFixture[T client.Object](f *Framework, source ManifestSource, values map[string]interface{}) (T, error) {
+	var empty T
+	objs, err := f.RenderFixture(source, values)
+	if err != nil {
+		return empty, err
+	}
+	found := []T{}
+	kinds := []string{}
+	for _, obj := range objs {
+		if t, ok := obj.(T); ok {
+			found = append(found, t)
+		}
+		kinds = append(kinds, f.kindOf(obj))
+	}
+	if len(found) != 1 {
+		return empty, fmt.Errorf("%w: want one %T, but the fixture has %d in [%s]", ErrWrongInput, empty, len(found), strings.Join(kinds, ", "))
+	}
+	return found[0], nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework_test
+
+import (
+	"strings"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	e2e "github.com/spidernet-io/e2eframework/framework"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const testFixture = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ randomName "config" }}
+  namespace: {{ .Namespace }}
+data:
+  pools: {{ toJson .Values.pools | quote }}
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ randomName "nginx" }}
+  namespace: {{ .Namespace }}
+{{- if and .Info.IpV4Enabled .Info.IpV6Enabled }}
+  labels:
+    stack: dual
+{{- else if .Info.IpV4Enabled }}
+  labels:
+    stack: ipv4
+{{- end }}
+spec:
+  replicas: {{ default 1 (index .Values "replicas") }}
+  selector:
+    matchLabels:
+      app: {{ randomName "nginx" }}
+  template:
+    metadata:
+      labels:
+        app: {{ randomName "nginx" }}
+    spec:
+      containers:
+      - name: nginx
+        image: nginx
+        volumeMounts:
+        - name: config
+          mountPath: /etc/config
+      volumes:
+      - name: config
+        configMap:
+          name: {{ randomName "config" }}
+`
+
+var _ = Describe("test fixture", Label("fixture"), func() {
+	var f *e2e.Framework
+
+	BeforeEach(func() {
+		f = fakeFramework()
+		f.Namespace = "spec-ns"
+	})
+
+	It("render the fixture with ClusterInfo", func() {
+		fsys := fstest.MapFS{"deploy.yaml": {Data: []byte(testFixture)}}
+		values := map[string]interface{}{"pools": []string{"pool-v4", "pool-v6"}}
+
+		objs, e := f.RenderFixture(e2e.ManifestFromFS(fsys, "deploy.yaml"), values)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(objs).To(HaveLen(2))
+		cm, ok := objs[0].(*corev1.ConfigMap)
+		Expect(ok).To(BeTrue())
+		Expect(cm.Namespace).To(Equal("spec-ns"))
+		Expect(cm.Data).To(HaveKeyWithValue("pools", `["pool-v4","pool-v6"]`))
+
+		deploy, e := e2e.LoadFixture[*appsv1.Deployment](f, e2e.ManifestFromFS(fsys, "deploy.yaml"), values)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(deploy.Labels).To(HaveKeyWithValue("stack", "dual"))
+		Expect(*deploy.Spec.Replicas).To(Equal(int32(1)))
+		Expect(strings.HasPrefix(deploy.Name, "nginx-")).To(BeTrue())
+		// the same prefix gets the same name in one rendering
+		Expect(deploy.Spec.Template.Labels).To(HaveKeyWithValue("app", deploy.Name))
+		Expect(deploy.Spec.Template.Spec.Volumes[0].ConfigMap.Name).To(HavePrefix("config-"))
+		Expect(deploy.Spec.Template.Spec.Volumes[0].ConfigMap.Name).NotTo(Equal(cm.Name))
+
+		f.Info.IpV6Enabled = false
+		values["replicas"] = 3
+		deploy, e = e2e.LoadFixture[*appsv1.Deployment](f, e2e.ManifestFromFS(fsys, "deploy.yaml"), values)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(deploy.Labels).To(HaveKeyWithValue("stack", "ipv4"))
+		Expect(*deploy.Spec.Replicas).To(Equal(int32(3)))
+	})
+
+	It("refuse the invalid fixture", func() {
+		// the missing value
+		_, e := f.RenderFixture(e2e.ManifestFromString("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Values.name }}\n"), nil)
+		Expect(e).To(MatchError(e2e.ErrWrongInput))
+
+		// the unknown field
+		_, e = f.RenderFixture(e2e.ManifestFromString("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm\ndatas:\n  a: b\n"), nil)
+		Expect(e).To(MatchError(ContainSubstring("datas")))
+
+		// the kind not registered
+		_, e = f.RenderFixture(e2e.ManifestFromString("apiVersion: example.io/v1\nkind: Foo\nmetadata:\n  name: foo\n"), nil)
+		Expect(e).To(MatchError(ContainSubstring("not registered")))
+
+		_, e = e2e.LoadFixture[*appsv1.Deployment](f, e2e.ManifestFromString("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm\n"), nil)
+		Expect(e).To(MatchError(ContainSubstring("[configmap]")))
+	})
+})
@@ -5,6 +5,7 @@ package framework
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"time"
 
@@ -12,6 +13,7 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	network_v1alpha1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -23,6 +25,8 @@ import (
 	nadv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	flowcontrolv1 "k8s.io/api/flowcontrol/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	apiextensions_v1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 )
 
@@ -36,6 +40,8 @@ type ClusterInfo struct {
 	WhereaboutIPAMEnabled bool
 	ClusterName           string
 	KubeConfigPath        string
+	// context of KubeConfigPath, empty means the current context
+	KubeContext string
 	// docker container name for kind cluster
 	KindNodeList    []string
 	KindNodeListRaw string
@@ -43,6 +49,11 @@ type ClusterInfo struct {
 	MultusDefaultCni    string
 	MultusAdditionalCni string
 	SpiderSubnetEnabled bool
+	// the directory of the Vagrantfile, for the cluster of vagrant VMs
+	VagrantDir string
+	// VM name to node name
+	VagrantNodeMap    map[string]string
+	VagrantNodeMapRaw string
 }
 
 var ClusterInformation = &ClusterInfo{}
@@ -52,13 +63,13 @@ type envconfig struct {
 	DestStr  *string
 	DestBool *bool
 	Default  string
-	Required bool
 	BoolType bool
 }
 
 const (
 	E2E_CLUSTER_NAME            = "E2E_CLUSTER_NAME"
 	E2E_KUBECONFIG_PATH         = "E2E_KUBECONFIG_PATH"
+	E2E_KUBECONFIG_CONTEXT      = "E2E_KUBECONFIG_CONTEXT"
 	E2E_IPV4_ENABLED            = "E2E_IPV4_ENABLED"
 	E2E_IPV6_ENABLED            = "E2E_IPV6_ENABLED"
 	E2E_MULTUS_CNI_ENABLED      = "E2E_MULTUS_CNI_ENABLED"
@@ -70,25 +81,41 @@ const (
 	E2E_SPIDERSUBNET_ENABLED    = "E2E_SPIDERSUBNET_ENABLED"
 )
 
-var envConfigList = []envconfig{
-	// --- multus field
-	{EnvName: E2E_Multus_DefaultCni, DestStr: &ClusterInformation.MultusDefaultCni, Default: "", Required: false},
-	{EnvName: E2E_Multus_AdditionalCni, DestStr: &ClusterInformation.MultusAdditionalCni, Default: "", Required: false},
-	// --- require field
-	{EnvName: E2E_CLUSTER_NAME, DestStr: &ClusterInformation.ClusterName, Default: "", Required: true},
-	{EnvName: E2E_KUBECONFIG_PATH, DestStr: &ClusterInformation.KubeConfigPath, Default: "", Required: true},
-	// ---- optional field
-	{EnvName: E2E_IPV4_ENABLED, DestBool: &ClusterInformation.IpV4Enabled, Default: "true", Required: false},
-	{EnvName: E2E_IPV6_ENABLED, DestBool: &ClusterInformation.IpV6Enabled, Default: "true", Required: false},
-	{EnvName: E2E_MULTUS_CNI_ENABLED, DestBool: &ClusterInformation.MultusEnabled, Default: "false", Required: false},
-	{EnvName: E2E_SPIDERPOOL_IPAM_ENABLED, DestBool: &ClusterInformation.SpiderIPAMEnabled, Default: "false", Required: false},
-	{EnvName: E2E_WHEREABOUT_IPAM_ENABLED, DestBool: &ClusterInformation.WhereaboutIPAMEnabled, Default: "false", Required: false},
-	// ---- kind field
-	{EnvName: E2E_KIND_CLUSTER_NODE_LIST, DestStr: &ClusterInformation.KindNodeListRaw, Default: "false", Required: false},
-	// ---- subnet field
-	{EnvName: E2E_SPIDERSUBNET_ENABLED, DestBool: &ClusterInformation.SpiderSubnetEnabled, Default: "true", Required: false},
-
-	// ---- vagrant field
+// envConfigList maps the E2E_* ENV to the fields of info and fc
+func envConfigList(info *ClusterInfo, fc *FConfig) []envconfig {
+	return []envconfig{
+		// --- multus field
+		{EnvName: E2E_Multus_DefaultCni, DestStr: &info.MultusDefaultCni, Default: ""},
+		{EnvName: E2E_Multus_AdditionalCni, DestStr: &info.MultusAdditionalCni, Default: ""},
+		// --- require field, checked by ClusterInfo.Validate()
+		{EnvName: E2E_CLUSTER_NAME, DestStr: &info.ClusterName, Default: ""},
+		{EnvName: E2E_KUBECONFIG_PATH, DestStr: &info.KubeConfigPath, Default: ""},
+		// ---- optional field
+		{EnvName: E2E_KUBECONFIG_CONTEXT, DestStr: &info.KubeContext, Default: ""},
+		{EnvName: E2E_IPV4_ENABLED, DestBool: &info.IpV4Enabled, Default: "true"},
+		{EnvName: E2E_IPV6_ENABLED, DestBool: &info.IpV6Enabled, Default: "true"},
+		{EnvName: E2E_MULTUS_CNI_ENABLED, DestBool: &info.MultusEnabled, Default: "false"},
+		{EnvName: E2E_SPIDERPOOL_IPAM_ENABLED, DestBool: &info.SpiderIPAMEnabled, Default: "false"},
+		{EnvName: E2E_WHEREABOUT_IPAM_ENABLED, DestBool: &info.WhereaboutIPAMEnabled, Default: "false"},
+		// ---- kind field
+		{EnvName: E2E_KIND_CLUSTER_NODE_LIST, DestStr: &info.KindNodeListRaw, Default: ""},
+		// ---- subnet field
+		{EnvName: E2E_SPIDERSUBNET_ENABLED, DestBool: &info.SpiderSubnetEnabled, Default: "true"},
+
+		// ---- framework field
+		{EnvName: E2E_CLUSTER_PROBE, DestStr: &fc.ClusterProbe, Default: ""},
+		{EnvName: E2E_ARTIFACTS_DIR, DestStr: &fc.ArtifactsDir, Default: ""},
+		{EnvName: E2E_CONTAINER_RUNTIME, DestStr: &fc.ContainerRuntime, Default: ""},
+		{EnvName: E2E_NODE_ACCESS, DestStr: &fc.NodeAccess, Default: ""},
+		{EnvName: E2E_SSH_USER, DestStr: &fc.SSHUser, Default: ""},
+		{EnvName: E2E_SSH_KEY_FILE, DestStr: &fc.SSHKeyFile, Default: ""},
+		{EnvName: E2E_SSH_KNOWN_HOSTS_FILE, DestStr: &fc.SSHKnownHostsFile, Default: ""},
+		{EnvName: E2E_SSH_INSECURE_HOST_KEY, DestBool: &fc.SSHInsecureHostKey, Default: "false"},
+
+		// ---- vagrant field
+		{EnvName: E2E_VAGRANT_DIR, DestStr: &info.VagrantDir, Default: ""},
+		{EnvName: E2E_VAGRANT_NODE_MAP, DestStr: &info.VagrantNodeMapRaw, Default: ""},
+	}
 }
 
 // -------------------------------------------
@@ -96,19 +123,54 @@ var envConfigList = []envconfig{
 type FConfig struct {
 	ApiOperateTimeout     time.Duration
 	ResourceDeleteTimeout time.Duration
+	// one of ClusterProbe* mode, empty means ClusterProbeOff
+	ClusterProbe string
+	// the root directory of the failure diagnostics, empty means no dump on failure
+	ArtifactsDir string
+	// one of docker, podman and nerdctl, empty means the first one found in PATH
+	ContainerRuntime string
+	// one of NodeAccess*, empty means kind for the node in KindNodeList, then vagrant with VagrantDir
+	NodeAccess string
+	// for NodeAccessSSH, empty means the ssh config and agent
+	SSHUser    string
+	SSHKeyFile string
+	// the known_hosts of NodeAccessSSH with the strict host key checking, empty means the known_hosts of the user
+	SSHKnownHostsFile string
+	// SSHInsecureHostKey skips the host key checking of NodeAccessSSH, only for the throwaway nodes
+	SSHInsecureHostKey bool
 }
 
+// FConfigInformation holds the FConfig loaded from E2E_CONFIG_FILE, zero field means default
+var FConfigInformation = &FConfig{}
+
 type Framework struct {
 	// clienset
 	KClient client.WithWatch
 	KConfig *rest.Config
+	// for the sub resource like the pod log, nil for the fake client unless set by the unitest
+	KClientSet kubernetes.Interface
 
 	// cluster info
 	Info ClusterInfo
 
+	// Namespace is the namespace of the current spec, set by CreateTestNamespace
+	Namespace string
+
 	t         TestingT
 	Config    FConfig
 	EnableLog bool
+
+	// the runtime of the kind nodes, see ContainerRuntime()
+	Runtime ContainerRuntime
+	// NodeExecutor runs the command of ExecOnNode, nil means the one of Config.NodeAccess
+	NodeExecutor NodeExecutor
+
+	// the objects created by CreateResource, for Cleanup
+	tracker *objectTracker
+	// the faults injected on the nodes, for RollbackFaults
+	faults *faultTracker
+	// the nodes changed by the spec, for RestoreNodeState
+	nodeStates *nodeStateTracker
 }
 
 // -------------------------------------------
@@ -124,6 +186,59 @@ var (
 	Default_k8sClient_ResourceDeleteTimeout = 60 * time.Second
 )
 
+// NewScheme returns the scheme of the real client, with the types of the framework and schemeRegisterList
+func NewScheme(schemeRegisterList []func(*runtime.Scheme) error) (*runtime.Scheme, error) {
+	scheme := runtime.NewScheme()
+	err := corev1.AddToScheme(scheme)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add runtime Scheme : %v", err)
+	}
+
+	err = appsv1.AddToScheme(scheme)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add appsv1 Scheme : %v", err)
+	}
+
+	err = batchv1.AddToScheme(scheme)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add batchv1 Scheme")
+	}
+
+	err = network_v1alpha1.AddToScheme(scheme)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add networking Scheme")
+	}
+
+	err = apiextensions_v1.AddToScheme(scheme)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add apiextensions_v1 Scheme : %v", err)
+	}
+
+	err = nadv1.AddToScheme(scheme)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add network_attachment_definition_v1 Scheme")
+	}
+
+	// for the eviction of DrainNode
+	err = policyv1.AddToScheme(scheme)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add policyv1 Scheme : %v", err)
+	}
+
+	// for APIThrottleAction
+	err = flowcontrolv1.AddToScheme(scheme)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add flowcontrolv1 Scheme : %v", err)
+	}
+
+	for n, v := range schemeRegisterList {
+		if err := v(scheme); err != nil {
+			return nil, fmt.Errorf("failed to add schemeRegisterList[%v], reason=%v ", n, err)
+		}
+	}
+	return scheme, nil
+}
+
 // NewFramework init Framework struct
 // fakeClient for unitest
 func NewFramework(t TestingT, schemeRegisterList []func(*runtime.Scheme) error, fakeClient ...client.WithWatch) (*Framework, error) {
@@ -132,9 +247,6 @@ func NewFramework(t TestingT, schemeRegisterList []func(*runtime.Scheme) error,
 		return nil, fmt.Errorf("miss TestingT")
 	}
 
-	var err error
-	var ok bool
-
 	// defer GinkgoRecover()
 	if len(ClusterInformation.ClusterName) == 0 {
 		if e := initClusterInfo(); e != nil {
@@ -142,11 +254,28 @@ func NewFramework(t TestingT, schemeRegisterList []func(*runtime.Scheme) error,
 		}
 	}
 
+	return NewFrameworkWithClusterInfo(t, *ClusterInformation, schemeRegisterList, fakeClient...)
+}
+
+// NewFrameworkWithClusterInfo init Framework struct for the given cluster, rather than ClusterInformation
+// fakeClient for unitest
+func NewFrameworkWithClusterInfo(t TestingT, info ClusterInfo, schemeRegisterList []func(*runtime.Scheme) error, fakeClient ...client.WithWatch) (*Framework, error) {
+
+	if t == nil {
+		return nil, fmt.Errorf("miss TestingT")
+	}
+
+	var err error
+	var ok bool
+
 	f := &Framework{}
 	f.t = t
 	f.EnableLog = true
+	f.tracker = &objectTracker{}
+	f.faults = &faultTracker{}
+	f.nodeStates = &nodeStateTracker{}
 
-	v := deepcopy.Copy(*ClusterInformation)
+	v := deepcopy.Copy(info)
 	f.Info, ok = v.(ClusterInfo)
 	if !ok {
 		return nil, fmt.Errorf("internal error, failed to deepcopy")
@@ -158,59 +287,53 @@ func NewFramework(t TestingT, schemeRegisterList []func(*runtime.Scheme) error,
 		if f.Info.KubeConfigPath == "" {
 			return nil, fmt.Errorf("miss KubeConfig Path")
 		}
-		f.KConfig, err = clientcmd.BuildConfigFromFlags("", f.Info.KubeConfigPath)
+		f.KConfig, err = buildKubeConfig(f.Info.KubeConfigPath, f.Info.KubeContext)
 		if err != nil {
 			return nil, fmt.Errorf("BuildConfigFromFlags failed % v", err)
 		}
 		f.KConfig.QPS = Default_k8sClient_QPS
 		f.KConfig.Burst = Default_k8sClient_Burst
 
-		scheme := runtime.NewScheme()
-		err = corev1.AddToScheme(scheme)
-		if err != nil {
-			return nil, fmt.Errorf("failed to add runtime Scheme : %v", err)
-		}
-
-		err = appsv1.AddToScheme(scheme)
-		if err != nil {
-			return nil, fmt.Errorf("failed to add appsv1 Scheme : %v", err)
-		}
-
-		err = batchv1.AddToScheme(scheme)
-		if err != nil {
-			return nil, fmt.Errorf("failed to add batchv1 Scheme")
-		}
-
-		err = network_v1alpha1.AddToScheme(scheme)
-		if err != nil {
-			return nil, fmt.Errorf("failed to add networking Scheme")
-		}
-
-		err = apiextensions_v1.AddToScheme(scheme)
-		if err != nil {
-			return nil, fmt.Errorf("failed to add apiextensions_v1 Scheme : %v", err)
-		}
-		// f.Client, err = client.New(f.kConfig, client.Options{Scheme: scheme})
-
-		err = nadv1.AddToScheme(scheme)
+		scheme, err := NewScheme(schemeRegisterList)
 		if err != nil {
-			return nil, fmt.Errorf("failed to add network_attachment_definition_v1 Scheme")
-		}
-
-		for n, v := range schemeRegisterList {
-			if err := v(scheme); err != nil {
-				return nil, fmt.Errorf("failed to add schemeRegisterList[%v], reason=%v ", n, err)
-			}
+			return nil, err
 		}
 
 		f.KClient, err = client.NewWithWatch(f.KConfig, client.Options{Scheme: scheme})
 		if err != nil {
 			return nil, fmt.Errorf("failed to new clientset: %v", err)
 		}
+		f.KClientSet, err = kubernetes.NewForConfig(f.KConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to new kubernetes clientset: %v", err)
+		}
 	}
 
 	f.Config.ApiOperateTimeout = Default_k8sClient_ApiOperateTimeout
 	f.Config.ResourceDeleteTimeout = Default_k8sClient_ResourceDeleteTimeout
+	if FConfigInformation.ApiOperateTimeout != 0 {
+		f.Config.ApiOperateTimeout = FConfigInformation.ApiOperateTimeout
+	}
+	if FConfigInformation.ResourceDeleteTimeout != 0 {
+		f.Config.ResourceDeleteTimeout = FConfigInformation.ResourceDeleteTimeout
+	}
+	f.Config.ClusterProbe = FConfigInformation.ClusterProbe
+	f.Config.ArtifactsDir = FConfigInformation.ArtifactsDir
+	f.Config.ContainerRuntime = FConfigInformation.ContainerRuntime
+	f.Config.NodeAccess = FConfigInformation.NodeAccess
+	f.Config.SSHUser = FConfigInformation.SSHUser
+	f.Config.SSHKeyFile = FConfigInformation.SSHKeyFile
+	f.Config.SSHKnownHostsFile = FConfigInformation.SSHKnownHostsFile
+	f.Config.SSHInsecureHostKey = FConfigInformation.SSHInsecureHostKey
+	if err := f.Config.Validate(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), f.Config.ApiOperateTimeout)
+	defer cancel()
+	if err := f.CheckClusterInfo(ctx, f.Config.ClusterProbe); err != nil {
+		return nil, err
+	}
 
 	f.t.Logf("Framework ClusterInfo: %+v \n", f.Info)
 	f.t.Logf("Framework Config: %+v \n", f.Config)
@@ -218,77 +341,169 @@ func NewFramework(t TestingT, schemeRegisterList []func(*runtime.Scheme) error,
 	return f, nil
 }
 
+func buildKubeConfig(kubeConfigPath, kubeContext string) (*rest.Config, error) {
+	if kubeContext == "" {
+		return clientcmd.BuildConfigFromFlags("", kubeConfigPath)
+	}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeConfigPath},
+		&clientcmd.ConfigOverrides{CurrentContext: kubeContext},
+	).ClientConfig()
+}
+
 // ------------- basic operate
+// the XxxContext methods take the parent ctx first, so a cancelled ginkgo SpecContext stops them.
+// Each api call is still limited by Config.ApiOperateTimeout
 
 func (f *Framework) CreateResource(obj client.Object, opts ...client.CreateOption) error {
-	ctx1, cancel1 := context.WithTimeout(context.Background(), f.Config.ApiOperateTimeout)
+	return f.CreateResourceContext(context.Background(), obj, opts...)
+}
+
+func (f *Framework) CreateResourceContext(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	ctx1, cancel1 := context.WithTimeout(ctx, f.Config.ApiOperateTimeout)
 	defer cancel1()
-	return f.KClient.Create(ctx1, obj, opts...)
+	if err := f.KClient.Create(ctx1, obj, opts...); err != nil {
+		return err
+	}
+	if o := (&client.CreateOptions{}).ApplyOptions(opts); len(o.DryRun) == 0 {
+		f.track(obj)
+	}
+	return nil
 }
 
 func (f *Framework) DeleteResource(obj client.Object, opts ...client.DeleteOption) error {
-	ctx2, cancel2 := context.WithTimeout(context.Background(), f.Config.ApiOperateTimeout)
+	return f.DeleteResourceContext(context.Background(), obj, opts...)
+}
+
+func (f *Framework) DeleteResourceContext(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	ctx2, cancel2 := context.WithTimeout(ctx, f.Config.ApiOperateTimeout)
 	defer cancel2()
 	return f.KClient.Delete(ctx2, obj, opts...)
 }
 
 func (f *Framework) GetResource(key client.ObjectKey, obj client.Object) error {
-	ctx3, cancel3 := context.WithTimeout(context.Background(), f.Config.ApiOperateTimeout)
+	return f.GetResourceContext(context.Background(), key, obj)
+}
+
+func (f *Framework) GetResourceContext(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	ctx3, cancel3 := context.WithTimeout(ctx, f.Config.ApiOperateTimeout)
 	defer cancel3()
 	return f.KClient.Get(ctx3, key, obj)
 }
 
 func (f *Framework) ListResource(list client.ObjectList, opts ...client.ListOption) error {
-	ctx4, cancel4 := context.WithTimeout(context.Background(), f.Config.ApiOperateTimeout)
+	return f.ListResourceContext(context.Background(), list, opts...)
+}
+
+func (f *Framework) ListResourceContext(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	ctx4, cancel4 := context.WithTimeout(ctx, f.Config.ApiOperateTimeout)
 	defer cancel4()
 	return f.KClient.List(ctx4, list, opts...)
 }
 
 func (f *Framework) UpdateResource(obj client.Object, opts ...client.UpdateOption) error {
-	ctx5, cancel5 := context.WithTimeout(context.Background(), f.Config.ApiOperateTimeout)
+	return f.UpdateResourceContext(context.Background(), obj, opts...)
+}
+
+func (f *Framework) UpdateResourceContext(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	ctx5, cancel5 := context.WithTimeout(ctx, f.Config.ApiOperateTimeout)
 	defer cancel5()
 	return f.KClient.Update(ctx5, obj, opts...)
 }
 
 func (f *Framework) UpdateResourceStatus(obj client.Object, opts ...client.SubResourceUpdateOption) error {
-	ctx6, cancel6 := context.WithTimeout(context.Background(), f.Config.ApiOperateTimeout)
+	return f.UpdateResourceStatusContext(context.Background(), obj, opts...)
+}
+
+func (f *Framework) UpdateResourceStatusContext(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+	ctx6, cancel6 := context.WithTimeout(ctx, f.Config.ApiOperateTimeout)
 	defer cancel6()
 	return f.KClient.Status().Update(ctx6, obj, opts...)
 }
 
 func (f *Framework) PatchResource(obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
-	ctx7, cancel7 := context.WithTimeout(context.Background(), f.Config.ApiOperateTimeout)
+	return f.PatchResourceContext(context.Background(), obj, patch, opts...)
+}
+
+func (f *Framework) PatchResourceContext(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	ctx7, cancel7 := context.WithTimeout(ctx, f.Config.ApiOperateTimeout)
 	defer cancel7()
 	return f.KClient.Patch(ctx7, obj, patch, opts...)
 }
 
+// initClusterInfo fills a copy of ClusterInformation with the default values, then the config file of E2E_CONFIG_FILE,
+// then the E2E_* ENV, and reports the errors of the config file, the ENV, ClusterInfo and FConfig at once. ClusterInformation and FConfigInformation are set
+// only when all fields are valid, so the failed init is run again by the next NewFramework
 func initClusterInfo() error {
+	var errs []error
+	info := *ClusterInformation
+	fc := *FConfigInformation
+	list := envConfigList(&info, &fc)
+
+	for _, v := range list {
+		if err := v.set(v.Default); err != nil {
+			return err
+		}
+	}
 
-	for _, v := range envConfigList {
-		t := os.Getenv(v.EnvName)
-		if len(t) == 0 && v.Required {
-			return fmt.Errorf("error, failed to get ENV %s", v.EnvName)
+	if path := os.Getenv(E2E_CONFIG_FILE); len(path) > 0 {
+		if err := LoadClusterConfig(path, os.Getenv(E2E_CONFIG_PROFILE), &info, &fc); err != nil {
+			errs = append(errs, err)
 		}
-		r := v.Default
-		if len(t) > 0 {
-			r = t
+	}
+
+	for _, v := range list {
+		t := os.Getenv(v.EnvName)
+		if len(t) == 0 {
+			continue
 		}
-		if v.DestStr != nil {
-			*(v.DestStr) = r
-		} else {
-			if s, err := strconv.ParseBool(r); err != nil {
-				return fmt.Errorf("error, %v require a bool value, but get %v", v.EnvName, r)
-			} else {
-				*(v.DestBool) = s
-			}
+		if err := v.set(t); err != nil {
+			errs = append(errs, err)
 		}
 	}
 
-	if len(ClusterInformation.KindNodeListRaw) > 0 {
-		ClusterInformation.KindNodeList = strings.Split(ClusterInformation.KindNodeListRaw, ",")
+	info.KindNodeList = nil
+	if len(info.KindNodeListRaw) > 0 {
+		info.KindNodeList = strings.Split(info.KindNodeListRaw, ",")
 	}
+	info.VagrantNodeMap = nil
+	if len(info.VagrantNodeMapRaw) > 0 {
+		m, err := parseVagrantNodeMap(info.VagrantNodeMapRaw)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		info.VagrantNodeMap = m
+	}
+	errs = append(errs, info.Validate())
+	// the zero timeout is replaced by the default one in NewFrameworkWithClusterInfo
+	checked := fc
+	if checked.ApiOperateTimeout == 0 {
+		checked.ApiOperateTimeout = Default_k8sClient_ApiOperateTimeout
+	}
+	if checked.ResourceDeleteTimeout == 0 {
+		checked.ResourceDeleteTimeout = Default_k8sClient_ResourceDeleteTimeout
+	}
+	errs = append(errs, checked.Validate())
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	*ClusterInformation = info
+	*FConfigInformation = fc
 	return nil
+}
 
+func (v *envconfig) set(value string) error {
+	if v.DestStr != nil {
+		*(v.DestStr) = value
+		return nil
+	}
+	s, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("error, %v require a bool value, but get %v", v.EnvName, value)
+	}
+	*(v.DestBool) = s
+	return nil
 }
 
 func (f *Framework) Log(format string, args ...interface{}) {
@@ -296,3 +511,13 @@ func (f *Framework) Log(format string, args ...interface{}) {
 		f.t.Logf(format, args...)
 	}
 }
+
+// sleepContext sleeps for d, or returns early when ctx is done
+func sleepContext(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
@@ -33,4 +33,43 @@ var _ = Describe("test new Framework", Label("framework"), func() {
 		Expect(e).NotTo(HaveOccurred())
 	})
 
+	It("init the cluster info again after the failure", func() {
+		saved := *e2e.ClusterInformation
+		DeferCleanup(func() { *e2e.ClusterInformation = saved })
+		*e2e.ClusterInformation = e2e.ClusterInfo{}
+
+		os.Setenv(e2e.E2E_KUBECONFIG_PATH, "")
+		_, e := e2e.NewFramework(GinkgoT(), nil, fakeClient)
+		Expect(e).To(HaveOccurred())
+		// the invalid info is not kept
+		Expect(e2e.ClusterInformation.ClusterName).To(BeEmpty())
+
+		os.Setenv(e2e.E2E_KUBECONFIG_PATH, kubeConfigFile.Name())
+		f, e := e2e.NewFramework(GinkgoT(), nil, fakeClient)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(f.Info.ClusterName).To(Equal("testcluster"))
+		Expect(e2e.ClusterInformation.KindNodeList).To(Equal([]string{"master", "worker"}))
+	})
+
+	It("report the errors of the config file, ENV and framework config at once", func() {
+		saved := *e2e.ClusterInformation
+		DeferCleanup(func() {
+			*e2e.ClusterInformation = saved
+			os.Unsetenv(e2e.E2E_CONFIG_FILE)
+			os.Unsetenv(e2e.E2E_CLUSTER_PROBE)
+		})
+		*e2e.ClusterInformation = e2e.ClusterInfo{}
+
+		os.Setenv(e2e.E2E_CONFIG_FILE, "/tmp/not-existed-e2e-config")
+		os.Setenv(e2e.E2E_KUBECONFIG_PATH, "")
+		os.Setenv(e2e.E2E_CLUSTER_PROBE, "unknown")
+		_, e := e2e.NewFramework(GinkgoT(), nil, fakeClient)
+		Expect(e).To(HaveOccurred())
+		GinkgoWriter.Printf("expected error: %v \n", e)
+		Expect(e.Error()).To(ContainSubstring("not-existed-e2e-config"))
+		Expect(e.Error()).To(ContainSubstring(e2e.E2E_KUBECONFIG_PATH))
+		Expect(e.Error()).To(ContainSubstring(e2e.E2E_CLUSTER_PROBE))
+		Expect(e2e.ClusterInformation.ClusterName).To(BeEmpty())
+	})
+
 })
@@ -0,0 +1,138 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	api_errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// the generic helpers work for any type registered in the scheme of Framework,
+// including the CRD of schemeRegisterList, like:
+//   err := framework.Create(ctx, f, pool)
+//   pool, err := framework.Get[*spiderpoolv2beta1.SpiderIPPool](ctx, f, name, "")
+
+// Create waits for a same-named object to finish deleting, then creates obj
+func Create[T client.Object](ctx context.Context, f *Framework, obj T, opts ...client.CreateOption) error {
+	if f == nil || isNilObject(obj) || obj.GetName() == "" {
+		return ErrWrongInput
+	}
+
+	key := client.ObjectKeyFromObject(obj)
+	kind := f.kindOf(obj)
+	existing := newObjectLike(obj)
+	e := f.GetResourceContext(ctx, key, existing)
+	if e == nil && existing.GetDeletionTimestamp() == nil {
+		return fmt.Errorf("%w: %s '%s'", ErrAlreadyExisted, kind, keyString(key))
+	}
+	if e == nil {
+		// try to wait for finish last deleting
+		f.Log("waiting for a same %s %s to finish deleting \n", kind, keyString(key))
+		waitCtx, cancel := context.WithTimeout(ctx, f.Config.ResourceDeleteTimeout)
+		defer cancel()
+		if e := waitGone(waitCtx, f, obj); e != nil {
+			return e
+		}
+	}
+	return f.CreateResourceContext(ctx, obj, opts...)
+}
+
+// Get returns the object of T, namespace is empty for the cluster scope object
+func Get[T client.Object](ctx context.Context, f *Framework, name, namespace string) (T, error) {
+	var empty T
+	if f == nil || name == "" {
+		return empty, ErrWrongInput
+	}
+	existing := newObject[T]()
+	if e := f.GetResourceContext(ctx, client.ObjectKey{Namespace: namespace, Name: name}, existing); e != nil {
+		return empty, e
+	}
+	return existing, nil
+}
+
+// List returns the object list of L
+func List[L client.ObjectList](ctx context.Context, f *Framework, opts ...client.ListOption) (L, error) {
+	var empty L
+	if f == nil {
+		return empty, ErrWrongInput
+	}
+	list := newObject[L]()
+	if e := f.ListResourceContext(ctx, list, opts...); e != nil {
+		return empty, e
+	}
+	return list, nil
+}
+
+// DeleteAndWait deletes the object of T, and waits until it is gone or ctx is done.
+// It succeeds when the object does not exist
+func DeleteAndWait[T client.Object](ctx context.Context, f *Framework, name, namespace string, opts ...client.DeleteOption) error {
+	if f == nil || name == "" {
+		return ErrWrongInput
+	}
+	obj := newObject[T]()
+	obj.SetName(name)
+	obj.SetNamespace(namespace)
+	return deleteAndWait(ctx, f, obj, opts...)
+}
+
+// WaitFor waits until pred returns true for the object of T, or ctx is done.
+// A not-existed object is waited for, rather than failed
+func WaitFor[T client.Object](ctx context.Context, f *Framework, name, namespace string, pred func(T) bool) (T, error) {
+	var empty T
+	if f == nil || name == "" || pred == nil {
+		return empty, ErrWrongInput
+	}
+	return waitFor(ctx, f, client.ObjectKey{Namespace: namespace, Name: name}, newObject[T], pred)
+}
+
+func deleteAndWait[T client.Object](ctx context.Context, f *Framework, obj T, opts ...client.DeleteOption) error {
+	if e := f.DeleteResourceContext(ctx, obj, opts...); e != nil && !api_errors.IsNotFound(e) {
+		return e
+	}
+
+	return waitGone(ctx, f, obj)
+}
+
+// newObject returns a new object of the pointer type T
+func newObject[T any]() T {
+	var t T
+	return reflect.New(reflect.TypeOf(t).Elem()).Interface().(T)
+}
+
+// newObjectLike returns a new object of the same type of obj, and keeps the GVK of the unstructured object.
+// T may be client.Object
+func newObjectLike[T client.Object](obj T) T {
+	n := reflect.New(reflect.TypeOf(obj).Elem()).Interface().(T)
+	if _, ok := any(n).(runtime.Unstructured); ok {
+		n.GetObjectKind().SetGroupVersionKind(obj.GetObjectKind().GroupVersionKind())
+	}
+	return n
+}
+
+func isNilObject[T any](obj T) bool {
+	v := reflect.ValueOf(obj)
+	return !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil())
+}
+
+// kindOf returns the lower case kind of obj for the log and error
+func (f *Framework) kindOf(obj client.Object) string {
+	gvk, err := apiutil.GVKForObject(obj, f.KClient.Scheme())
+	if err != nil {
+		return fmt.Sprintf("%T", obj)
+	}
+	return strings.ToLower(gvk.Kind)
+}
+
+func keyString(key client.ObjectKey) string {
+	if key.Namespace == "" {
+		return key.Name
+	}
+	return key.String()
+}
@@ -0,0 +1,132 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	e2e "github.com/spidernet-io/e2eframework/framework"
+	spiderv2beta1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v2beta1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("test generic helpers", Label("generic"), func() {
+	var f *e2e.Framework
+	var ctx context.Context
+
+	BeforeEach(func() {
+		f = fakeFramework()
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 20*time.Second)
+		DeferCleanup(cancel)
+	})
+
+	It("operate the crd", func() {
+		pool := &spiderv2beta1.SpiderIPPool{
+			ObjectMeta: metav1.ObjectMeta{Name: "pool-v4"},
+			Spec: spiderv2beta1.IPPoolSpec{
+				Subnet: "172.40.0.0/16",
+			},
+		}
+		Expect(e2e.Create(ctx, f, pool)).To(Succeed())
+		Expect(e2e.Create(ctx, f, pool.DeepCopy())).To(MatchError(e2e.ErrAlreadyExisted))
+
+		p, e := e2e.Get[*spiderv2beta1.SpiderIPPool](ctx, f, "pool-v4", "")
+		Expect(e).NotTo(HaveOccurred())
+		Expect(p.Spec.Subnet).To(Equal("172.40.0.0/16"))
+
+		_, e = e2e.Get[*spiderv2beta1.SpiderIPPool](ctx, f, "not-existed", "")
+		Expect(e).To(HaveOccurred())
+		_, e = e2e.Get[*spiderv2beta1.SpiderIPPool](ctx, f, "", "")
+		Expect(e).To(MatchError(e2e.ErrWrongInput))
+
+		list, e := e2e.List[*spiderv2beta1.SpiderIPPoolList](ctx, f)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(list.Items).To(HaveLen(1))
+
+		Expect(e2e.DeleteAndWait[*spiderv2beta1.SpiderIPPool](ctx, f, "pool-v4", "")).To(Succeed())
+		Expect(e2e.DeleteAndWait[*spiderv2beta1.SpiderIPPool](ctx, f, "pool-v4", "")).To(Succeed())
+		list, e = e2e.List[*spiderv2beta1.SpiderIPPoolList](ctx, f)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(list.Items).To(BeEmpty())
+	})
+
+	It("create after the same object finishes deleting", func() {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "cm-generic",
+				Namespace:  "default",
+				Finalizers: []string{"e2e.spidernet.io/test"},
+			},
+		}
+		Expect(e2e.Create(ctx, f, cm)).To(Succeed())
+		Expect(f.DeleteConfigmap(cm.Name, cm.Namespace)).To(Succeed())
+
+		go func() {
+			defer GinkgoRecover()
+			time.Sleep(2 * time.Second)
+			existing, e := e2e.Get[*corev1.ConfigMap](ctx, f, cm.Name, cm.Namespace)
+			Expect(e).NotTo(HaveOccurred())
+			existing.Finalizers = nil
+			Expect(f.UpdateResource(existing)).To(Succeed())
+		}()
+
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "cm-generic", Namespace: "default"},
+			Data:       map[string]string{"version": "v2"},
+		}
+		Expect(e2e.Create(ctx, f, cm)).To(Succeed())
+		existing, e := e2e.Get[*corev1.ConfigMap](ctx, f, cm.Name, cm.Namespace)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(existing.DeletionTimestamp).To(BeNil())
+		Expect(existing.Data["version"]).To(Equal("v2"))
+	})
+
+	It("wait for the condition", func() {
+		go func() {
+			defer GinkgoRecover()
+			time.Sleep(time.Second)
+			pod := generateExamplePodYaml("pod-generic", "default", nil, corev1.PodPending)
+			Expect(e2e.Create(ctx, f, pod)).To(Succeed())
+			time.Sleep(time.Second)
+			pod.Status.Phase = corev1.PodRunning
+			Expect(f.UpdateResourceStatus(pod)).To(Succeed())
+		}()
+
+		pod, e := e2e.WaitFor(ctx, f, "pod-generic", "default", func(pod *corev1.Pod) bool {
+			return pod.Status.Phase == corev1.PodRunning
+		})
+		Expect(e).NotTo(HaveOccurred())
+		Expect(pod.Name).To(Equal("pod-generic"))
+
+		shortCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+		pod, e = e2e.WaitFor(shortCtx, f, "pod-generic", "default", func(pod *corev1.Pod) bool {
+			return pod.Status.Phase == corev1.PodSucceeded
+		})
+		Expect(e).To(MatchError(e2e.ErrTimeOut))
+		Expect(pod).NotTo(BeNil())
+		Expect(pod.Status.Phase).To(Equal(corev1.PodRunning))
+
+		shortCtx, cancel = context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+		_, e = e2e.WaitFor(shortCtx, f, "not-existed", "default", func(pod *corev1.Pod) bool { return true })
+		Expect(e).To(MatchError(e2e.ErrTimeOut))
+	})
+
+	It("fail for the type out of scheme", func() {
+		ing := &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: "ing", Namespace: "default"}}
+		Expect(e2e.Create(ctx, f, ing)).NotTo(Succeed())
+		_, e := e2e.Get[*networkingv1.Ingress](ctx, f, "ing", "default")
+		Expect(e).To(HaveOccurred())
+		_, e = e2e.List[*networkingv1.IngressList](ctx, f, client.InNamespace("default"))
+		Expect(e).To(HaveOccurred())
+	})
+})
@@ -4,53 +4,31 @@ package framework
 
 import (
 	"context"
-	"fmt"
-	"time"
-
-	"github.com/spidernet-io/e2eframework/tools"
 
 	//appsv1beta2 "k8s.io/api/apps/v1beta2"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
-	api_errors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func (f *Framework) CreateJob(jb *batchv1.Job, opts ...client.CreateOption) error {
+	return f.CreateJobContext(context.Background(), jb, opts...)
+}
 
-	// try to wait for finish last deleting
-	fake := &batchv1.Job{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: jb.Namespace,
-			Name:      jb.Name,
-		},
-	}
-	key := client.ObjectKeyFromObject(fake)
-	existing := &batchv1.Job{}
-	e := f.GetResource(key, existing)
-	if e == nil && existing.DeletionTimestamp == nil {
-		return fmt.Errorf("%w: job '%s/%s'", ErrAlreadyExisted, existing.Namespace, existing.Name)
-	}
-	t := func() bool {
-		existing := &batchv1.Job{}
-		e := f.GetResource(key, existing)
-		b := api_errors.IsNotFound(e)
-		if !b {
-			f.Log("waiting for a same Job %v/%v to finish deleting \n", jb.Name, jb.Namespace)
-			return false
-		}
-		return true
-	}
-	if !tools.Eventually(t, f.Config.ResourceDeleteTimeout, time.Second) {
-		return ErrTimeOut
+func (f *Framework) CreateJobContext(ctx context.Context, jb *batchv1.Job, opts ...client.CreateOption) error {
+	if jb == nil {
+		return ErrWrongInput
 	}
-
-	return f.CreateResource(jb, opts...)
+	return Create(ctx, f, jb, opts...)
 }
 
 func (f *Framework) DeleteJob(name, namespace string, opts ...client.DeleteOption) error {
+	return f.DeleteJobContext(context.Background(), name, namespace, opts...)
+}
+
+func (f *Framework) DeleteJobContext(ctx context.Context, name, namespace string, opts ...client.DeleteOption) error {
 	if name == "" || namespace == "" {
 		return ErrWrongInput
 
@@ -62,30 +40,25 @@ func (f *Framework) DeleteJob(name, namespace string, opts ...client.DeleteOptio
 			Name:      name,
 		},
 	}
-	return f.DeleteResource(jb, opts...)
+	return f.DeleteResourceContext(ctx, jb, opts...)
 }
 
 func (f *Framework) GetJob(name, namespace string) (*batchv1.Job, error) {
+	return f.GetJobContext(context.Background(), name, namespace)
+}
+
+func (f *Framework) GetJobContext(ctx context.Context, name, namespace string) (*batchv1.Job, error) {
 	if name == "" || namespace == "" {
 		return nil, ErrWrongInput
 	}
-
-	jb := &batchv1.Job{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: namespace,
-			Name:      name,
-		},
-	}
-	key := client.ObjectKeyFromObject(jb)
-	existing := &batchv1.Job{}
-	e := f.GetResource(key, existing)
-	if e != nil {
-		return nil, e
-	}
-	return existing, e
+	return Get[*batchv1.Job](ctx, f, name, namespace)
 }
 
 func (f *Framework) GetJobPodList(jb *batchv1.Job) (*corev1.PodList, error) {
+	return f.GetJobPodListContext(context.Background(), jb)
+}
+
+func (f *Framework) GetJobPodListContext(ctx context.Context, jb *batchv1.Job) (*corev1.PodList, error) {
 	if jb == nil {
 		return nil, ErrWrongInput
 	}
@@ -95,7 +68,7 @@ func (f *Framework) GetJobPodList(jb *batchv1.Job) (*corev1.PodList, error) {
 			Selector: labels.SelectorFromSet(jb.Spec.Selector.MatchLabels),
 		},
 	}
-	e := f.ListResource(pods, ops...)
+	e := f.ListResourceContext(ctx, pods, ops...)
 	if e != nil {
 		return nil, e
 	}
@@ -104,26 +77,28 @@ func (f *Framework) GetJobPodList(jb *batchv1.Job) (*corev1.PodList, error) {
 
 // WaitJobFinished wait for all job pod finish , no matter succeed or fail
 func (f *Framework) WaitJobFinished(jobName, namespace string, ctx context.Context) (*batchv1.Job, bool, error) {
-	for {
-		select {
-		default:
-			job, err := f.GetJob(jobName, namespace)
-			if err != nil {
-				return nil, false, err
+	return f.WaitJobFinishedContext(ctx, jobName, namespace)
+}
+
+func (f *Framework) WaitJobFinishedContext(ctx context.Context, jobName, namespace string) (*batchv1.Job, bool, error) {
+	if jobName == "" || namespace == "" {
+		return nil, false, ErrWrongInput
+	}
+	succeeded := false
+	job, err := waitUntilNotDeleted(ctx, f, jobName, namespace, "finished", newObject[*batchv1.Job], func(job *batchv1.Job) (bool, error) {
+		for _, c := range job.Status.Conditions {
+			if c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue {
+				return true, nil
 			}
-			for _, c := range job.Status.Conditions {
-				if c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue {
-					return job, false, nil
-				}
-				if c.Type == batchv1.JobComplete && c.Status == corev1.ConditionTrue {
-					return job, true, nil
-				}
+			if c.Type == batchv1.JobComplete && c.Status == corev1.ConditionTrue {
+				succeeded = true
+				return true, nil
 			}
-
-			time.Sleep(time.Second)
-		case <-ctx.Done():
-			return nil, false, ErrTimeOut
-
 		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, false, err
 	}
+	return job, succeeded, nil
 }
@@ -0,0 +1,413 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensions_v1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	api_errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// the manifests shipped to the users are applied as they are, like:
+//   //go:embed manifests
+//   var manifests embed.FS
+//   objs, err := f.ApplyManifests(ctx, framework.ManifestFromFS(manifests, "manifests"), &framework.ManifestOptions{Wait: true})
+//   defer f.DeleteManifests(ctx, framework.ManifestFromFS(manifests, "manifests"), nil)
+
+// DefaultFieldManager is the field manager of the server-side apply by default
+const DefaultFieldManager = "e2eframework"
+
+// ManifestFile is a file of the multi-document YAML or JSON
+type ManifestFile struct {
+	Name string
+	Data []byte
+}
+
+// ManifestSource reads the manifest files, in the order of applying
+type ManifestSource func() ([]ManifestFile, error)
+
+// ManifestOptions is the options of ApplyManifests and DeleteManifests, nil means the default
+type ManifestOptions struct {
+	// the field manager of the server-side apply, DefaultFieldManager by default
+	FieldManager string
+	// the namespace of the namespaced object without one, Framework.Namespace by default
+	Namespace string
+	// take the ownership of the fields conflicting with the other field managers
+	Force bool
+	// wait for every applied object to be ready, or every deleted object to be gone
+	Wait bool
+}
+
+// ManifestFromFile reads the file
+func ManifestFromFile(filePath string) ManifestSource {
+	return func() ([]ManifestFile, error) {
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		return []ManifestFile{{Name: filePath, Data: data}}, nil
+	}
+}
+
+// ManifestFromDir reads the .yaml, .yml and .json files under dir recursively, in the lexical order
+func ManifestFromDir(dir string) ManifestSource {
+	return func() ([]ManifestFile, error) {
+		files, err := ManifestFromFS(os.DirFS(dir), ".")()
+		if err != nil {
+			return nil, err
+		}
+		for n := range files {
+			files[n].Name = path.Join(dir, files[n].Name)
+		}
+		return files, nil
+	}
+}
+
+// ManifestFromFS reads the file root, or the .yaml, .yml and .json files under the directory root
+// recursively in the lexical order, root is "." for the whole fsys
+func ManifestFromFS(fsys fs.FS, root string) ManifestSource {
+	return func() ([]ManifestFile, error) {
+		files := []ManifestFile{}
+		err := fs.WalkDir(fsys, root, func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			if name != root && !isManifestFile(name) {
+				return nil
+			}
+			data, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return err
+			}
+			files = append(files, ManifestFile{Name: name, Data: data})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return files, nil
+	}
+}
+
+// ManifestFromString reads the manifest in memory
+func ManifestFromString(manifest string) ManifestSource {
+	return func() ([]ManifestFile, error) {
+		return []ManifestFile{{Name: "string", Data: []byte(manifest)}}, nil
+	}
+}
+
+func isManifestFile(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// DecodeManifests splits the documents of source, and decodes them to the typed objects of the scheme,
+// or the unstructured objects for the kinds not registered. The List is expanded to its items.
+// The CRDs come first, then the namespaces, then the others in the order of source
+func (f *Framework) DecodeManifests(source ManifestSource) ([]client.Object, error) {
+	items, err := f.decodeManifestSource(source)
+	if err != nil {
+		return nil, err
+	}
+	return manifestObjects(items), nil
+}
+
+// manifestObject is a decoded object with the document it comes from
+type manifestObject struct {
+	obj client.Object
+	// the document as it is in the manifest, which is applied without the zero fields of the typed object
+	doc *unstructured.Unstructured
+}
+
+func manifestObjects(items []manifestObject) []client.Object {
+	objs := make([]client.Object, 0, len(items))
+	for _, item := range items {
+		objs = append(objs, item.obj)
+	}
+	return objs
+}
+
+// decodeManifestSource decodes the files of source in the order of DecodeManifests
+func (f *Framework) decodeManifestSource(source ManifestSource) ([]manifestObject, error) {
+	if source == nil {
+		return nil, ErrWrongInput
+	}
+	files, err := source()
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := f.decodeManifestFiles(files, false)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return manifestOrder(items[i].obj) < manifestOrder(items[j].obj)
+	})
+	return items, nil
+}
+
+// decodeManifestFiles decodes the files in order, and strict requires the typed objects without unknown fields
+func (f *Framework) decodeManifestFiles(files []ManifestFile, strict bool) ([]manifestObject, error) {
+	objs := []manifestObject{}
+	for _, file := range files {
+		docs, err := splitManifest(file.Data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrWrongInput, file.Name, err)
+		}
+		for n, doc := range docs {
+			items, err := f.decodeManifest(doc, strict)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s, document %d: %v", ErrWrongInput, file.Name, n+1, err)
+			}
+			objs = append(objs, items...)
+		}
+	}
+	return objs, nil
+}
+
+// splitManifest returns the JSON of the documents, and skips the empty ones
+func splitManifest(data []byte) ([][]byte, error) {
+	docs := [][]byte{}
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		j, err := yaml.YAMLToJSON(doc)
+		if err != nil {
+			return nil, err
+		}
+		if s := string(bytes.TrimSpace(j)); s == "" || s == "null" {
+			continue
+		}
+		docs = append(docs, j)
+	}
+}
+
+func (f *Framework) decodeManifest(doc []byte, strict bool) ([]manifestObject, error) {
+	u := &unstructured.Unstructured{}
+	if err := u.UnmarshalJSON(doc); err != nil {
+		return nil, err
+	}
+	docs := []*unstructured.Unstructured{u}
+	if u.IsList() {
+		list, err := u.ToList()
+		if err != nil {
+			return nil, err
+		}
+		docs = docs[:0]
+		for n := range list.Items {
+			docs = append(docs, &list.Items[n])
+		}
+	}
+
+	objs := []manifestObject{}
+	for _, d := range docs {
+		obj, err := f.toTyped(d, strict)
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, manifestObject{obj: obj, doc: d.DeepCopy()})
+	}
+	return objs, nil
+}
+
+// toTyped converts u to the typed object of the scheme, or returns u for the kinds not registered.
+// With strict, the kind must be registered and the unknown fields are refused
+func (f *Framework) toTyped(u *unstructured.Unstructured, strict bool) (client.Object, error) {
+	gvk := u.GroupVersionKind()
+	if gvk.Kind == "" || gvk.Version == "" {
+		return nil, fmt.Errorf("apiVersion and kind are required")
+	}
+	if u.GetName() == "" {
+		return nil, fmt.Errorf("%v has no name", gvk)
+	}
+	if !f.KClient.Scheme().Recognizes(gvk) {
+		if strict {
+			return nil, fmt.Errorf("%v is not registered in the scheme", gvk)
+		}
+		return u, nil
+	}
+	typed, err := f.KClient.Scheme().New(gvk)
+	if err != nil {
+		return nil, err
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructuredWithValidation(u.Object, typed, strict); err != nil {
+		return nil, err
+	}
+	obj, ok := typed.(client.Object)
+	if !ok {
+		return u, nil
+	}
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+	return obj, nil
+}
+
+func manifestOrder(obj client.Object) int {
+	switch obj.GetObjectKind().GroupVersionKind().GroupKind() {
+	case apiextensions_v1.SchemeGroupVersion.WithKind("CustomResourceDefinition").GroupKind():
+		return 0
+	case corev1.SchemeGroupVersion.WithKind("Namespace").GroupKind():
+		return 1
+	}
+	return 2
+}
+
+// ApplyManifests applies the objects of source by the server-side apply in the order of DecodeManifests,
+// and returns the applied objects updated to the state of the server. The applied objects are tracked for Cleanup.
+// With ManifestOptions.Wait, the CRDs are established before the others are applied,
+// and every object is waited to be ready by WaitReady
+func (f *Framework) ApplyManifests(ctx context.Context, source ManifestSource, opts *ManifestOptions) ([]client.Object, error) {
+	if opts == nil {
+		opts = &ManifestOptions{}
+	}
+	items, err := f.decodeManifestSource(source)
+	if err != nil {
+		return nil, err
+	}
+	objs := manifestObjects(items)
+
+	patchOpts := []client.PatchOption{client.FieldOwner(opts.FieldManager)}
+	if opts.FieldManager == "" {
+		patchOpts[0] = client.FieldOwner(DefaultFieldManager)
+	}
+	if opts.Force {
+		patchOpts = append(patchOpts, client.ForceOwnership)
+	}
+
+	for n, obj := range objs {
+		if opts.Wait && n > 0 && manifestOrder(objs[n-1]) == 0 && manifestOrder(obj) != 0 {
+			for _, crd := range objs[:n] {
+				if err := f.WaitReady(ctx, crd); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if err := f.applyManifest(ctx, items[n], opts, patchOpts); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Wait {
+		for _, obj := range objs {
+			if err := f.WaitReady(ctx, obj); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return objs, nil
+}
+
+// applyManifest applies the document as it is in the manifest, so the field manager owns only the fields set there,
+// the zero fields of the typed object like `strategy: {}` are not sent. Then the object is updated with the response
+func (f *Framework) applyManifest(ctx context.Context, item manifestObject, opts *ManifestOptions, patchOpts []client.PatchOption) error {
+	obj := item.obj
+	if err := f.defaultManifestNamespace(obj, opts); err != nil {
+		return err
+	}
+
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	u := item.doc.DeepCopy()
+	u.SetNamespace(obj.GetNamespace())
+	u.SetManagedFields(nil)
+
+	key := keyString(client.ObjectKeyFromObject(obj))
+	if err := f.PatchResourceContext(ctx, u, client.Apply, patchOpts...); err != nil {
+		return fmt.Errorf("failed to apply %s '%s': %w", strings.ToLower(gvk.Kind), key, err)
+	}
+	f.Log("applied %s '%s' \n", strings.ToLower(gvk.Kind), key)
+
+	if typed, ok := obj.(*unstructured.Unstructured); ok {
+		typed.Object = u.Object
+	} else {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, obj); err != nil {
+			return err
+		}
+		obj.GetObjectKind().SetGroupVersionKind(gvk)
+	}
+	f.track(obj)
+	return nil
+}
+
+// defaultManifestNamespace sets the namespace of the namespaced obj without one.
+// The kind unknown to the RESTMapper is left as it is, like the CR whose CRD is not established yet
+func (f *Framework) defaultManifestNamespace(obj client.Object, opts *ManifestOptions) error {
+	if obj.GetNamespace() != "" {
+		return nil
+	}
+	namespaced, err := f.KClient.IsObjectNamespaced(obj)
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil
+		}
+		return err
+	}
+	if namespaced {
+		if opts.Namespace != "" {
+			obj.SetNamespace(opts.Namespace)
+		} else {
+			obj.SetNamespace(f.Namespace)
+		}
+	}
+	return nil
+}
+
+// DeleteManifests deletes the objects of source in the reverse order of ApplyManifests, and ignores the deleted ones.
+// With ManifestOptions.Wait, every object is waited to be gone before the next one is deleted
+func (f *Framework) DeleteManifests(ctx context.Context, source ManifestSource, opts *ManifestOptions) error {
+	if opts == nil {
+		opts = &ManifestOptions{}
+	}
+	objs, err := f.DecodeManifests(source)
+	if err != nil {
+		return err
+	}
+
+	for n := len(objs) - 1; n >= 0; n-- {
+		obj := objs[n]
+		if err := f.defaultManifestNamespace(obj, opts); err != nil {
+			return err
+		}
+
+		if opts.Wait {
+			err = deleteAndWait(ctx, f, obj)
+		} else {
+			err = f.DeleteResourceContext(ctx, obj)
+		}
+		if err != nil && !api_errors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+			return fmt.Errorf("failed to delete %s '%s': %w", f.kindOf(obj), keyString(client.ObjectKeyFromObject(obj)), err)
+		}
+		f.Untrack(obj)
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing/fstest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	e2e "github.com/spidernet-io/e2eframework/framework"
+	corev1 "k8s.io/api/core/v1"
+	apiextensions_v1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	api_errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+const testManifest = `
+# the leading comment
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: manifest-cm
+data:
+  key: value
+---
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: manifest-ns
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ServiceAccount
+  metadata:
+    name: manifest-sa
+    namespace: manifest-ns
+- apiVersion: example.io/v1
+  kind: Foo
+  metadata:
+    name: manifest-foo
+    namespace: manifest-ns
+  spec:
+    size: 1
+`
+
+const testCRDManifest = `{
+  "apiVersion": "apiextensions.k8s.io/v1",
+  "kind": "CustomResourceDefinition",
+  "metadata": {"name": "foos.example.io"},
+  "spec": {
+    "group": "example.io",
+    "scope": "Namespaced",
+    "names": {"plural": "foos", "singular": "foo", "kind": "Foo"},
+    "versions": [{"name": "v1", "served": true, "storage": true}]
+  }
+}`
+
+var _ = Describe("test manifests", Label("manifests"), func() {
+	var f *e2e.Framework
+	var ctx context.Context
+
+	BeforeEach(func() {
+		f = fakeFramework()
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 20*time.Second)
+		DeferCleanup(cancel)
+	})
+
+	It("decode and order the documents", func() {
+		fsys := fstest.MapFS{
+			"manifests/b.yaml":     {Data: []byte(testManifest)},
+			"manifests/a/crd.json": {Data: []byte(testCRDManifest)},
+			"manifests/README.md":  {Data: []byte("# not a manifest")},
+		}
+		objs, e := f.DecodeManifests(e2e.ManifestFromFS(fsys, "manifests"))
+		Expect(e).NotTo(HaveOccurred())
+		Expect(objs).To(HaveLen(5))
+
+		Expect(objs[0]).To(BeAssignableToTypeOf(&apiextensions_v1.CustomResourceDefinition{}))
+		Expect(objs[1]).To(BeAssignableToTypeOf(&corev1.Namespace{}))
+		cm, ok := objs[2].(*corev1.ConfigMap)
+		Expect(ok).To(BeTrue())
+		Expect(cm.Data).To(HaveKeyWithValue("key", "value"))
+		Expect(objs[3]).To(BeAssignableToTypeOf(&corev1.ServiceAccount{}))
+		// the kind not registered in the scheme is unstructured
+		foo, ok := objs[4].(*unstructured.Unstructured)
+		Expect(ok).To(BeTrue())
+		Expect(foo.GetName()).To(Equal("manifest-foo"))
+
+		_, e = f.DecodeManifests(e2e.ManifestFromString("kind: ConfigMap\nmetadata:\n  name: cm\n"))
+		Expect(e).To(MatchError(e2e.ErrWrongInput))
+		_, e = f.DecodeManifests(e2e.ManifestFromString("apiVersion: v1\nkind: ConfigMap\n"))
+		Expect(e).To(MatchError(ContainSubstring("document 1")))
+		_, e = f.DecodeManifests(e2e.ManifestFromFile("/not/existed.yaml"))
+		Expect(e).To(HaveOccurred())
+	})
+
+	It("apply and delete the manifests", func() {
+		dir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "app.yaml"), []byte(testManifest), 0o600)).To(Succeed())
+
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+		mapper.Add(corev1.SchemeGroupVersion.WithKind("ServiceAccount"), meta.RESTScopeNamespace)
+		mapper.Add(corev1.SchemeGroupVersion.WithKind("Namespace"), meta.RESTScopeRoot)
+		f.KClient = fake.NewClientBuilder().WithScheme(f.KClient.Scheme()).WithRESTMapper(mapper).WithReturnManagedFields().Build()
+		f.Namespace = "default"
+		objs, e := f.ApplyManifests(ctx, e2e.ManifestFromDir(dir), nil)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(objs).To(HaveLen(4))
+
+		// the namespaced object without namespace goes to Framework.Namespace
+		cm := &corev1.ConfigMap{}
+		Expect(f.GetResourceContext(ctx, client.ObjectKey{Name: "manifest-cm", Namespace: "default"}, cm)).To(Succeed())
+		Expect(cm.Data).To(HaveKeyWithValue("key", "value"))
+		Expect(cm.ManagedFields).NotTo(BeEmpty())
+		Expect(cm.ManagedFields[0].Manager).To(Equal(e2e.DefaultFieldManager))
+		// the returned object is updated by the response
+		Expect(objs[1].GetManagedFields()).NotTo(BeEmpty())
+		Expect(f.TrackedObjects()).To(HaveLen(4))
+
+		// apply again with the changed manifest and the other namespace
+		changed := `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: manifest-cm
+data:
+  key: changed
+`
+		_, e = f.ApplyManifests(ctx, e2e.ManifestFromString(changed), &e2e.ManifestOptions{Namespace: "manifest-ns", FieldManager: "tester", Force: true, Wait: true})
+		Expect(e).NotTo(HaveOccurred())
+		Expect(f.GetResourceContext(ctx, client.ObjectKey{Name: "manifest-cm", Namespace: "manifest-ns"}, cm)).To(Succeed())
+		Expect(cm.Data).To(HaveKeyWithValue("key", "changed"))
+
+		Expect(f.DeleteManifests(ctx, e2e.ManifestFromDir(dir), &e2e.ManifestOptions{Wait: true})).To(Succeed())
+		e = f.GetResourceContext(ctx, client.ObjectKey{Name: "manifest-cm", Namespace: "default"}, &corev1.ConfigMap{})
+		Expect(api_errors.IsNotFound(e)).To(BeTrue())
+		e = f.GetResourceContext(ctx, client.ObjectKey{Name: "manifest-ns"}, &corev1.Namespace{})
+		Expect(api_errors.IsNotFound(e)).To(BeTrue())
+		Expect(f.TrackedObjects()).To(HaveLen(1))
+
+		// the deleted objects are ignored
+		Expect(f.DeleteManifests(ctx, e2e.ManifestFromDir(dir), nil)).To(Succeed())
+	})
+
+	It("apply only the fields of the manifest", func() {
+		svc := `
+apiVersion: v1
+kind: Service
+metadata:
+  name: manifest-svc
+spec:
+  ports:
+  - port: 80
+`
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(corev1.SchemeGroupVersion.WithKind("Service"), meta.RESTScopeNamespace)
+		var applied []byte
+		f.KClient = fake.NewClientBuilder().WithScheme(f.KClient.Scheme()).WithRESTMapper(mapper).WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				var err error
+				applied, err = patch.Data(obj)
+				if err != nil {
+					return err
+				}
+				return c.Patch(ctx, obj, patch, opts...)
+			},
+		}).Build()
+		f.Namespace = "default"
+		objs, e := f.ApplyManifests(ctx, e2e.ManifestFromString(svc), nil)
+		Expect(e).NotTo(HaveOccurred())
+
+		// the zero fields of the typed object are not applied
+		Expect(string(applied)).To(ContainSubstring(`"port":80`))
+		Expect(string(applied)).To(ContainSubstring(`"namespace":"default"`))
+		Expect(string(applied)).NotTo(ContainSubstring("targetPort"))
+		Expect(string(applied)).NotTo(ContainSubstring("selector"))
+		Expect(string(applied)).NotTo(ContainSubstring("status"))
+		s, ok := objs[0].(*corev1.Service)
+		Expect(ok).To(BeTrue())
+		Expect(s.Namespace).To(Equal("default"))
+		Expect(s.Spec.Ports[0].Port).To(BeEquivalentTo(80))
+	})
+})
@@ -3,7 +3,7 @@
 package framework
 
 import (
-	"fmt"
+	"context"
 
 	v1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -11,24 +11,20 @@ import (
 )
 
 func (f *Framework) GetMultusInstance(name, namespace string) (*v1.NetworkAttachmentDefinition, error) {
-	obj := &v1.NetworkAttachmentDefinition{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      name,
-			Namespace: namespace,
-		},
-	}
+	return f.GetMultusInstanceContext(context.Background(), name, namespace)
+}
 
-	key := client.ObjectKeyFromObject(obj)
-	nad := &v1.NetworkAttachmentDefinition{}
-	if err := f.GetResource(key, nad); err != nil {
-		return nil, err
-	}
-	return nad, nil
+func (f *Framework) GetMultusInstanceContext(ctx context.Context, name, namespace string) (*v1.NetworkAttachmentDefinition, error) {
+	return Get[*v1.NetworkAttachmentDefinition](ctx, f, name, namespace)
 }
 
 func (f *Framework) ListMultusInstances(opts ...client.ListOption) (*v1.NetworkAttachmentDefinitionList, error) {
+	return f.ListMultusInstancesContext(context.Background(), opts...)
+}
+
+func (f *Framework) ListMultusInstancesContext(ctx context.Context, opts ...client.ListOption) (*v1.NetworkAttachmentDefinitionList, error) {
 	nads := &v1.NetworkAttachmentDefinitionList{}
-	if err := f.ListResource(nads, opts...); err != nil {
+	if err := f.ListResourceContext(ctx, nads, opts...); err != nil {
 		return nil, err
 	}
 
@@ -36,15 +32,22 @@ func (f *Framework) ListMultusInstances(opts ...client.ListOption) (*v1.NetworkA
 }
 
 func (f *Framework) CreateMultusInstance(nad *v1.NetworkAttachmentDefinition, opts ...client.CreateOption) error {
-	exist, err := f.GetMultusInstance(nad.Name, nad.Namespace)
-	if err == nil && exist.DeletionTimestamp == nil {
-		return fmt.Errorf("failed to create %s/%s, instance has exists", nad.Namespace, nad.Name)
+	return f.CreateMultusInstanceContext(context.Background(), nad, opts...)
+}
+
+func (f *Framework) CreateMultusInstanceContext(ctx context.Context, nad *v1.NetworkAttachmentDefinition, opts ...client.CreateOption) error {
+	if nad == nil {
+		return ErrWrongInput
 	}
-	return f.CreateResource(nad, opts...)
+	return Create(ctx, f, nad, opts...)
 }
 
 func (f *Framework) DeleteMultusInstance(name, namespace string) error {
-	return f.DeleteResource(&v1.NetworkAttachmentDefinition{
+	return f.DeleteMultusInstanceContext(context.Background(), name, namespace)
+}
+
+func (f *Framework) DeleteMultusInstanceContext(ctx context.Context, name, namespace string) error {
+	return f.DeleteResourceContext(ctx, &v1.NetworkAttachmentDefinition{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      name,
 			Namespace: namespace,
@@ -7,14 +7,16 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/spidernet-io/e2eframework/tools"
 	corev1 "k8s.io/api/core/v1"
-	api_errors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func (f *Framework) CreateNamespace(nsName string, opts ...client.CreateOption) error {
+	return f.CreateNamespaceContext(context.Background(), nsName, opts...)
+}
+
+func (f *Framework) CreateNamespaceContext(ctx context.Context, nsName string, opts ...client.CreateOption) error {
 	ns := &corev1.Namespace{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: nsName,
@@ -28,44 +30,48 @@ func (f *Framework) CreateNamespace(nsName string, opts ...client.CreateOption)
 
 	key := client.ObjectKeyFromObject(ns)
 	existing := &corev1.Namespace{}
-	e := f.GetResource(key, existing)
+	e := f.GetResourceContext(ctx, key, existing)
 
 	if e == nil && existing.Status.Phase == corev1.NamespaceTerminating {
-		r := func() bool {
-			existing := &corev1.Namespace{}
-			e := f.GetResource(key, existing)
-			b := api_errors.IsNotFound(e)
-			if !b {
-				f.Log("waiting for a same namespace %v to finish deleting \n", nsName)
-				return false
-			}
-			return true
-		}
-		if !tools.Eventually(r, f.Config.ResourceDeleteTimeout, time.Second) {
-			return fmt.Errorf("time out to wait a deleting namespace")
+		f.Log("waiting for a same namespace %v to finish deleting \n", nsName)
+		waitCtx, cancel := context.WithTimeout(ctx, f.Config.ResourceDeleteTimeout)
+		defer cancel()
+		if err := waitGone(waitCtx, f, ns); err != nil {
+			return fmt.Errorf("time out to wait a deleting namespace: %w", err)
 		}
 	}
-	return f.CreateResource(ns, opts...)
+	return f.CreateResourceContext(ctx, ns, opts...)
 }
 
 func (f *Framework) CreateNamespaceUntilDefaultServiceAccountReady(nsName string, timeoutForSA time.Duration, opts ...client.CreateOption) error {
+	if timeoutForSA == 0 {
+		if nsName == "" {
+			return ErrWrongInput
+		}
+		return f.CreateNamespace(nsName, opts...)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeoutForSA)
+	defer cancel()
+	return f.CreateNamespaceUntilDefaultServiceAccountReadyContext(ctx, nsName, opts...)
+}
+
+// CreateNamespaceUntilDefaultServiceAccountReadyContext waits for the default ServiceAccount until ctx is done
+func (f *Framework) CreateNamespaceUntilDefaultServiceAccountReadyContext(ctx context.Context, nsName string, opts ...client.CreateOption) error {
 	if nsName == "" {
 		return ErrWrongInput
 	}
-	err := f.CreateNamespace(nsName, opts...)
+	err := f.CreateNamespaceContext(ctx, nsName, opts...)
 	if err != nil {
 		return err
 	}
-	if timeoutForSA != 0 {
-		err = f.WaitServiceAccountReady("default", nsName, timeoutForSA)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+	return f.WaitServiceAccountReadyContext(ctx, "default", nsName)
 }
 
 func (f *Framework) GetNamespace(nsName string) (*corev1.Namespace, error) {
+	return f.GetNamespaceContext(context.Background(), nsName)
+}
+
+func (f *Framework) GetNamespaceContext(ctx context.Context, nsName string) (*corev1.Namespace, error) {
 	if nsName == "" {
 		return nil, ErrWrongInput
 	}
@@ -80,7 +86,7 @@ func (f *Framework) GetNamespace(nsName string) (*corev1.Namespace, error) {
 	}
 	key := client.ObjectKeyFromObject(ns)
 	namespace := &corev1.Namespace{}
-	err := f.GetResource(key, namespace)
+	err := f.GetResourceContext(ctx, key, namespace)
 	if err != nil {
 		return nil, err
 	}
@@ -88,6 +94,10 @@ func (f *Framework) GetNamespace(nsName string) (*corev1.Namespace, error) {
 }
 
 func (f *Framework) DeleteNamespace(nsName string, opts ...client.DeleteOption) error {
+	return f.DeleteNamespaceContext(context.Background(), nsName, opts...)
+}
+
+func (f *Framework) DeleteNamespaceContext(ctx context.Context, nsName string, opts ...client.DeleteOption) error {
 
 	if nsName == "" {
 		return ErrWrongInput
@@ -98,28 +108,20 @@ func (f *Framework) DeleteNamespace(nsName string, opts ...client.DeleteOption)
 			Name: nsName,
 		},
 	}
-	return f.DeleteResource(ns, opts...)
+	return f.DeleteResourceContext(ctx, ns, opts...)
 }
 
 func (f *Framework) DeleteNamespaceUntilFinish(nsName string, ctx context.Context, opts ...client.DeleteOption) error {
+	return f.DeleteNamespaceUntilFinishContext(ctx, nsName, opts...)
+}
+
+func (f *Framework) DeleteNamespaceUntilFinishContext(ctx context.Context, nsName string, opts ...client.DeleteOption) error {
 	if nsName == "" {
 		return ErrWrongInput
 	}
-	err := f.DeleteNamespace(nsName, opts...)
+	err := f.DeleteNamespaceContext(ctx, nsName, opts...)
 	if err != nil {
 		return err
 	}
-	for {
-		select {
-		default:
-			namespace, _ := f.GetNamespace(nsName)
-			if namespace == nil {
-				return nil
-			}
-			time.Sleep(time.Second)
-		case <-ctx.Done():
-			return ErrTimeOut
-		}
-	}
-
+	return waitGone(ctx, f, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: nsName}})
 }
@@ -4,7 +4,6 @@ package framework
 
 import (
 	"context"
-	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -12,9 +11,12 @@ import (
 )
 
 func (f *Framework) GetNode(nodeName string) (*corev1.Node, error) {
-	ctx := context.TODO()
+	return f.GetNodeContext(context.Background(), nodeName)
+}
+
+func (f *Framework) GetNodeContext(ctx context.Context, nodeName string) (*corev1.Node, error) {
 	node := &corev1.Node{}
-	err1 := f.KClient.Get(ctx, types.NamespacedName{Name: nodeName}, node)
+	err1 := f.GetResourceContext(ctx, types.NamespacedName{Name: nodeName}, node)
 	if err1 != nil {
 		return nil, err1
 	}
@@ -22,32 +24,27 @@ func (f *Framework) GetNode(nodeName string) (*corev1.Node, error) {
 }
 
 func (f *Framework) WaitClusterNodeReady(ctx context.Context) (bool, error) {
-	for {
-		select {
-		default:
-			nodeReadyNum := 0
-			nodes, e := f.GetNodeList()
-			if e != nil {
-				return false, e
-			}
-			for _, node := range nodes.Items {
-				if f.CheckNodeStatus(&node, true) {
-					nodeReadyNum++
-				}
+	_, err := WaitList(ctx, f, func(nodes *corev1.NodeList) (bool, error) {
+		for _, node := range nodes.Items {
+			if !f.CheckNodeStatus(&node, true) {
+				return false, nil
 			}
-			if nodeReadyNum == len(nodes.Items) {
-				return true, nil
-			}
-			time.Sleep(time.Second)
-		case <-ctx.Done():
-			return false, ErrTimeOut
 		}
+		return true, nil
+	})
+	if err != nil {
+		return false, err
 	}
+	return true, nil
 }
 
 func (f *Framework) GetNodeList(opts ...client.ListOption) (*corev1.NodeList, error) {
+	return f.GetNodeListContext(context.Background(), opts...)
+}
+
+func (f *Framework) GetNodeListContext(ctx context.Context, opts ...client.ListOption) (*corev1.NodeList, error) {
 	nodes := &corev1.NodeList{}
-	e := f.ListResource(nodes, opts...)
+	e := f.ListResourceContext(ctx, nodes, opts...)
 	if e != nil {
 		return nil, e
 	}
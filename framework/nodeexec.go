@@ -0,0 +1,305 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	api_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+// the node level command runs the same way on kind, the VMs and the bare metal, like:
+//   node, err := f.GetNode("worker")
+//   r, err := f.ExecOnNode(ctx, node, []string{"ip", "-6", "route", "show"})
+// the access of the node is set by E2E_NODE_ACCESS, or f.NodeExecutor for the unitest
+
+const (
+	// E2E_NODE_ACCESS is one of NodeAccess*, empty means kind for the node in KindNodeList, then vagrant with VagrantDir
+	E2E_NODE_ACCESS = "E2E_NODE_ACCESS"
+	// E2E_SSH_USER and E2E_SSH_KEY_FILE are for NodeAccessSSH, empty means the ssh config and agent
+	E2E_SSH_USER     = "E2E_SSH_USER"
+	E2E_SSH_KEY_FILE = "E2E_SSH_KEY_FILE"
+	// E2E_SSH_KNOWN_HOSTS_FILE is the known_hosts of NodeAccessSSH, empty means the known_hosts of the user
+	E2E_SSH_KNOWN_HOSTS_FILE = "E2E_SSH_KNOWN_HOSTS_FILE"
+	// E2E_SSH_INSECURE_HOST_KEY skips the host key checking of NodeAccessSSH, only for the throwaway nodes
+	E2E_SSH_INSECURE_HOST_KEY = "E2E_SSH_INSECURE_HOST_KEY"
+
+	NodeAccessKind     = "kind"
+	NodeAccessSSH      = "ssh"
+	NodeAccessVagrant  = "vagrant"
+	NodeAccessDebugPod = "debugpod"
+
+	DefaultDebugPodImage = "busybox"
+)
+
+var nodeAccesses = []string{NodeAccessKind, NodeAccessSSH, NodeAccessVagrant, NodeAccessDebugPod}
+
+// NodeExecutor runs command on the node, every argument is passed as it is.
+// The ExecResult is returned even with the *ExecExitError
+type NodeExecutor interface {
+	ExecOnNode(ctx context.Context, node *corev1.Node, command []string) (*ExecResult, error)
+}
+
+// ExecOnNode runs command on node by f.NodeExecutor, or the executor of Config.NodeAccess
+func (f *Framework) ExecOnNode(ctx context.Context, node *corev1.Node, command []string) (*ExecResult, error) {
+	if node == nil || node.Name == "" || len(command) == 0 {
+		return nil, ErrWrongInput
+	}
+	e, err := f.nodeExecutorOf(node)
+	if err != nil {
+		return nil, err
+	}
+	f.Log("exec on node %s: %q \n", node.Name, command)
+	return e.ExecOnNode(ctx, node, command)
+}
+
+func (f *Framework) nodeExecutorOf(node *corev1.Node) (NodeExecutor, error) {
+	if f.NodeExecutor != nil {
+		return f.NodeExecutor, nil
+	}
+
+	access := f.Config.NodeAccess
+	if access == "" {
+		switch {
+		case slices.Contains(f.Info.KindNodeList, node.Name):
+			access = NodeAccessKind
+		case f.Info.VagrantDir != "":
+			access = NodeAccessVagrant
+		default:
+			return nil, fmt.Errorf("%w: no access to node %s, set it by ENV %v or the config file", ErrWrongInput, node.Name, E2E_NODE_ACCESS)
+		}
+	}
+
+	switch access {
+	case NodeAccessKind:
+		r, err := f.ContainerRuntime()
+		if err != nil {
+			return nil, err
+		}
+		return &KindNodeExecutor{Runtime: r}, nil
+	case NodeAccessSSH:
+		return &SSHNodeExecutor{
+			User:                 f.Config.SSHUser,
+			IdentityFile:         f.Config.SSHKeyFile,
+			KnownHostsFile:       f.Config.SSHKnownHostsFile,
+			InsecureSkipHostKeys: f.Config.SSHInsecureHostKey,
+		}, nil
+	case NodeAccessVagrant:
+		return f.Vagrant()
+	case NodeAccessDebugPod:
+		return NewDebugPodNodeExecutor(f, "", ""), nil
+	}
+	return nil, fmt.Errorf("%w: unknown node access %q", ErrWrongInput, access)
+}
+
+func isNodeAccess(access string) bool {
+	return slices.Contains(nodeAccesses, access)
+}
+
+// ------------- kind
+
+// KindNodeExecutor runs the command in the node container of the same name
+type KindNodeExecutor struct {
+	Runtime ContainerRuntime
+}
+
+func (k *KindNodeExecutor) ExecOnNode(ctx context.Context, node *corev1.Node, command []string) (*ExecResult, error) {
+	if node == nil || k.Runtime == nil {
+		return nil, ErrWrongInput
+	}
+	stdout, err := k.Runtime.Exec(ctx, node.Name, command)
+	r := &ExecResult{Stdout: stdout}
+	var exitErr *ExecExitError
+	if errors.As(err, &exitErr) {
+		r.Stderr = exitErr.Stderr
+	}
+	return r, err
+}
+
+// ------------- ssh
+
+// SSHNodeExecutor runs the command by the ssh binary, with the key or the ssh agent.
+// The host key is checked strictly by the known_hosts, unless InsecureSkipHostKeys
+type SSHNodeExecutor struct {
+	// empty means the user of the ssh config
+	User string
+	// 0 means the port of the ssh config
+	Port int
+	// empty means the ssh agent or the default keys
+	IdentityFile string
+	// empty means the known_hosts of the user
+	KnownHostsFile string
+	// InsecureSkipHostKeys accepts any host key, for the test of the throwaway nodes
+	InsecureSkipHostKeys bool
+	// the extra options, like []string{"-o", "ProxyJump=bastion"}
+	Options []string
+	// "ssh" in PATH by default
+	Binary string
+	// Address returns the address of the node, nil means the first InternalIP, then ExternalIP
+	Address func(node *corev1.Node) (string, error)
+}
+
+func (s *SSHNodeExecutor) ExecOnNode(ctx context.Context, node *corev1.Node, command []string) (*ExecResult, error) {
+	if node == nil {
+		return nil, ErrWrongInput
+	}
+	address := nodeAddress
+	if s.Address != nil {
+		address = s.Address
+	}
+	host, err := address(node)
+	if err != nil {
+		return nil, err
+	}
+	if s.User != "" {
+		host = s.User + "@" + host
+	}
+
+	args := []string{"-o", "BatchMode=yes", "-o", "LogLevel=ERROR"}
+	switch {
+	case s.InsecureSkipHostKeys:
+		args = append(args, "-o", "StrictHostKeyChecking=no", "-o", "UserKnownHostsFile=/dev/null")
+	case s.KnownHostsFile != "":
+		args = append(args, "-o", "StrictHostKeyChecking=yes", "-o", "UserKnownHostsFile="+s.KnownHostsFile)
+	default:
+		args = append(args, "-o", "StrictHostKeyChecking=yes")
+	}
+	if s.Port != 0 {
+		args = append(args, "-p", fmt.Sprint(s.Port))
+	}
+	if s.IdentityFile != "" {
+		args = append(args, "-i", s.IdentityFile)
+	}
+	args = append(args, s.Options...)
+	args = append(args, host)
+
+	binary := s.Binary
+	if binary == "" {
+		binary = "ssh"
+	}
+	return runSSH(ctx, binary, args, command)
+}
+
+func nodeAddress(node *corev1.Node) (string, error) {
+	for _, t := range []corev1.NodeAddressType{corev1.NodeInternalIP, corev1.NodeExternalIP} {
+		for _, a := range node.Status.Addresses {
+			if a.Type == t && a.Address != "" {
+				return a.Address, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%w: node %s has no InternalIP or ExternalIP", ErrWrongInput, node.Name)
+}
+
+// runSSH runs the command line of command by ssh with args
+func runSSH(ctx context.Context, binary string, args []string, command []string) (*ExecResult, error) {
+	if len(command) == 0 {
+		return nil, ErrWrongInput
+	}
+	args = append(append([]string{}, args...), "--", shellQuote(command))
+	stdout, stderr, err := runBinary(ctx, "", "ssh", binary, args...)
+	return &ExecResult{Stdout: stdout, Stderr: stderr}, err
+}
+
+// shellQuote joins argv to a command line of the posix shell, every argument is single quoted
+func shellQuote(argv []string) string {
+	quoted := make([]string, 0, len(argv))
+	for _, a := range argv {
+		quoted = append(quoted, "'"+strings.ReplaceAll(a, "'", `'\''`)+"'")
+	}
+	return strings.Join(quoted, " ")
+}
+
+// ------------- debug pod
+
+// DebugPodNodeExecutor runs the command in the privileged pod on the node, which chroots to the root of the node.
+// The pod is created for the first command on the node, and deleted by Cleanup
+type DebugPodNodeExecutor struct {
+	f         *Framework
+	Image     string
+	Namespace string
+}
+
+// NewDebugPodNodeExecutor returns the executor, empty image means DefaultDebugPodImage,
+// and empty namespace means Framework.Namespace, then default
+func NewDebugPodNodeExecutor(f *Framework, image, namespace string) *DebugPodNodeExecutor {
+	if image == "" {
+		image = DefaultDebugPodImage
+	}
+	if namespace == "" {
+		namespace = f.Namespace
+	}
+	if namespace == "" {
+		namespace = corev1.NamespaceDefault
+	}
+	return &DebugPodNodeExecutor{f: f, Image: image, Namespace: namespace}
+}
+
+// PodName returns the name of the debug pod on the node
+func (d *DebugPodNodeExecutor) PodName(node string) string {
+	return "e2e-node-debug-" + node
+}
+
+func (d *DebugPodNodeExecutor) ExecOnNode(ctx context.Context, node *corev1.Node, command []string) (*ExecResult, error) {
+	if node == nil || len(command) == 0 {
+		return nil, ErrWrongInput
+	}
+	name := d.PodName(node.Name)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: d.Namespace},
+		Spec: corev1.PodSpec{
+			NodeName:      node.Name,
+			HostNetwork:   true,
+			HostPID:       true,
+			HostIPC:       true,
+			RestartPolicy: corev1.RestartPolicyNever,
+			Tolerations:   []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+			Containers: []corev1.Container{{
+				Name:            "debug",
+				Image:           d.Image,
+				Command:         []string{"sleep", "86400"},
+				SecurityContext: &corev1.SecurityContext{Privileged: ptr.To(true)},
+				VolumeMounts:    []corev1.VolumeMount{{Name: "host", MountPath: "/host"}},
+			}},
+			Volumes: []corev1.Volume{{
+				Name:         "host",
+				VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/"}},
+			}},
+		},
+	}
+	if err := d.createPod(ctx, pod); err != nil {
+		return nil, err
+	}
+	if _, err := d.f.WaitPodStartedContext(ctx, name, d.Namespace); err != nil {
+		return nil, err
+	}
+	return d.f.ExecInPod(ctx, name, d.Namespace, append([]string{"chroot", "/host"}, command...), nil)
+}
+
+// createPod creates the debug pod, the existing one is reused unless it is finished, like after the node restarts,
+// then it is deleted and created again
+func (d *DebugPodNodeExecutor) createPod(ctx context.Context, pod *corev1.Pod) error {
+	err := d.f.CreateResourceContext(ctx, pod.DeepCopy())
+	if !api_errors.IsAlreadyExists(err) {
+		return err
+	}
+	existing, err := d.f.GetPodContext(ctx, pod.Name, pod.Namespace)
+	if err != nil {
+		return err
+	}
+	if existing.Status.Phase != corev1.PodSucceeded && existing.Status.Phase != corev1.PodFailed {
+		return nil
+	}
+	d.f.Log("recreate the debug pod %s/%s of phase %s \n", pod.Namespace, pod.Name, existing.Status.Phase)
+	if err := d.f.DeletePodUntilFinishContext(ctx, pod.Name, pod.Namespace); err != nil && !api_errors.IsNotFound(err) {
+		return err
+	}
+	return d.f.CreateResourceContext(ctx, pod.DeepCopy())
+}
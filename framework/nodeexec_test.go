@@ -0,0 +1,166 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	e2e "github.com/spidernet-io/e2eframework/framework"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// the stub ssh prints the argv one per line, and fails for the command false
+const fakeNodeSSHScript = `#!/bin/sh
+printf '%s\n' "$@"
+case "$*" in
+*"'false'"*) echo "failed" >&2; exit 2 ;;
+esac
+`
+
+func exampleNode(name string, addresses ...corev1.NodeAddress) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     corev1.NodeStatus{Addresses: addresses},
+	}
+}
+
+var _ = Describe("test node executor", Label("nodeexec"), func() {
+	var f *e2e.Framework
+	var ctx context.Context
+
+	BeforeEach(func() {
+		f = fakeFramework()
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 20*time.Second)
+		DeferCleanup(cancel)
+	})
+
+	It("run on the kind node by default", func() {
+		runtime := e2e.NewFakeContainerRuntime("worker")
+		runtime.ExecFunc = func(container string, command []string) ([]byte, error) {
+			if command[0] == "false" {
+				return nil, &e2e.ExecExitError{Command: command, Code: 1, Stderr: []byte("failed")}
+			}
+			return []byte(strings.Join(command, " ")), nil
+		}
+		f.Runtime = runtime
+
+		r, e := f.ExecOnNode(ctx, exampleNode("worker"), []string{"ip", "link"})
+		Expect(e).NotTo(HaveOccurred())
+		Expect(string(r.Stdout)).To(Equal("ip link"))
+
+		r, e = f.ExecOnNode(ctx, exampleNode("worker"), []string{"false"})
+		var exitErr *e2e.ExecExitError
+		Expect(errors.As(e, &exitErr)).To(BeTrue())
+		Expect(string(r.Stderr)).To(Equal("failed"))
+
+		// the node out of KindNodeList has no access
+		_, e = f.ExecOnNode(ctx, exampleNode("vm-1"), []string{"ip", "link"})
+		Expect(e).To(MatchError(e2e.ErrWrongInput))
+		Expect(e.Error()).To(ContainSubstring(e2e.E2E_NODE_ACCESS))
+	})
+
+	It("run on the node over ssh", func() {
+		dir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "ssh"), []byte(fakeNodeSSHScript), 0o700)).To(Succeed())
+		f.NodeExecutor = &e2e.SSHNodeExecutor{
+			User:         "root",
+			Port:         2222,
+			IdentityFile: "/tmp/id_rsa",
+			Binary:       filepath.Join(dir, "ssh"),
+		}
+
+		node := exampleNode("vm-1",
+			corev1.NodeAddress{Type: corev1.NodeHostName, Address: "vm-1"},
+			corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: "fd00::10"},
+		)
+		r, e := f.ExecOnNode(ctx, node, []string{"sh", "-c", "echo 'a b'"})
+		Expect(e).NotTo(HaveOccurred())
+		args := strings.Split(strings.TrimSpace(string(r.Stdout)), "\n")
+		Expect(args).To(ContainElements("-p", "2222", "-i", "/tmp/id_rsa", "BatchMode=yes", "StrictHostKeyChecking=yes"))
+		Expect(args).NotTo(ContainElement(HavePrefix("UserKnownHostsFile")))
+		Expect(args[len(args)-3:]).To(Equal([]string{"root@fd00::10", "--", `'sh' '-c' 'echo '\''a b'\'''`}))
+
+		r, e = f.ExecOnNode(ctx, node, []string{"false"})
+		var exitErr *e2e.ExecExitError
+		Expect(errors.As(e, &exitErr)).To(BeTrue())
+		Expect(exitErr.Code).To(Equal(2))
+		Expect(string(r.Stderr)).To(Equal("failed\n"))
+
+		_, e = f.ExecOnNode(ctx, exampleNode("vm-2"), []string{"true"})
+		Expect(e).To(MatchError(e2e.ErrWrongInput))
+
+		// the known_hosts of the cluster, or no host key checking for the throwaway nodes
+		executor := f.NodeExecutor.(*e2e.SSHNodeExecutor)
+		executor.KnownHostsFile = "/tmp/known_hosts"
+		r, e = f.ExecOnNode(ctx, node, []string{"true"})
+		Expect(e).NotTo(HaveOccurred())
+		Expect(strings.Split(string(r.Stdout), "\n")).To(ContainElements("StrictHostKeyChecking=yes", "UserKnownHostsFile=/tmp/known_hosts"))
+		executor.InsecureSkipHostKeys = true
+		r, e = f.ExecOnNode(ctx, node, []string{"true"})
+		Expect(e).NotTo(HaveOccurred())
+		Expect(strings.Split(string(r.Stdout), "\n")).To(ContainElements("StrictHostKeyChecking=no", "UserKnownHostsFile=/dev/null"))
+	})
+
+	It("run on the node in the debug pod", func() {
+		f.Config.NodeAccess = e2e.NodeAccessDebugPod
+		f.Namespace = "debug"
+		executor := e2e.NewDebugPodNodeExecutor(f, "", "")
+
+		go func() {
+			defer GinkgoRecover()
+			pod := &corev1.Pod{}
+			Eventually(func() error {
+				return f.GetResourceContext(ctx, client.ObjectKey{Name: executor.PodName("vm-1"), Namespace: "debug"}, pod)
+			}).WithContext(ctx).Should(Succeed())
+			// after the wait is watching
+			time.Sleep(time.Second)
+			pod.Status.Phase = corev1.PodRunning
+			Expect(f.UpdateResourceStatusContext(ctx, pod)).To(Succeed())
+		}()
+
+		// the fake client has no clientset for the exec
+		_, e := f.ExecOnNode(ctx, exampleNode("vm-1"), []string{"ip", "link"})
+		Expect(e).To(MatchError(e2e.ErrNoClientSet))
+
+		pod := &corev1.Pod{}
+		Expect(f.GetResourceContext(ctx, client.ObjectKey{Name: executor.PodName("vm-1"), Namespace: "debug"}, pod)).To(Succeed())
+		Expect(pod.Spec.NodeName).To(Equal("vm-1"))
+		Expect(pod.Spec.HostNetwork).To(BeTrue())
+		Expect(pod.Spec.Containers[0].Image).To(Equal(e2e.DefaultDebugPodImage))
+		Expect(*pod.Spec.Containers[0].SecurityContext.Privileged).To(BeTrue())
+		Expect(f.TrackedObjects()).To(HaveLen(1))
+
+		// the existing pod is reused
+		_, e = f.ExecOnNode(ctx, exampleNode("vm-1"), []string{"ip", "link"})
+		Expect(e).To(MatchError(e2e.ErrNoClientSet))
+
+		// the finished pod is created again, like after the node restarts
+		pod.Status.Phase = corev1.PodFailed
+		Expect(f.UpdateResourceStatusContext(ctx, pod)).To(Succeed())
+		go func() {
+			defer GinkgoRecover()
+			pod := &corev1.Pod{}
+			Eventually(func() bool {
+				return f.GetResourceContext(ctx, client.ObjectKey{Name: executor.PodName("vm-1"), Namespace: "debug"}, pod) == nil && pod.Status.Phase == ""
+			}).WithContext(ctx).Should(BeTrue())
+			time.Sleep(time.Second)
+			pod.Status.Phase = corev1.PodRunning
+			Expect(f.UpdateResourceStatusContext(ctx, pod)).To(Succeed())
+		}()
+		_, e = f.ExecOnNode(ctx, exampleNode("vm-1"), []string{"ip", "link"})
+		Expect(e).To(MatchError(e2e.ErrNoClientSet))
+		Expect(f.GetResourceContext(ctx, client.ObjectKey{Name: executor.PodName("vm-1"), Namespace: "debug"}, pod)).To(Succeed())
+		Expect(pod.Status.Phase).To(Equal(corev1.PodRunning))
+	})
+})
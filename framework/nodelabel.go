@@ -0,0 +1,142 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// change the labels and taints of the node for the spec, they are restored when the spec finishes, like:
+//   err := f.AddNodeLabels(ctx, "worker", map[string]string{"e2e-zone": "a"})
+//   err := f.AddNodeTaints(ctx, "worker", corev1.Taint{Key: "e2e", Effect: corev1.TaintEffectNoSchedule})
+// and in AfterSuite:
+//   Expect(f.CheckLeftoverNodeLabels(ctx)).To(Succeed())
+
+// NodeOwnedLabelsAnnotation lists the label keys added by the framework, like "a,b", the keys are removed by RestoreNodeState
+const NodeOwnedLabelsAnnotation = "e2eframework.spidernet.io/owned-labels"
+
+// AddNodeLabels adds or overwrites the labels of the node, after saving the node state
+func (f *Framework) AddNodeLabels(ctx context.Context, name string, labels map[string]string) error {
+	if len(labels) == 0 {
+		return ErrWrongInput
+	}
+	return f.patchNode(ctx, name, func(node *corev1.Node) {
+		if node.Labels == nil {
+			node.Labels = map[string]string{}
+		}
+		maps.Copy(node.Labels, labels)
+
+		owned := ownedNodeLabels(node)
+		for k := range labels {
+			if !slices.Contains(owned, k) {
+				owned = append(owned, k)
+			}
+		}
+		slices.Sort(owned)
+		if node.Annotations == nil {
+			node.Annotations = map[string]string{}
+		}
+		node.Annotations[NodeOwnedLabelsAnnotation] = strings.Join(owned, ",")
+	})
+}
+
+// RemoveNodeLabels removes the labels of the node, after saving the node state
+func (f *Framework) RemoveNodeLabels(ctx context.Context, name string, keys ...string) error {
+	if len(keys) == 0 {
+		return ErrWrongInput
+	}
+	return f.patchNode(ctx, name, func(node *corev1.Node) {
+		for _, k := range keys {
+			delete(node.Labels, k)
+		}
+	})
+}
+
+// AddNodeTaints adds the taints to the node, after saving the node state.
+// The taint of the same key and effect is overwritten
+func (f *Framework) AddNodeTaints(ctx context.Context, name string, taints ...corev1.Taint) error {
+	if len(taints) == 0 {
+		return ErrWrongInput
+	}
+	return f.patchNode(ctx, name, func(node *corev1.Node) {
+		for _, t := range taints {
+			node.Spec.Taints = slices.DeleteFunc(node.Spec.Taints, func(old corev1.Taint) bool { return old.MatchTaint(&t) })
+			node.Spec.Taints = append(node.Spec.Taints, t)
+		}
+	})
+}
+
+// RemoveNodeTaints removes the taints of the same key and effect from the node, after saving the node state
+func (f *Framework) RemoveNodeTaints(ctx context.Context, name string, taints ...corev1.Taint) error {
+	if len(taints) == 0 {
+		return ErrWrongInput
+	}
+	return f.patchNode(ctx, name, func(node *corev1.Node) {
+		for _, t := range taints {
+			node.Spec.Taints = slices.DeleteFunc(node.Spec.Taints, func(old corev1.Taint) bool { return old.MatchTaint(&t) })
+		}
+	})
+}
+
+// patchNode saves the node state, then patches the node by mutate with the resourceVersion, and retries on the conflict.
+// The changes are recorded for RestoreNodeState
+func (f *Framework) patchNode(ctx context.Context, name string, mutate func(node *corev1.Node)) error {
+	if name == "" {
+		return ErrWrongInput
+	}
+	if err := f.SaveNodeState(ctx, name); err != nil {
+		return err
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		node, err := f.GetNodeContext(ctx, name)
+		if err != nil {
+			return err
+		}
+		old := node.DeepCopy()
+		patch := client.MergeFromWithOptions(old, client.MergeFromWithOptimisticLock{})
+		mutate(node)
+		f.recordNodeChanges(old, node)
+		return f.PatchResourceContext(ctx, node, patch)
+	})
+}
+
+func ownedNodeLabels(node *corev1.Node) []string {
+	v := node.Annotations[NodeOwnedLabelsAnnotation]
+	if v == "" {
+		return nil
+	}
+	return strings.Split(v, ",")
+}
+
+// CheckLeftoverNodeLabels fails with ErrLeftover for the nodes still carrying the labels added by AddNodeLabels,
+// which are not restored for the crash or the missing RestoreNodeState
+func (f *Framework) CheckLeftoverNodeLabels(ctx context.Context) error {
+	nodes, err := f.GetNodeListContext(ctx)
+	if err != nil {
+		return err
+	}
+	leftover := []string{}
+	for _, node := range nodes.Items {
+		keys := []string{}
+		for _, k := range ownedNodeLabels(&node) {
+			if _, ok := node.Labels[k]; ok {
+				keys = append(keys, k)
+			}
+		}
+		if len(keys) > 0 {
+			leftover = append(leftover, fmt.Sprintf("%s: %s", node.Name, strings.Join(keys, ",")))
+		}
+	}
+	if len(leftover) > 0 {
+		return fmt.Errorf("%w: node labels of the framework, %s", ErrLeftover, strings.Join(leftover, "; "))
+	}
+	return nil
+}
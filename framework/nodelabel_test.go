@@ -0,0 +1,89 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework_test
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	e2e "github.com/spidernet-io/e2eframework/framework"
+	corev1 "k8s.io/api/core/v1"
+	api_errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+var _ = Describe("test node label", Label("nodelabel"), func() {
+	var f *e2e.Framework
+	var t *cleanupT
+	var ctx context.Context
+	var conflicts atomic.Int32
+
+	BeforeEach(func() {
+		fakeEnv("/tmp/nokubeconfigfile")
+		// the patch conflicts with the change by others
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				if conflicts.Add(-1) >= 0 {
+					return api_errors.NewConflict(schema.GroupResource{Resource: "nodes"}, obj.GetName(), nil)
+				}
+				return c.Patch(ctx, obj, patch, opts...)
+			},
+		}).Build()
+		t = &cleanupT{}
+		var e error
+		f, e = e2e.NewFramework(t, nil, c)
+		Expect(e).NotTo(HaveOccurred())
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 20*time.Second)
+		DeferCleanup(cancel)
+
+		node := exampleNode("worker")
+		node.Labels = map[string]string{"zone": "a", "disk": "ssd"}
+		node.Spec.Taints = []corev1.Taint{{Key: "dedicated", Value: "infra", Effect: corev1.TaintEffectNoSchedule}}
+		Expect(f.CreateResourceContext(ctx, node)).To(Succeed())
+	})
+
+	It("change the labels and taints, then restore them", func() {
+		conflicts.Store(2)
+		Expect(f.AddNodeLabels(ctx, "worker", map[string]string{"zone": "b", "e2e-pool": "v4"})).To(Succeed())
+		Expect(conflicts.Load()).To(BeNumerically("<", 0))
+		Expect(f.RemoveNodeLabels(ctx, "worker", "disk")).To(Succeed())
+		Expect(f.AddNodeTaints(ctx, "worker",
+			corev1.Taint{Key: "dedicated", Value: "e2e", Effect: corev1.TaintEffectNoSchedule},
+			corev1.Taint{Key: "e2e", Effect: corev1.TaintEffectNoExecute},
+		)).To(Succeed())
+		Expect(f.RemoveNodeTaints(ctx, "worker", corev1.Taint{Key: "e2e", Effect: corev1.TaintEffectNoExecute})).To(Succeed())
+
+		node, e := f.GetNodeContext(ctx, "worker")
+		Expect(e).NotTo(HaveOccurred())
+		Expect(node.Labels).To(Equal(map[string]string{"zone": "b", "e2e-pool": "v4"}))
+		Expect(node.Annotations).To(HaveKeyWithValue(e2e.NodeOwnedLabelsAnnotation, "e2e-pool,zone"))
+		Expect(node.Spec.Taints).To(Equal([]corev1.Taint{{Key: "dedicated", Value: "e2e", Effect: corev1.TaintEffectNoSchedule}}))
+
+		e = f.CheckLeftoverNodeLabels(ctx)
+		Expect(e).To(MatchError(e2e.ErrLeftover))
+		Expect(e.Error()).To(ContainSubstring("worker: e2e-pool,zone"))
+
+		// the spec finishes
+		t.runCleanups()
+		Expect(t.errs).To(BeEmpty())
+		node, e = f.GetNodeContext(ctx, "worker")
+		Expect(e).NotTo(HaveOccurred())
+		Expect(node.Labels).To(Equal(map[string]string{"zone": "a", "disk": "ssd"}))
+		Expect(node.Annotations).NotTo(HaveKey(e2e.NodeOwnedLabelsAnnotation))
+		Expect(node.Spec.Taints).To(Equal([]corev1.Taint{{Key: "dedicated", Value: "infra", Effect: corev1.TaintEffectNoSchedule}}))
+		Expect(f.CheckLeftoverNodeLabels(ctx)).To(Succeed())
+
+		Expect(f.AddNodeLabels(ctx, "worker", nil)).To(MatchError(e2e.ErrWrongInput))
+		Expect(f.RemoveNodeTaints(ctx, "worker")).To(MatchError(e2e.ErrWrongInput))
+	})
+})
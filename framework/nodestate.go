@@ -0,0 +1,251 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// the node changed by the spec is restored when the spec finishes, like:
+//   err := f.CordonNode(ctx, "worker")
+//   err := f.AddNodeLabels(ctx, "worker", map[string]string{"e2e-zone": "a"})
+// the state is the unschedulable, the labels and the taints, which are restored if f.t is CleanupT,
+// or by f.RestoreNodeState(ctx). Only the label keys and taints changed by the framework are restored,
+// so the changes by others are kept
+
+// the taints managed by the node lifecycle controller, which are never saved or restored
+const nodeLifecycleTaintPrefix = "node.kubernetes.io/"
+
+// NodeState is the saved state of the node
+type NodeState struct {
+	Name          string
+	Unschedulable bool
+	Labels        map[string]string
+	Taints        []corev1.Taint
+}
+
+type nodeStateTracker struct {
+	lock    sync.Mutex
+	states  map[string]*NodeState
+	changes map[string]*nodeChanges
+}
+
+// nodeChanges is what the framework changes on the saved node
+type nodeChanges struct {
+	unschedulable bool
+	labels        []string
+	// the key and effect of the taints
+	taints []corev1.Taint
+}
+
+func (c *nodeChanges) empty() bool {
+	return !c.unschedulable && len(c.labels) == 0 && len(c.taints) == 0
+}
+
+func (c *nodeChanges) changedTaint(t corev1.Taint) bool {
+	return slices.ContainsFunc(c.taints, func(changed corev1.Taint) bool { return changed.MatchTaint(&t) })
+}
+
+// recordNodeChanges records the label keys and taints of old changed to node, and the unschedulable
+func (f *Framework) recordNodeChanges(old, node *corev1.Node) {
+	f.nodeStates.lock.Lock()
+	defer f.nodeStates.lock.Unlock()
+	if f.nodeStates.changes == nil {
+		f.nodeStates.changes = map[string]*nodeChanges{}
+	}
+	c, ok := f.nodeStates.changes[node.Name]
+	if !ok {
+		c = &nodeChanges{}
+		f.nodeStates.changes[node.Name] = c
+	}
+
+	if old.Spec.Unschedulable != node.Spec.Unschedulable {
+		c.unschedulable = true
+	}
+	for _, labels := range []map[string]string{old.Labels, node.Labels} {
+		for k := range labels {
+			v1, ok1 := old.Labels[k]
+			v2, ok2 := node.Labels[k]
+			if (v1 != v2 || ok1 != ok2) && !slices.Contains(c.labels, k) {
+				c.labels = append(c.labels, k)
+			}
+		}
+	}
+	for _, pair := range [][2][]corev1.Taint{{old.Spec.Taints, node.Spec.Taints}, {node.Spec.Taints, old.Spec.Taints}} {
+		for _, t := range userTaints(pair[0]) {
+			if !slices.ContainsFunc(pair[1], func(o corev1.Taint) bool { return taintEqual(o, t) }) && !c.changedTaint(t) {
+				c.taints = append(c.taints, corev1.Taint{Key: t.Key, Effect: t.Effect})
+			}
+		}
+	}
+}
+
+// SaveNodeState saves the state of the nodes, the saved node is skipped,
+// so the state is the one before the first change of the spec
+func (f *Framework) SaveNodeState(ctx context.Context, names ...string) error {
+	if len(names) == 0 || f.nodeStates == nil {
+		return ErrWrongInput
+	}
+	for _, name := range names {
+		f.nodeStates.lock.Lock()
+		_, ok := f.nodeStates.states[name]
+		f.nodeStates.lock.Unlock()
+		if ok {
+			continue
+		}
+
+		node, err := f.GetNodeContext(ctx, name)
+		if err != nil {
+			return err
+		}
+		state := &NodeState{
+			Name:          name,
+			Unschedulable: node.Spec.Unschedulable,
+			Labels:        maps.Clone(node.Labels),
+			Taints:        userTaints(node.Spec.Taints),
+		}
+
+		f.nodeStates.lock.Lock()
+		if _, ok := f.nodeStates.states[name]; ok {
+			f.nodeStates.lock.Unlock()
+			continue
+		}
+		if f.nodeStates.states == nil {
+			f.nodeStates.states = map[string]*NodeState{}
+		}
+		f.nodeStates.states[name] = state
+		f.nodeStates.lock.Unlock()
+
+		if t, ok := f.t.(CleanupT); ok {
+			t.Cleanup(func() {
+				ctx, cancel := context.WithTimeout(context.Background(), f.Config.ApiOperateTimeout)
+				defer cancel()
+				if err := f.RestoreNodeState(ctx, name); err != nil {
+					t.Errorf("%v", err)
+				}
+			})
+		}
+		f.Log("saved the state of node %s \n", name)
+	}
+	return nil
+}
+
+// SavedNodeStates returns the saved states, in the order of the name
+func (f *Framework) SavedNodeStates() []NodeState {
+	if f.nodeStates == nil {
+		return nil
+	}
+	f.nodeStates.lock.Lock()
+	defer f.nodeStates.lock.Unlock()
+	r := []NodeState{}
+	for _, name := range slices.Sorted(maps.Keys(f.nodeStates.states)) {
+		r = append(r, *f.nodeStates.states[name])
+	}
+	return r
+}
+
+// RestoreNodeState restores the saved nodes, empty means all the saved nodes.
+// Each label key and taint changed by the framework goes back to the saved value, the label added is removed
+// with its key in NodeOwnedLabelsAnnotation, and the other labels and taints are kept.
+// The node not saved is skipped, and the restored node is not saved anymore
+func (f *Framework) RestoreNodeState(ctx context.Context, names ...string) error {
+	if f.nodeStates == nil {
+		return nil
+	}
+	f.nodeStates.lock.Lock()
+	if len(names) == 0 {
+		names = slices.Sorted(maps.Keys(f.nodeStates.states))
+	}
+	states := []*NodeState{}
+	changes := []*nodeChanges{}
+	for _, name := range names {
+		if s, ok := f.nodeStates.states[name]; ok {
+			states = append(states, s)
+			changes = append(changes, f.nodeStates.changes[name])
+		}
+	}
+	f.nodeStates.lock.Unlock()
+
+	var errs []error
+	for n, s := range states {
+		if err := f.restoreNode(ctx, s, changes[n]); err != nil {
+			errs = append(errs, fmt.Errorf("failed to restore node %s: %w", s.Name, err))
+			continue
+		}
+		f.nodeStates.lock.Lock()
+		delete(f.nodeStates.states, s.Name)
+		delete(f.nodeStates.changes, s.Name)
+		f.nodeStates.lock.Unlock()
+		f.Log("restored the state of node %s \n", s.Name)
+	}
+	return errors.Join(errs...)
+}
+
+// restoreNode patches back the changes of c with the resourceVersion, and retries on the conflict
+func (f *Framework) restoreNode(ctx context.Context, s *NodeState, c *nodeChanges) error {
+	if c == nil || c.empty() {
+		return nil
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		node, err := f.GetNodeContext(ctx, s.Name)
+		if err != nil {
+			return err
+		}
+		patch := client.MergeFromWithOptions(node.DeepCopy(), client.MergeFromWithOptimisticLock{})
+
+		if c.unschedulable {
+			node.Spec.Unschedulable = s.Unschedulable
+		}
+		for _, k := range c.labels {
+			v, ok := s.Labels[k]
+			if !ok {
+				delete(node.Labels, k)
+				continue
+			}
+			if node.Labels == nil {
+				node.Labels = map[string]string{}
+			}
+			node.Labels[k] = v
+		}
+		if len(c.taints) > 0 {
+			node.Spec.Taints = slices.DeleteFunc(node.Spec.Taints, c.changedTaint)
+			for _, t := range s.Taints {
+				if c.changedTaint(t) {
+					node.Spec.Taints = append(node.Spec.Taints, t)
+				}
+			}
+		}
+		owned := slices.DeleteFunc(ownedNodeLabels(node), func(k string) bool { return slices.Contains(c.labels, k) })
+		if len(owned) == 0 {
+			delete(node.Annotations, NodeOwnedLabelsAnnotation)
+		} else {
+			node.Annotations[NodeOwnedLabelsAnnotation] = strings.Join(owned, ",")
+		}
+		return f.PatchResourceContext(ctx, node, patch)
+	})
+}
+
+// userTaints returns the taints not managed by the node lifecycle controller
+func userTaints(taints []corev1.Taint) []corev1.Taint {
+	r := []corev1.Taint{}
+	for _, t := range taints {
+		if !strings.HasPrefix(t.Key, nodeLifecycleTaintPrefix) {
+			r = append(r, t)
+		}
+	}
+	return r
+}
+
+func taintEqual(a, b corev1.Taint) bool {
+	return a.Key == b.Key && a.Value == b.Value && a.Effect == b.Effect
+}
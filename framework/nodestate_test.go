@@ -0,0 +1,84 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	e2e "github.com/spidernet-io/e2eframework/framework"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("test node state", Label("nodestate"), func() {
+	var f *e2e.Framework
+	var t *cleanupT
+	var ctx context.Context
+
+	BeforeEach(func() {
+		fakeEnv("/tmp/nokubeconfigfile")
+		t = &cleanupT{}
+		var e error
+		f, e = e2e.NewFramework(t, nil, fakeClientSet())
+		Expect(e).NotTo(HaveOccurred())
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 20*time.Second)
+		DeferCleanup(cancel)
+
+		node := exampleNode("worker")
+		node.Labels = map[string]string{"zone": "a"}
+		node.Spec.Taints = []corev1.Taint{{Key: "dedicated", Value: "e2e", Effect: corev1.TaintEffectNoSchedule}}
+		Expect(f.CreateResourceContext(ctx, node)).To(Succeed())
+	})
+
+	It("restore the node when the spec finishes", func() {
+		Expect(f.CordonNode(ctx, "worker")).To(Succeed())
+		Expect(f.AddNodeLabels(ctx, "worker", map[string]string{"zone": "b"})).To(Succeed())
+		Expect(f.AddNodeTaints(ctx, "worker", corev1.Taint{Key: "e2e", Effect: corev1.TaintEffectNoExecute})).To(Succeed())
+
+		// the changes by others after the saving
+		node, e := f.GetNodeContext(ctx, "worker")
+		Expect(e).NotTo(HaveOccurred())
+		node.Labels["team"] = "network"
+		node.Spec.Taints = append(node.Spec.Taints,
+			corev1.Taint{Key: corev1.TaintNodeUnschedulable, Effect: corev1.TaintEffectNoSchedule},
+			corev1.Taint{Key: "other", Effect: corev1.TaintEffectNoSchedule},
+		)
+		Expect(f.UpdateResourceContext(ctx, node)).To(Succeed())
+
+		Expect(f.SavedNodeStates()).To(Equal([]e2e.NodeState{{
+			Name:   "worker",
+			Labels: map[string]string{"zone": "a"},
+			Taints: []corev1.Taint{{Key: "dedicated", Value: "e2e", Effect: corev1.TaintEffectNoSchedule}},
+		}}))
+
+		t.runCleanups()
+		Expect(t.errs).To(BeEmpty())
+		Expect(f.SavedNodeStates()).To(BeEmpty())
+		node, e = f.GetNodeContext(ctx, "worker")
+		Expect(e).NotTo(HaveOccurred())
+		Expect(node.Spec.Unschedulable).To(BeFalse())
+		Expect(node.Labels).To(Equal(map[string]string{"zone": "a", "team": "network"}))
+		Expect(node.Annotations).NotTo(HaveKey(e2e.NodeOwnedLabelsAnnotation))
+		// the taints by others and the node lifecycle controller are kept
+		Expect(node.Spec.Taints).To(Equal([]corev1.Taint{
+			{Key: "dedicated", Value: "e2e", Effect: corev1.TaintEffectNoSchedule},
+			{Key: corev1.TaintNodeUnschedulable, Effect: corev1.TaintEffectNoSchedule},
+			{Key: "other", Effect: corev1.TaintEffectNoSchedule},
+		}))
+
+		// the saved node without the change is untouched
+		Expect(f.SaveNodeState(ctx, "worker")).To(Succeed())
+		Expect(f.RestoreNodeState(ctx)).To(Succeed())
+		restored, e := f.GetNodeContext(ctx, "worker")
+		Expect(e).NotTo(HaveOccurred())
+		Expect(restored.ResourceVersion).To(Equal(node.ResourceVersion))
+
+		Expect(f.SaveNodeState(ctx)).To(MatchError(e2e.ErrWrongInput))
+		Expect(f.CordonNode(ctx, "not-existed")).NotTo(Succeed())
+	})
+})
@@ -9,44 +9,27 @@ import (
 
 	"github.com/spidernet-io/e2eframework/tools"
 	corev1 "k8s.io/api/core/v1"
-	api_errors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/kubectl/pkg/util/podutils"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func (f *Framework) CreatePod(pod *corev1.Pod, opts ...client.CreateOption) error {
-	// try to wait for finish last deleting
-	fake := &corev1.Pod{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: pod.Namespace,
-			Name:      pod.Name,
-		},
-	}
-	key := client.ObjectKeyFromObject(fake)
-	existing := &corev1.Pod{}
-	e := f.GetResource(key, existing)
-	if e == nil && existing.DeletionTimestamp == nil {
-		return fmt.Errorf("failed to create , a same pod %v/%v exists", pod.Namespace, pod.Name)
-	} else {
-		t := func() bool {
-			existing := &corev1.Pod{}
-			e := f.GetResource(key, existing)
-			b := api_errors.IsNotFound(e)
-			if !b {
-				f.Log("waiting for a same pod %v/%v to finish deleting \n", pod.Namespace, pod.Name)
-				return false
-			}
-			return true
-		}
-		if !tools.Eventually(t, f.Config.ResourceDeleteTimeout, time.Second) {
-			return ErrTimeOut
-		}
+	return f.CreatePodContext(context.Background(), pod, opts...)
+}
+
+func (f *Framework) CreatePodContext(ctx context.Context, pod *corev1.Pod, opts ...client.CreateOption) error {
+	if pod == nil {
+		return ErrWrongInput
 	}
-	return f.CreateResource(pod, opts...)
+	return Create(ctx, f, pod, opts...)
 }
 
 func (f *Framework) DeletePod(name, namespace string, opts ...client.DeleteOption) error {
+	return f.DeletePodContext(context.Background(), name, namespace, opts...)
+}
+
+func (f *Framework) DeletePodContext(ctx context.Context, name, namespace string, opts ...client.DeleteOption) error {
 
 	if name == "" || namespace == "" {
 		return ErrWrongInput
@@ -58,33 +41,27 @@ func (f *Framework) DeletePod(name, namespace string, opts ...client.DeleteOptio
 			Name:      name,
 		},
 	}
-	return f.DeleteResource(pod, opts...)
+	return f.DeleteResourceContext(ctx, pod, opts...)
 }
 
 func (f *Framework) GetPod(name, namespace string) (*corev1.Pod, error) {
+	return f.GetPodContext(context.Background(), name, namespace)
+}
 
+func (f *Framework) GetPodContext(ctx context.Context, name, namespace string) (*corev1.Pod, error) {
 	if name == "" || namespace == "" {
 		return nil, ErrWrongInput
 	}
-
-	pod := &corev1.Pod{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: namespace,
-			Name:      name,
-		},
-	}
-	key := client.ObjectKeyFromObject(pod)
-	existing := &corev1.Pod{}
-	e := f.GetResource(key, existing)
-	if e != nil {
-		return nil, e
-	}
-	return existing, e
+	return Get[*corev1.Pod](ctx, f, name, namespace)
 }
 
 func (f *Framework) GetPodList(opts ...client.ListOption) (*corev1.PodList, error) {
+	return f.GetPodListContext(context.Background(), opts...)
+}
+
+func (f *Framework) GetPodListContext(ctx context.Context, opts ...client.ListOption) (*corev1.PodList, error) {
 	pods := &corev1.PodList{}
-	e := f.ListResource(pods, opts...)
+	e := f.ListResourceContext(ctx, pods, opts...)
 	if e != nil {
 		return nil, e
 	}
@@ -92,38 +69,36 @@ func (f *Framework) GetPodList(opts ...client.ListOption) (*corev1.PodList, erro
 }
 
 func (f *Framework) WaitPodStarted(name, namespace string, ctx context.Context) (*corev1.Pod, error) {
-	var pod corev1.Pod
-	for {
-		select {
-		case <-ctx.Done():
-			f.Log("pod %s/%s is still in phase %s \n", namespace, name, pod.Status.Phase)
-			podEvents, err := f.GetEvents(context.Background(), "Pod", name, namespace)
-			if nil == err {
-				for _, item := range podEvents.Items {
-					f.Log("pod %s/%s events: %s\n", namespace, name, item.String())
-				}
-			} else {
-				f.Log("failed to get pod %s/%s events, error: %v \n", namespace, name, err)
-			}
-			return nil, fmt.Errorf("time out to wait pod %s/%s running", namespace, name)
-		default:
-			pod, err := f.GetPod(name, namespace)
-			if nil != err {
-				if api_errors.IsNotFound(err) {
-					time.Sleep(3 * time.Second)
-					continue
-				}
-				return nil, err
-			}
-			if pod.Status.Phase == corev1.PodRunning {
-				return pod, nil
+	return f.WaitPodStartedContext(ctx, name, namespace)
+}
+
+func (f *Framework) WaitPodStartedContext(ctx context.Context, name, namespace string) (*corev1.Pod, error) {
+	if name == "" || namespace == "" {
+		return nil, ErrWrongInput
+	}
+	key := client.ObjectKey{Namespace: namespace, Name: name}
+	pod, err := waitObject(ctx, f, key, newObject[*corev1.Pod], "running", func(pod *corev1.Pod, found bool) (bool, error) {
+		return found && pod.Status.Phase == corev1.PodRunning, nil
+	})
+	if err != nil && ctx.Err() != nil {
+		podEvents, e := f.GetEvents(context.Background(), "Pod", name, namespace)
+		if nil == e {
+			for _, item := range podEvents.Items {
+				f.Log("pod %s/%s events: %s\n", namespace, name, item.String())
 			}
-			time.Sleep(3 * time.Second)
+		} else {
+			f.Log("failed to get pod %s/%s events, error: %v \n", namespace, name, e)
 		}
+		return nil, err
 	}
+	return pod, err
 }
 
 func (f *Framework) WaitPodListDeleted(namespace string, label map[string]string, ctx context.Context) error {
+	return f.WaitPodListDeletedContext(ctx, namespace, label)
+}
+
+func (f *Framework) WaitPodListDeletedContext(ctx context.Context, namespace string, label map[string]string) error {
 	// Query all pods corresponding to the label
 	// Delete the resource until the query is empty
 
@@ -131,48 +106,27 @@ func (f *Framework) WaitPodListDeleted(namespace string, label map[string]string
 		return ErrWrongInput
 	}
 
-	opts := []client.ListOption{
-		client.InNamespace(namespace),
-		client.MatchingLabels(label),
-	}
-	for {
-		select {
-		case <-ctx.Done():
-			return ErrTimeOut
-		default:
-			podlist, err := f.GetPodList(opts...)
-			if err != nil {
-				return err
-			} else if len(podlist.Items) == 0 {
-				return nil
-			}
-			time.Sleep(3 * time.Second)
-		}
-	}
+	_, err := WaitList(ctx, f, func(podList *corev1.PodList) (bool, error) {
+		return len(podList.Items) == 0, nil
+	}, client.InNamespace(namespace), client.MatchingLabels(label))
+	return err
 }
 
 func (f *Framework) DeletePodUntilFinish(name, namespace string, ctx context.Context, opts ...client.DeleteOption) error {
+	return f.DeletePodUntilFinishContext(ctx, name, namespace, opts...)
+}
+
+func (f *Framework) DeletePodUntilFinishContext(ctx context.Context, name, namespace string, opts ...client.DeleteOption) error {
 	// Query all pods by name in namespace
 	// Delete the resource until the query is empty
 	if namespace == "" || name == "" {
 		return ErrWrongInput
 	}
-	err := f.DeletePod(name, namespace, opts...)
+	err := f.DeletePodContext(ctx, name, namespace, opts...)
 	if err != nil {
 		return err
 	}
-	for {
-		select {
-		case <-ctx.Done():
-			return ErrTimeOut
-		default:
-			pod, _ := f.GetPod(name, namespace)
-			if pod == nil {
-				return nil
-			}
-			time.Sleep(time.Second)
-		}
-	}
+	return waitGone(ctx, f, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}})
 }
 
 func (f *Framework) CheckPodListIpReady(podList *corev1.PodList) error {
@@ -211,13 +165,17 @@ func (f *Framework) CheckPodListIpReady(podList *corev1.PodList) error {
 }
 
 func (f *Framework) GetPodListByLabel(label map[string]string) (*corev1.PodList, error) {
+	return f.GetPodListByLabelContext(context.Background(), label)
+}
+
+func (f *Framework) GetPodListByLabelContext(ctx context.Context, label map[string]string) (*corev1.PodList, error) {
 	if label == nil {
 		return nil, ErrWrongInput
 	}
 	ops := []client.ListOption{
 		client.MatchingLabels(label),
 	}
-	return f.GetPodList(ops...)
+	return f.GetPodListContext(ctx, ops...)
 }
 
 func (f *Framework) CheckPodListRunning(podList *corev1.PodList) bool {
@@ -233,11 +191,15 @@ func (f *Framework) CheckPodListRunning(podList *corev1.PodList) bool {
 }
 
 func (f *Framework) DeletePodList(podList *corev1.PodList, opts ...client.DeleteOption) error {
+	return f.DeletePodListContext(context.Background(), podList, opts...)
+}
+
+func (f *Framework) DeletePodListContext(ctx context.Context, podList *corev1.PodList, opts ...client.DeleteOption) error {
 	if podList == nil {
 		return ErrWrongInput
 	}
 	for _, item := range podList.Items {
-		err := f.DeletePod(item.Name, item.Namespace, opts...)
+		err := f.DeletePodContext(ctx, item.Name, item.Namespace, opts...)
 		if err != nil {
 			return err
 		}
@@ -246,136 +208,120 @@ func (f *Framework) DeletePodList(podList *corev1.PodList, opts ...client.Delete
 }
 
 func (f *Framework) WaitPodListRunning(label map[string]string, expectedPodNum int, ctx context.Context) error {
+	return f.WaitPodListRunningContext(ctx, label, expectedPodNum)
+}
+
+func (f *Framework) WaitPodListRunningContext(ctx context.Context, label map[string]string, expectedPodNum int) error {
 	if label == nil || expectedPodNum == 0 {
 		return ErrWrongInput
 	}
-	for {
-		select {
-		default:
-			// get pod list
-			podList, err := f.GetPodListByLabel(label)
-			if err != nil {
-				return err
-			}
-			if len(podList.Items) != expectedPodNum {
-				break
-			}
-
-			// wait pod list Running
-			if f.CheckPodListRunning(podList) {
-				return nil
-			}
-			time.Sleep(time.Second)
-		case <-ctx.Done():
-			return fmt.Errorf("time out to wait podList ready")
-		}
-	}
+	_, err := WaitList(ctx, f, func(podList *corev1.PodList) (bool, error) {
+		return len(podList.Items) == expectedPodNum && f.CheckPodListRunning(podList), nil
+	}, client.MatchingLabels(label))
+	return err
 }
 
 func (f *Framework) DeletePodListRepeatedly(label map[string]string, interval time.Duration, ctx context.Context, opts ...client.DeleteOption) error {
+	return f.DeletePodListRepeatedlyContext(ctx, label, interval, opts...)
+}
+
+// DeletePodListRepeatedlyContext deletes the pods of label every interval until ctx is done, and stops at the first error.
+// See Chaos for the concurrent actions with the jitter
+func (f *Framework) DeletePodListRepeatedlyContext(ctx context.Context, label map[string]string, interval time.Duration, opts ...client.DeleteOption) error {
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
 		default:
-			podList, e1 := f.GetPodListByLabel(label)
+			podList, e1 := f.GetPodListByLabelContext(ctx, label)
 			if e1 != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
 				return e1
 			}
-			e2 := f.DeletePodList(podList, opts...)
+			e2 := f.DeletePodListContext(ctx, podList, opts...)
 			if e2 != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
 				return e2
 			}
-			time.Sleep(interval)
+			sleepContext(ctx, interval)
 		}
 	}
 }
 
 func (f *Framework) DeletePodListUntilReady(podList *corev1.PodList, timeOut time.Duration, opts ...client.DeleteOption) (*corev1.PodList, error) {
-	if podList == nil {
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+	return f.DeletePodListUntilReadyContext(ctx, podList, opts...)
+}
+
+func (f *Framework) DeletePodListUntilReadyContext(ctx context.Context, podList *corev1.PodList, opts ...client.DeleteOption) (*corev1.PodList, error) {
+	if podList == nil || len(podList.Items) == 0 {
 		return nil, ErrWrongInput
 	}
 
-	err := f.DeletePodList(podList, opts...)
+	err := f.DeletePodListContext(ctx, podList, opts...)
 	if err != nil {
 		f.Log("failed to DeletePodList")
 		return nil, err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
-	defer cancel()
-OUTER:
-	for {
-		time.Sleep(time.Second)
-		select {
-		case <-ctx.Done():
-			return nil, ErrTimeOut
-		default:
-		}
-		f.Log("checking restarted pod ")
-
-		podListWithLabel, err := f.GetPodListByLabel(podList.Items[0].Labels)
-		if err != nil {
-			f.Log("failed to GetPodListByLabel , reason=%v", err)
-			continue
-		}
-
+	f.Log("checking restarted pod ")
+	podListWithLabel, err := WaitList(ctx, f, func(podListWithLabel *corev1.PodList) (bool, error) {
 		if len(podListWithLabel.Items) != len(podList.Items) {
-			continue
+			return false, nil
 		}
-
 		for _, newPod := range podListWithLabel.Items {
 			if !podutils.IsPodReady(&newPod) {
-				continue OUTER
+				return false, nil
 			}
 			for _, oldPod := range podList.Items {
 				if newPod.UID == oldPod.UID {
-					continue OUTER
+					return false, nil
 				}
 			}
 
 			// make sure pod ready
 			for _, newPodContainer := range newPod.Status.ContainerStatuses {
 				if !newPodContainer.Ready {
-					continue OUTER
+					return false, nil
 				}
 			}
 		}
-		return podListWithLabel, nil
+		return true, nil
+	}, client.MatchingLabels(podList.Items[0].Labels))
+	if err != nil {
+		return nil, err
 	}
+	return podListWithLabel, nil
 }
 
 // Waiting for all pods in all namespaces to run
 func (f *Framework) WaitAllPodUntilRunning(ctx context.Context) error {
-	var AllPodList *corev1.PodList
-	var err error
-
-	for {
-		select {
-		case <-ctx.Done():
-			return ErrTimeOut
-		default:
-
-			// GetPodList（opts ... ListOption） If no value is specified, get all the namespace's pods
-			AllPodList, err = f.GetPodList()
-			if err != nil {
-				return err
-			}
+	return f.WaitAllPodUntilRunningContext(ctx)
+}
 
-			if f.CheckPodListRunning(AllPodList) {
-				return nil
-			}
-			time.Sleep(time.Second)
-		}
-	}
+func (f *Framework) WaitAllPodUntilRunningContext(ctx context.Context) error {
+	// If no option is specified, watch the pods of all the namespaces
+	_, err := WaitList(ctx, f, func(allPodList *corev1.PodList) (bool, error) {
+		return f.CheckPodListRunning(allPodList), nil
+	})
+	return err
 }
 
 func (f *Framework) DeletePodListByLabel(label map[string]string) error {
+	return f.DeletePodListByLabelContext(context.Background(), label)
+}
+
+func (f *Framework) DeletePodListByLabelContext(ctx context.Context, label map[string]string) error {
 	if label == nil {
 		return ErrWrongInput
 	}
 
-	podList, err := f.GetPodListByLabel(label)
+	podList, err := f.GetPodListByLabelContext(ctx, label)
 	if err != nil {
 		return err
 	}
@@ -384,5 +330,5 @@ func (f *Framework) DeletePodListByLabel(label map[string]string) error {
 		return nil
 	}
 
-	return f.DeletePodList(podList)
+	return f.DeletePodListContext(ctx, podList)
 }
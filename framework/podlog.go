@@ -0,0 +1,147 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// the pod log operations need KClientSet, opts selects the container, previous, sinceTime, tailLines and limitBytes,
+// nil opts means the only container of the pod, like:
+//   tail := int64(100)
+//   logs, err := f.GetPodLogs(ctx, name, namespace, &corev1.PodLogOptions{Container: "agent", TailLines: &tail})
+//   line, err := f.WaitPodLogLine(ctx, name, namespace, nil, regexp.MustCompile(`ip allocated`))
+
+// GetPodLogs returns the log of the container, opts.Follow is ignored
+func (f *Framework) GetPodLogs(ctx context.Context, name, namespace string, opts *corev1.PodLogOptions) (string, error) {
+	if name == "" || namespace == "" {
+		return "", ErrWrongInput
+	}
+	o := podLogOptions(opts)
+	o.Follow = false
+
+	ctx, cancel := context.WithTimeout(ctx, f.Config.ApiOperateTimeout)
+	defer cancel()
+	stream, err := f.openPodLogs(ctx, name, namespace, o)
+	if err != nil {
+		return "", err
+	}
+	defer stream.Close()
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// StreamPodLogs follows the log of the container, and calls fn for each line until fn returns false,
+// the log ends or ctx is done. It returns nil when fn stops it or the log ends
+func (f *Framework) StreamPodLogs(ctx context.Context, name, namespace string, opts *corev1.PodLogOptions, fn func(line string) bool) error {
+	if name == "" || namespace == "" || fn == nil {
+		return ErrWrongInput
+	}
+	o := podLogOptions(opts)
+	o.Follow = true
+
+	stream, err := f.openPodLogs(ctx, name, namespace, o)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	// close the stream to stop the blocking read when ctx is done
+	stop := context.AfterFunc(ctx, func() {
+		stream.Close()
+	})
+	defer stop()
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if !fn(scanner.Text()) {
+			return nil
+		}
+	}
+	if ctx.Err() != nil {
+		return ErrTimeOut
+	}
+	return scanner.Err()
+}
+
+// FollowPodLogs delivers the log lines to the returned channel, which is closed when the log ends or ctx is done.
+// Then the error channel gets the result of StreamPodLogs
+func (f *Framework) FollowPodLogs(ctx context.Context, name, namespace string, opts *corev1.PodLogOptions) (<-chan string, <-chan error) {
+	lines := make(chan string, 100)
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(errCh)
+		defer close(lines)
+		errCh <- f.StreamPodLogs(ctx, name, namespace, opts, func(line string) bool {
+			select {
+			case lines <- line:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	return lines, errCh
+}
+
+// WaitPodLogLine waits until a line of the container log matches pattern, and returns the line.
+// It keeps retrying when the container is not started yet or restarts, until ctx is done
+func (f *Framework) WaitPodLogLine(ctx context.Context, name, namespace string, opts *corev1.PodLogOptions, pattern *regexp.Regexp) (string, error) {
+	if name == "" || namespace == "" || pattern == nil {
+		return "", ErrWrongInput
+	}
+	var lastErr error
+	for {
+		matched := ""
+		err := f.StreamPodLogs(ctx, name, namespace, opts, func(line string) bool {
+			if pattern.MatchString(line) {
+				matched = line
+				return false
+			}
+			return true
+		})
+		if matched != "" {
+			return matched, nil
+		}
+		if err != nil {
+			lastErr = err
+		}
+		select {
+		case <-ctx.Done():
+			if lastErr != nil && lastErr != ErrTimeOut {
+				return "", fmt.Errorf("%w: no log of %v/%v matches %q, last error: %v", ErrTimeOut, namespace, name, pattern.String(), lastErr)
+			}
+			return "", fmt.Errorf("%w: no log of %v/%v matches %q", ErrTimeOut, namespace, name, pattern.String())
+		default:
+		}
+		sleepContext(ctx, time.Second)
+	}
+}
+
+func (f *Framework) openPodLogs(ctx context.Context, name, namespace string, opts *corev1.PodLogOptions) (io.ReadCloser, error) {
+	if f.KClientSet == nil {
+		return nil, ErrNoClientSet
+	}
+	stream, err := f.KClientSet.CoreV1().Pods(namespace).GetLogs(name, opts).Stream(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get log of %v/%v container %q: %w", namespace, name, opts.Container, err)
+	}
+	return stream, nil
+}
+
+func podLogOptions(opts *corev1.PodLogOptions) *corev1.PodLogOptions {
+	if opts == nil {
+		return &corev1.PodLogOptions{}
+	}
+	return opts.DeepCopy()
+}
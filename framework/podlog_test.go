@@ -0,0 +1,64 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework_test
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	e2e "github.com/spidernet-io/e2eframework/framework"
+	corev1 "k8s.io/api/core/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("test pod log", Label("podlog"), func() {
+	var f *e2e.Framework
+	var ctx context.Context
+
+	BeforeEach(func() {
+		f = fakeFramework()
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 20*time.Second)
+		DeferCleanup(cancel)
+	})
+
+	It("need the clientset", func() {
+		_, e := f.GetPodLogs(ctx, "pod", "default", nil)
+		Expect(e).To(MatchError(e2e.ErrNoClientSet))
+		_, e = f.GetPodLogs(ctx, "", "default", nil)
+		Expect(e).To(MatchError(e2e.ErrWrongInput))
+	})
+
+	It("get and follow the log", func() {
+		// the fake clientset returns "fake logs" for any pod
+		f.KClientSet = k8sfake.NewSimpleClientset()
+		tail := int64(10)
+		logs, e := f.GetPodLogs(ctx, "pod", "default", &corev1.PodLogOptions{Container: "test", TailLines: &tail})
+		Expect(e).NotTo(HaveOccurred())
+		Expect(logs).To(Equal("fake logs"))
+
+		lines := []string{}
+		Expect(f.StreamPodLogs(ctx, "pod", "default", nil, func(line string) bool {
+			lines = append(lines, line)
+			return true
+		})).To(Succeed())
+		Expect(lines).To(Equal([]string{"fake logs"}))
+
+		ch, errCh := f.FollowPodLogs(ctx, "pod", "default", nil)
+		Eventually(ch).Should(Receive(Equal("fake logs")))
+		Eventually(errCh).Should(Receive(BeNil()))
+
+		line, e := f.WaitPodLogLine(ctx, "pod", "default", nil, regexp.MustCompile(`^fake`))
+		Expect(e).NotTo(HaveOccurred())
+		Expect(line).To(Equal("fake logs"))
+
+		shortCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+		_, e = f.WaitPodLogLine(shortCtx, "pod", "default", nil, regexp.MustCompile(`not-existed`))
+		Expect(e).To(MatchError(e2e.ErrTimeOut))
+	})
+})
@@ -0,0 +1,314 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// the port-forward listens on a local address, and forwards each connection to the pod over the API server, like:
+//   pf, err := f.PortForwardService(ctx, "spiderpool-agent", "kube-system", 5711)
+//   resp, err := http.Get("http://" + pf.Addr + "/v1/runtime/readiness")
+// When the pod restarts, or the service selects another pod, the next connection reconnects to the new target.
+// It is closed when ctx is done, when the spec finishes if f.t is CleanupT, or by Close()
+
+// PortForward is a running port-forward
+type PortForward struct {
+	// the local address like "127.0.0.1:34567"
+	Addr      string
+	LocalPort int
+
+	f        *Framework
+	listener net.Listener
+	// resolve returns the pod and port of the target, which is called again for the reconnection
+	resolve func(ctx context.Context) (string, string, int, error)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	lock      sync.Mutex
+	conn      httpstream.Connection
+	port      int
+	requestID int
+}
+
+// PortForwardPod forwards a local port to the port of the pod
+func (f *Framework) PortForwardPod(ctx context.Context, name, namespace string, port int) (*PortForward, error) {
+	if name == "" || namespace == "" || port <= 0 {
+		return nil, ErrWrongInput
+	}
+	return f.startPortForward(ctx, func(ctx context.Context) (string, string, int, error) {
+		return name, namespace, port, nil
+	})
+}
+
+// PortForwardService forwards a local port to the service port, by a ready endpoint pod of the service
+func (f *Framework) PortForwardService(ctx context.Context, name, namespace string, port int) (*PortForward, error) {
+	if name == "" || namespace == "" || port <= 0 {
+		return nil, ErrWrongInput
+	}
+	return f.startPortForward(ctx, func(ctx context.Context) (string, string, int, error) {
+		pod, podPort, err := f.ResolveServicePod(ctx, name, namespace, port)
+		return pod, namespace, podPort, err
+	})
+}
+
+// ResolveServicePod returns a ready endpoint pod of the service, and the target port of the service port on it
+func (f *Framework) ResolveServicePod(ctx context.Context, name, namespace string, port int) (string, int, error) {
+	if name == "" || namespace == "" || port <= 0 {
+		return "", 0, ErrWrongInput
+	}
+	svc, err := f.GetServiceContext(ctx, name, namespace)
+	if err != nil {
+		return "", 0, err
+	}
+	var svcPort *corev1.ServicePort
+	for n := range svc.Spec.Ports {
+		if int(svc.Spec.Ports[n].Port) == port {
+			svcPort = &svc.Spec.Ports[n]
+			break
+		}
+	}
+	if svcPort == nil {
+		return "", 0, fmt.Errorf("%w: service %v/%v has no port %v", ErrWrongInput, namespace, name, port)
+	}
+
+	ep, err := f.GetEndpointContext(ctx, name, namespace)
+	if err != nil {
+		return "", 0, err
+	}
+	for _, subset := range ep.Subsets {
+		podPort := 0
+		for _, p := range subset.Ports {
+			if p.Name == svcPort.Name {
+				podPort = int(p.Port)
+				break
+			}
+		}
+		if podPort == 0 && svcPort.TargetPort.Type == intstr.Int && svcPort.TargetPort.IntValue() > 0 {
+			podPort = svcPort.TargetPort.IntValue()
+		}
+		if podPort == 0 {
+			continue
+		}
+		for _, addr := range subset.Addresses {
+			if addr.TargetRef != nil && addr.TargetRef.Kind == "Pod" {
+				return addr.TargetRef.Name, podPort, nil
+			}
+		}
+	}
+	return "", 0, fmt.Errorf("service %v/%v has no ready endpoint pod for port %v", namespace, name, port)
+}
+
+func (f *Framework) startPortForward(ctx context.Context, resolve func(ctx context.Context) (string, string, int, error)) (*PortForward, error) {
+	if f.KConfig == nil || f.KClientSet == nil {
+		return nil, ErrNoClientSet
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	pf := &PortForward{
+		Addr:      listener.Addr().String(),
+		LocalPort: listener.Addr().(*net.TCPAddr).Port,
+		f:         f,
+		listener:  listener,
+		resolve:   resolve,
+		done:      make(chan struct{}),
+	}
+	pf.ctx, pf.cancel = context.WithCancel(ctx)
+
+	// fail early for a wrong target
+	if _, _, err := pf.connection(); err != nil {
+		pf.Close()
+		return nil, err
+	}
+	if t, ok := f.t.(CleanupT); ok {
+		t.Cleanup(pf.Close)
+	}
+	go pf.serve()
+	f.Log("port-forward %v is ready \n", pf.Addr)
+	return pf, nil
+}
+
+// Close stops the listener and the connection to the pod
+func (pf *PortForward) Close() {
+	pf.cancel()
+	pf.listener.Close()
+	pf.lock.Lock()
+	if pf.conn != nil {
+		pf.conn.Close()
+		pf.conn = nil
+	}
+	pf.lock.Unlock()
+}
+
+// Done is closed when the port-forward stops
+func (pf *PortForward) Done() <-chan struct{} {
+	return pf.done
+}
+
+func (pf *PortForward) serve() {
+	defer close(pf.done)
+	go func() {
+		<-pf.ctx.Done()
+		pf.Close()
+	}()
+	for {
+		local, err := pf.listener.Accept()
+		if err != nil {
+			return
+		}
+		go pf.handle(local)
+	}
+}
+
+// connection returns the connection to the pod and the target port, and reconnects after the last one is closed
+func (pf *PortForward) connection() (httpstream.Connection, int, error) {
+	pf.lock.Lock()
+	defer pf.lock.Unlock()
+	if pf.conn != nil {
+		select {
+		case <-pf.conn.CloseChan():
+			pf.conn = nil
+		default:
+			return pf.conn, pf.port, nil
+		}
+	}
+	if pf.ctx.Err() != nil {
+		return nil, 0, ErrTimeOut
+	}
+
+	// resolve again, the service may select another pod
+	pod, namespace, port, err := pf.resolve(pf.ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	transport, upgrader, err := spdy.RoundTripperFor(pf.f.KConfig)
+	if err != nil {
+		return nil, 0, err
+	}
+	url := pf.f.KClientSet.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(pod).
+		SubResource("portforward").URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", url)
+	conn, _, err := dialer.Dial(portforward.PortForwardProtocolV1Name)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to port-forward to %v/%v: %w", namespace, pod, err)
+	}
+	conn.SetIdleTimeout(0)
+	pf.conn, pf.port = conn, port
+	pf.f.Log("port-forward %v connects to %v/%v:%v \n", pf.Addr, namespace, pod, port)
+	return conn, port, nil
+}
+
+// dropConnection closes the broken conn, and forgets it unless another one has been connected,
+// so that the next connection() never returns it before its CloseChan is closed
+func (pf *PortForward) dropConnection(conn httpstream.Connection) {
+	pf.lock.Lock()
+	if pf.conn == conn {
+		pf.conn = nil
+	}
+	pf.lock.Unlock()
+	conn.Close()
+}
+
+func (pf *PortForward) handle(local net.Conn) {
+	defer local.Close()
+	conn, port, err := pf.connection()
+	if err != nil {
+		pf.f.Log("port-forward %v: %v \n", pf.Addr, err)
+		return
+	}
+	errorStream, dataStream, err := pf.createStreams(conn, port)
+	if err != nil {
+		// the pod may restart, reconnect once over a new connection
+		pf.dropConnection(conn)
+		if conn, port, err = pf.connection(); err == nil {
+			errorStream, dataStream, err = pf.createStreams(conn, port)
+		}
+		if err != nil {
+			pf.f.Log("port-forward %v: %v \n", pf.Addr, err)
+			return
+		}
+	}
+	defer conn.RemoveStreams(errorStream, dataStream)
+
+	errCh := make(chan error, 1)
+	go func() {
+		message, err := io.ReadAll(errorStream)
+		switch {
+		case err != nil:
+			errCh <- err
+		case len(message) > 0:
+			errCh <- errors.New(string(message))
+		}
+		close(errCh)
+	}()
+
+	localDone := make(chan struct{})
+	go func() {
+		// the pod closes the data stream
+		_, _ = io.Copy(local, dataStream)
+		close(localDone)
+	}()
+	go func() {
+		_, _ = io.Copy(dataStream, local)
+		dataStream.Close()
+	}()
+
+	select {
+	case <-localDone:
+	case <-pf.ctx.Done():
+		return
+	}
+	select {
+	case err := <-errCh:
+		if err != nil {
+			pf.f.Log("port-forward %v: %v \n", pf.Addr, err)
+		}
+	case <-pf.ctx.Done():
+	}
+}
+
+func (pf *PortForward) createStreams(conn httpstream.Connection, port int) (httpstream.Stream, httpstream.Stream, error) {
+	pf.lock.Lock()
+	pf.requestID++
+	requestID := pf.requestID
+	pf.lock.Unlock()
+
+	headers := http.Header{}
+	headers.Set(corev1.StreamType, corev1.StreamTypeError)
+	headers.Set(corev1.PortHeader, strconv.Itoa(port))
+	headers.Set(corev1.PortForwardRequestIDHeader, strconv.Itoa(requestID))
+	errorStream, err := conn.CreateStream(headers)
+	if err != nil {
+		return nil, nil, err
+	}
+	// only read the error stream
+	errorStream.Close()
+
+	headers.Set(corev1.StreamType, corev1.StreamTypeData)
+	dataStream, err := conn.CreateStream(headers)
+	if err != nil {
+		conn.RemoveStreams(errorStream)
+		return nil, nil, err
+	}
+	return errorStream, dataStream, nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	e2e "github.com/spidernet-io/e2eframework/framework"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+var _ = Describe("test port-forward", Label("portforward"), func() {
+	var f *e2e.Framework
+	var ctx context.Context
+
+	BeforeEach(func() {
+		f = fakeFramework()
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 20*time.Second)
+		DeferCleanup(cancel)
+	})
+
+	It("check the input", func() {
+		_, e := f.PortForwardPod(ctx, "pod", "default", 80)
+		Expect(e).To(MatchError(e2e.ErrNoClientSet))
+		_, e = f.PortForwardPod(ctx, "pod", "default", 0)
+		Expect(e).To(MatchError(e2e.ErrWrongInput))
+		_, e = f.PortForwardService(ctx, "", "default", 80)
+		Expect(e).To(MatchError(e2e.ErrWrongInput))
+	})
+
+	It("resolve the service to a ready endpoint pod", func() {
+		svc := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: corev1.ServiceSpec{
+				Ports: []corev1.ServicePort{
+					{Name: "http", Port: 80, TargetPort: intstr.FromString("web")},
+					{Name: "metrics", Port: 9090, TargetPort: intstr.FromInt32(9091)},
+				},
+			},
+		}
+		Expect(f.CreateResourceContext(ctx, svc)).To(Succeed())
+
+		_, _, e := f.ResolveServicePod(ctx, "web", "default", 80)
+		Expect(e).To(HaveOccurred())
+
+		ep := &corev1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Subsets: []corev1.EndpointSubset{
+				{
+					NotReadyAddresses: []corev1.EndpointAddress{
+						{IP: "10.0.0.1", TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: "web-0"}},
+					},
+					Addresses: []corev1.EndpointAddress{
+						{IP: "10.0.0.2", TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: "web-1"}},
+					},
+					Ports: []corev1.EndpointPort{
+						{Name: "http", Port: 8080},
+						{Name: "metrics", Port: 9091},
+					},
+				},
+			},
+		}
+		Expect(f.CreateResourceContext(ctx, ep)).To(Succeed())
+
+		pod, port, e := f.ResolveServicePod(ctx, "web", "default", 80)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(pod).To(Equal("web-1"))
+		Expect(port).To(Equal(8080))
+
+		pod, port, e = f.ResolveServicePod(ctx, "web", "default", 9090)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(pod).To(Equal("web-1"))
+		Expect(port).To(Equal(9091))
+
+		_, _, e = f.ResolveServicePod(ctx, "web", "default", 443)
+		Expect(e).To(MatchError(e2e.ErrWrongInput))
+	})
+
+	It("fail for the denied port-forward", func() {
+		lock := sync.Mutex{}
+		paths := []string{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			defer lock.Unlock()
+			paths = append(paths, r.URL.Path)
+			http.Error(w, "port-forward is not supported", http.StatusForbidden)
+		}))
+		DeferCleanup(server.Close)
+
+		var e error
+		f.KConfig = &rest.Config{Host: server.URL}
+		f.KClientSet, e = kubernetes.NewForConfig(f.KConfig)
+		Expect(e).NotTo(HaveOccurred())
+
+		_, e = f.PortForwardPod(ctx, "pod", "default", 80)
+		Expect(e).To(HaveOccurred())
+		Expect(strings.Contains(e.Error(), "default/pod")).To(BeTrue())
+
+		lock.Lock()
+		defer lock.Unlock()
+		Expect(paths).To(ContainElement("/api/v1/namespaces/default/pods/pod/portforward"))
+	})
+})
@@ -0,0 +1,397 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensions_v1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// the readiness is computed like kstatus, the object is Current only when the controller has observed
+// the latest generation and the rollout of it has finished, so an updated Deployment is not ready for the old pods, like:
+//   r, err := framework.ComputeReadiness(deploy)
+//   err := f.WaitReady(ctx, deploy)
+// The custom resource is Current when it has no status, or its Ready condition is true
+
+// ReadinessStatus is the result of ComputeReadiness
+type ReadinessStatus string
+
+const (
+	// ReadinessInProgress means the object is still reconciled or rolling out
+	ReadinessInProgress ReadinessStatus = "InProgress"
+	// ReadinessCurrent means the object reaches the desired state
+	ReadinessCurrent ReadinessStatus = "Current"
+	// ReadinessFailed means the object can not reach the desired state without the intervention
+	ReadinessFailed ReadinessStatus = "Failed"
+)
+
+// Readiness is the readiness status with the reason
+type Readiness struct {
+	Status ReadinessStatus
+	Reason string
+}
+
+func (r Readiness) String() string {
+	if r.Reason == "" {
+		return string(r.Status)
+	}
+	return fmt.Sprintf("%s: %s", r.Status, r.Reason)
+}
+
+var (
+	deploymentGK  = schema.GroupKind{Group: appsv1.GroupName, Kind: "Deployment"}
+	statefulSetGK = schema.GroupKind{Group: appsv1.GroupName, Kind: "StatefulSet"}
+	daemonSetGK   = schema.GroupKind{Group: appsv1.GroupName, Kind: "DaemonSet"}
+	replicaSetGK  = schema.GroupKind{Group: appsv1.GroupName, Kind: "ReplicaSet"}
+	jobGK         = schema.GroupKind{Group: batchv1.GroupName, Kind: "Job"}
+	podGK         = schema.GroupKind{Kind: "Pod"}
+	pvcGK         = schema.GroupKind{Kind: "PersistentVolumeClaim"}
+	serviceGK     = schema.GroupKind{Kind: "Service"}
+	crdGK         = schema.GroupKind{Group: apiextensions_v1.GroupName, Kind: "CustomResourceDefinition"}
+
+	// the typed object of the unstructured object with the built-in kind
+	typedObjects = map[schema.GroupKind]func() client.Object{
+		deploymentGK:  func() client.Object { return &appsv1.Deployment{} },
+		statefulSetGK: func() client.Object { return &appsv1.StatefulSet{} },
+		daemonSetGK:   func() client.Object { return &appsv1.DaemonSet{} },
+		replicaSetGK:  func() client.Object { return &appsv1.ReplicaSet{} },
+		jobGK:         func() client.Object { return &batchv1.Job{} },
+		podGK:         func() client.Object { return &corev1.Pod{} },
+		pvcGK:         func() client.Object { return &corev1.PersistentVolumeClaim{} },
+		serviceGK:     func() client.Object { return &corev1.Service{} },
+		crdGK:         func() client.Object { return &apiextensions_v1.CustomResourceDefinition{} },
+	}
+)
+
+// ComputeReadiness returns the readiness of obj, the unstructured object of the built-in kind is computed as the typed one
+func ComputeReadiness(obj client.Object) (Readiness, error) {
+	if isNilObject(obj) {
+		return Readiness{}, ErrWrongInput
+	}
+	if obj.GetDeletionTimestamp() != nil {
+		return inProgressReadiness("the object is being deleted"), nil
+	}
+
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		newTyped, ok := typedObjects[u.GroupVersionKind().GroupKind()]
+		if !ok {
+			return customResourceReadiness(u)
+		}
+		typed := newTyped()
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, typed); err != nil {
+			return Readiness{}, err
+		}
+		obj = typed
+	}
+
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		return deploymentReadiness(o), nil
+	case *appsv1.StatefulSet:
+		return statefulSetReadiness(o), nil
+	case *appsv1.DaemonSet:
+		return daemonSetReadiness(o), nil
+	case *appsv1.ReplicaSet:
+		return replicaSetReadiness(o), nil
+	case *batchv1.Job:
+		return jobReadiness(o), nil
+	case *corev1.Pod:
+		return podReadiness(o), nil
+	case *corev1.PersistentVolumeClaim:
+		return pvcReadiness(o), nil
+	case *corev1.Service:
+		return serviceReadiness(o), nil
+	case *apiextensions_v1.CustomResourceDefinition:
+		return crdReadiness(o), nil
+	}
+
+	// like the typed CRD of schemeRegisterList
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return Readiness{}, err
+	}
+	return customResourceReadiness(&unstructured.Unstructured{Object: content})
+}
+
+// WaitReady waits until obj is Current, and updates obj to the latest state.
+// It fails at once with ErrResFailed when obj is Failed
+func (f *Framework) WaitReady(ctx context.Context, obj client.Object) error {
+	if isNilObject(obj) || obj.GetName() == "" {
+		return ErrWrongInput
+	}
+	newObj := func() client.Object {
+		return newObjectLike(obj)
+	}
+	latest, err := waitReady(ctx, f, obj.GetName(), obj.GetNamespace(), newObj)
+	if err != nil {
+		return err
+	}
+	reflect.ValueOf(obj).Elem().Set(reflect.ValueOf(latest).Elem())
+	return nil
+}
+
+// waitReady waits until the object is Current, and fails with ErrResFailed or ErrResDel
+func waitReady[T client.Object](ctx context.Context, f *Framework, name, namespace string, newObj func() T) (T, error) {
+	var last Readiness
+	return waitUntilNotDeleted(ctx, f, name, namespace, "ready", newObj, func(obj T) (bool, error) {
+		r, err := ComputeReadiness(obj)
+		if err != nil {
+			return false, err
+		}
+		key := keyString(client.ObjectKeyFromObject(obj))
+		if r != last {
+			f.Log("%s %s is %v \n", f.kindOf(obj), key, r)
+			last = r
+		}
+		switch r.Status {
+		case ReadinessCurrent:
+			return true, nil
+		case ReadinessFailed:
+			return false, fmt.Errorf("%w: %s '%s': %s", ErrResFailed, f.kindOf(obj), key, r.Reason)
+		}
+		return false, nil
+	})
+}
+
+func inProgressReadiness(format string, args ...interface{}) Readiness {
+	return Readiness{Status: ReadinessInProgress, Reason: fmt.Sprintf(format, args...)}
+}
+
+func failedReadiness(format string, args ...interface{}) Readiness {
+	return Readiness{Status: ReadinessFailed, Reason: fmt.Sprintf(format, args...)}
+}
+
+func currentReadiness(format string, args ...interface{}) Readiness {
+	return Readiness{Status: ReadinessCurrent, Reason: fmt.Sprintf(format, args...)}
+}
+
+// generationObserved reports the stale status of the old spec
+func generationObserved(obj client.Object, observedGeneration int64) (Readiness, bool) {
+	if observedGeneration < obj.GetGeneration() {
+		return inProgressReadiness("the controller observed generation %d, the latest is %d", observedGeneration, obj.GetGeneration()), false
+	}
+	return Readiness{}, true
+}
+
+func replicasOf(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
+
+func deploymentReadiness(d *appsv1.Deployment) Readiness {
+	if r, ok := generationObserved(d, d.Status.ObservedGeneration); !ok {
+		return r
+	}
+	for _, c := range d.Status.Conditions {
+		if c.Type == appsv1.DeploymentProgressing && c.Reason == "ProgressDeadlineExceeded" {
+			return failedReadiness("progress deadline exceeded: %s", c.Message)
+		}
+	}
+	replicas := replicasOf(d.Spec.Replicas)
+	switch {
+	case d.Spec.Paused:
+		return inProgressReadiness("the deployment is paused")
+	case d.Status.UpdatedReplicas < replicas:
+		return inProgressReadiness("updated replicas %d/%d", d.Status.UpdatedReplicas, replicas)
+	case d.Status.Replicas > d.Status.UpdatedReplicas:
+		return inProgressReadiness("%d old replicas are pending termination", d.Status.Replicas-d.Status.UpdatedReplicas)
+	case d.Status.AvailableReplicas < replicas:
+		return inProgressReadiness("available replicas %d/%d", d.Status.AvailableReplicas, replicas)
+	case d.Status.ReadyReplicas < replicas:
+		return inProgressReadiness("ready replicas %d/%d", d.Status.ReadyReplicas, replicas)
+	}
+	return currentReadiness("all %d replicas are updated and available", replicas)
+}
+
+func statefulSetReadiness(s *appsv1.StatefulSet) Readiness {
+	if r, ok := generationObserved(s, s.Status.ObservedGeneration); !ok {
+		return r
+	}
+	replicas := replicasOf(s.Spec.Replicas)
+	switch {
+	case s.Status.Replicas < replicas:
+		return inProgressReadiness("replicas %d/%d", s.Status.Replicas, replicas)
+	case s.Status.Replicas > replicas:
+		return inProgressReadiness("%d replicas are pending termination", s.Status.Replicas-replicas)
+	case s.Status.ReadyReplicas < replicas:
+		return inProgressReadiness("ready replicas %d/%d", s.Status.ReadyReplicas, replicas)
+	}
+	if s.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType {
+		return currentReadiness("all %d replicas are ready", replicas)
+	}
+	partition := int32(0)
+	if u := s.Spec.UpdateStrategy.RollingUpdate; u != nil && u.Partition != nil {
+		partition = *u.Partition
+	}
+	if partition > 0 {
+		if expected := replicas - partition; s.Status.UpdatedReplicas < expected {
+			return inProgressReadiness("updated replicas %d/%d of the partition", s.Status.UpdatedReplicas, expected)
+		}
+		return currentReadiness("the partition is rolled out")
+	}
+	if s.Status.UpdateRevision != s.Status.CurrentRevision {
+		return inProgressReadiness("waiting for the rolling update to revision %s, updated replicas %d/%d", s.Status.UpdateRevision, s.Status.UpdatedReplicas, replicas)
+	}
+	return currentReadiness("all %d replicas are updated and ready", replicas)
+}
+
+func daemonSetReadiness(d *appsv1.DaemonSet) Readiness {
+	if r, ok := generationObserved(d, d.Status.ObservedGeneration); !ok {
+		return r
+	}
+	desired := d.Status.DesiredNumberScheduled
+	switch {
+	case d.Status.CurrentNumberScheduled < desired:
+		return inProgressReadiness("scheduled pods %d/%d", d.Status.CurrentNumberScheduled, desired)
+	case d.Status.UpdatedNumberScheduled < desired:
+		return inProgressReadiness("updated pods %d/%d", d.Status.UpdatedNumberScheduled, desired)
+	case d.Status.NumberAvailable < desired:
+		return inProgressReadiness("available pods %d/%d", d.Status.NumberAvailable, desired)
+	case d.Status.NumberReady < desired:
+		return inProgressReadiness("ready pods %d/%d", d.Status.NumberReady, desired)
+	}
+	return currentReadiness("all %d pods are updated and available", desired)
+}
+
+func replicaSetReadiness(rs *appsv1.ReplicaSet) Readiness {
+	if r, ok := generationObserved(rs, rs.Status.ObservedGeneration); !ok {
+		return r
+	}
+	for _, c := range rs.Status.Conditions {
+		if c.Type == appsv1.ReplicaSetReplicaFailure && c.Status == corev1.ConditionTrue {
+			return failedReadiness("replica failure: %s", c.Message)
+		}
+	}
+	replicas := replicasOf(rs.Spec.Replicas)
+	switch {
+	case rs.Status.Replicas > replicas:
+		return inProgressReadiness("%d replicas are pending termination", rs.Status.Replicas-replicas)
+	case rs.Status.AvailableReplicas < replicas:
+		return inProgressReadiness("available replicas %d/%d", rs.Status.AvailableReplicas, replicas)
+	case rs.Status.ReadyReplicas < replicas:
+		return inProgressReadiness("ready replicas %d/%d", rs.Status.ReadyReplicas, replicas)
+	}
+	return currentReadiness("all %d replicas are available", replicas)
+}
+
+// jobReadiness is Current when the job completes
+func jobReadiness(job *batchv1.Job) Readiness {
+	for _, c := range job.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobFailed:
+			return failedReadiness("the job failed: %s %s", c.Reason, c.Message)
+		case batchv1.JobComplete:
+			return currentReadiness("the job completed")
+		}
+	}
+	return inProgressReadiness("active pods %d, succeeded pods %d", job.Status.Active, job.Status.Succeeded)
+}
+
+func podReadiness(pod *corev1.Pod) Readiness {
+	switch pod.Status.Phase {
+	case corev1.PodSucceeded:
+		return currentReadiness("the pod succeeded")
+	case corev1.PodFailed:
+		return failedReadiness("the pod failed: %s %s", pod.Status.Reason, pod.Status.Message)
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodScheduled && c.Status == corev1.ConditionFalse {
+			return inProgressReadiness("the pod is not scheduled: %s", c.Message)
+		}
+	}
+	for _, s := range pod.Status.ContainerStatuses {
+		if s.State.Waiting != nil && s.State.Waiting.Reason != "" {
+			return inProgressReadiness("container %s is waiting: %s", s.Name, s.State.Waiting.Reason)
+		}
+	}
+	if pod.Status.Phase == corev1.PodRunning {
+		for _, c := range pod.Status.Conditions {
+			if c.Type == corev1.PodReady && c.Status == corev1.ConditionTrue {
+				return currentReadiness("the pod is ready")
+			}
+		}
+	}
+	return inProgressReadiness("the pod is %s and not ready", pod.Status.Phase)
+}
+
+func pvcReadiness(pvc *corev1.PersistentVolumeClaim) Readiness {
+	switch pvc.Status.Phase {
+	case corev1.ClaimBound:
+		return currentReadiness("the claim is bound")
+	case corev1.ClaimLost:
+		return failedReadiness("the claim lost its volume")
+	}
+	return inProgressReadiness("the claim is %s", pvc.Status.Phase)
+}
+
+func serviceReadiness(svc *corev1.Service) Readiness {
+	if svc.Spec.Type == corev1.ServiceTypeLoadBalancer && len(svc.Status.LoadBalancer.Ingress) == 0 {
+		return inProgressReadiness("waiting for the load balancer ingress")
+	}
+	return currentReadiness("the service is ready")
+}
+
+func crdReadiness(crd *apiextensions_v1.CustomResourceDefinition) Readiness {
+	for _, c := range crd.Status.Conditions {
+		if c.Type == apiextensions_v1.NamesAccepted && c.Status == apiextensions_v1.ConditionFalse {
+			return failedReadiness("the names are not accepted: %s", c.Message)
+		}
+	}
+	for _, c := range crd.Status.Conditions {
+		if c.Type == apiextensions_v1.Established && c.Status == apiextensions_v1.ConditionTrue {
+			return currentReadiness("the crd is established")
+		}
+	}
+	return inProgressReadiness("the crd is not established")
+}
+
+// customResourceReadiness checks the generic fields of the status, like kstatus
+func customResourceReadiness(u *unstructured.Unstructured) (Readiness, error) {
+	if observed, found, err := unstructured.NestedInt64(u.Object, "status", "observedGeneration"); err == nil && found {
+		if r, ok := generationObserved(u, observed); !ok {
+			return r, nil
+		}
+	}
+	conditions, _, err := unstructured.NestedSlice(u.Object, "status", "conditions")
+	if err != nil {
+		return Readiness{}, fmt.Errorf("invalid status.conditions: %w", err)
+	}
+	ready := ""
+	readyMessage := ""
+	for _, item := range conditions {
+		c, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		condType, _, _ := unstructured.NestedString(c, "type")
+		status, _, _ := unstructured.NestedString(c, "status")
+		message, _, _ := unstructured.NestedString(c, "message")
+		switch {
+		case condType == "Stalled" && status == string(corev1.ConditionTrue):
+			return failedReadiness("stalled: %s", message), nil
+		case condType == "Reconciling" && status == string(corev1.ConditionTrue):
+			return inProgressReadiness("reconciling: %s", message), nil
+		case condType == "Ready":
+			ready, readyMessage = status, message
+		}
+	}
+	if ready != "" && ready != string(corev1.ConditionTrue) {
+		return inProgressReadiness("not ready: %s", readyMessage), nil
+	}
+	return currentReadiness(""), nil
+}
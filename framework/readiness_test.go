@@ -0,0 +1,174 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	e2e "github.com/spidernet-io/e2eframework/framework"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensions_v1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func readyDeployment() *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "ready", Namespace: "default", Generation: 2},
+		Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(2))},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 2,
+			Replicas:           2,
+			UpdatedReplicas:    2,
+			ReadyReplicas:      2,
+			AvailableReplicas:  2,
+		},
+	}
+}
+
+func expectReadiness(obj client.Object, status e2e.ReadinessStatus) {
+	GinkgoHelper()
+	r, e := e2e.ComputeReadiness(obj)
+	Expect(e).NotTo(HaveOccurred())
+	Expect(r.Status).To(Equal(status), "readiness: %v", r)
+	Expect(r.Reason).NotTo(BeEmpty())
+}
+
+var _ = Describe("test readiness", Label("readiness"), func() {
+	var f *e2e.Framework
+	var ctx context.Context
+
+	BeforeEach(func() {
+		f = fakeFramework()
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 20*time.Second)
+		DeferCleanup(cancel)
+	})
+
+	It("compute the readiness of the deployment", func() {
+		expectReadiness(readyDeployment(), e2e.ReadinessCurrent)
+
+		// the status is for the old spec
+		d := readyDeployment()
+		d.Generation = 3
+		expectReadiness(d, e2e.ReadinessInProgress)
+
+		// the old pods are still serving
+		d = readyDeployment()
+		d.Status.Replicas = 3
+		expectReadiness(d, e2e.ReadinessInProgress)
+
+		d = readyDeployment()
+		d.Status.UpdatedReplicas = 1
+		expectReadiness(d, e2e.ReadinessInProgress)
+
+		d = readyDeployment()
+		d.Status.Conditions = []appsv1.DeploymentCondition{
+			{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded"},
+		}
+		expectReadiness(d, e2e.ReadinessFailed)
+
+		// the unstructured deployment is computed as the typed one
+		content, e := runtime.DefaultUnstructuredConverter.ToUnstructured(readyDeployment())
+		Expect(e).NotTo(HaveOccurred())
+		u := &unstructured.Unstructured{Object: content}
+		u.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("Deployment"))
+		Expect(unstructured.SetNestedField(u.Object, int64(1), "status", "updatedReplicas")).To(Succeed())
+		expectReadiness(u, e2e.ReadinessInProgress)
+	})
+
+	It("compute the readiness of the other workloads", func() {
+		ds := &appsv1.DaemonSet{Status: appsv1.DaemonSetStatus{
+			DesiredNumberScheduled: 3, CurrentNumberScheduled: 3, NumberReady: 3, NumberAvailable: 3, UpdatedNumberScheduled: 2,
+		}}
+		expectReadiness(ds, e2e.ReadinessInProgress)
+		ds.Status.UpdatedNumberScheduled = 3
+		expectReadiness(ds, e2e.ReadinessCurrent)
+
+		sts := &appsv1.StatefulSet{
+			Spec: appsv1.StatefulSetSpec{Replicas: ptr.To(int32(2))},
+			Status: appsv1.StatefulSetStatus{
+				Replicas: 2, ReadyReplicas: 2, UpdatedReplicas: 1, CurrentRevision: "v1", UpdateRevision: "v2",
+			},
+		}
+		expectReadiness(sts, e2e.ReadinessInProgress)
+		sts.Spec.UpdateStrategy.RollingUpdate = &appsv1.RollingUpdateStatefulSetStrategy{Partition: ptr.To(int32(1))}
+		expectReadiness(sts, e2e.ReadinessCurrent)
+
+		pod := &corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodRunning}}
+		expectReadiness(pod, e2e.ReadinessInProgress)
+		pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+		expectReadiness(pod, e2e.ReadinessCurrent)
+		pod.Status.Phase = corev1.PodFailed
+		expectReadiness(pod, e2e.ReadinessFailed)
+
+		expectReadiness(&corev1.PersistentVolumeClaim{Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending}}, e2e.ReadinessInProgress)
+		expectReadiness(&corev1.PersistentVolumeClaim{Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound}}, e2e.ReadinessCurrent)
+
+		expectReadiness(&corev1.Service{Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer}}, e2e.ReadinessInProgress)
+		expectReadiness(&corev1.Service{Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP}}, e2e.ReadinessCurrent)
+
+		crd := &apiextensions_v1.CustomResourceDefinition{}
+		expectReadiness(crd, e2e.ReadinessInProgress)
+		crd.Status.Conditions = []apiextensions_v1.CustomResourceDefinitionCondition{
+			{Type: apiextensions_v1.Established, Status: apiextensions_v1.ConditionTrue},
+		}
+		expectReadiness(crd, e2e.ReadinessCurrent)
+	})
+
+	It("compute the readiness of the custom resource", func() {
+		u := e2e.NewUnstructured(schema.GroupVersionKind{Group: "example.io", Version: "v1", Kind: "Foo"}, "foo", "default")
+		r, e := e2e.ComputeReadiness(u)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(r.Status).To(Equal(e2e.ReadinessCurrent))
+
+		u.SetGeneration(2)
+		Expect(unstructured.SetNestedField(u.Object, int64(1), "status", "observedGeneration")).To(Succeed())
+		expectReadiness(u, e2e.ReadinessInProgress)
+
+		Expect(unstructured.SetNestedField(u.Object, int64(2), "status", "observedGeneration")).To(Succeed())
+		Expect(unstructured.SetNestedSlice(u.Object, []interface{}{
+			map[string]interface{}{"type": "Ready", "status": "False", "message": "waiting"},
+		}, "status", "conditions")).To(Succeed())
+		expectReadiness(u, e2e.ReadinessInProgress)
+
+		Expect(unstructured.SetNestedSlice(u.Object, []interface{}{
+			map[string]interface{}{"type": "Stalled", "status": "True", "message": "bad spec"},
+		}, "status", "conditions")).To(Succeed())
+		expectReadiness(u, e2e.ReadinessFailed)
+	})
+
+	It("wait for the updated deployment to be ready", func() {
+		d := readyDeployment()
+		d.Status = appsv1.DeploymentStatus{}
+		Expect(f.CreateResourceContext(ctx, d)).To(Succeed())
+
+		go func() {
+			defer GinkgoRecover()
+			time.Sleep(time.Second)
+			latest := &appsv1.Deployment{}
+			Expect(f.GetResourceContext(ctx, client.ObjectKeyFromObject(d), latest)).To(Succeed())
+			latest.Status = readyDeployment().Status
+			Expect(f.UpdateResourceStatusContext(ctx, latest)).To(Succeed())
+		}()
+
+		obj := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: d.Name, Namespace: d.Namespace}}
+		Expect(f.WaitReady(ctx, obj)).To(Succeed())
+		Expect(obj.Status.AvailableReplicas).To(Equal(int32(2)))
+
+		failedPod := generateExamplePodYaml("failed-pod", "default", nil, corev1.PodFailed)
+		Expect(f.CreatePodContext(ctx, failedPod)).To(Succeed())
+		Expect(f.WaitReady(ctx, failedPod)).To(MatchError(e2e.ErrResFailed))
+
+		Expect(f.WaitReady(ctx, &corev1.Pod{})).To(MatchError(e2e.ErrWrongInput))
+	})
+})
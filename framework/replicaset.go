@@ -4,52 +4,31 @@ package framework
 
 import (
 	"context"
-	"fmt"
-	"time"
 
-	"github.com/spidernet-io/e2eframework/tools"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	api_errors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func (f *Framework) CreateReplicaSet(rs *appsv1.ReplicaSet, opts ...client.CreateOption) error {
-	// try to wait for finish last deleting
-	fake := &appsv1.ReplicaSet{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: rs.Namespace,
-			Name:      rs.Name,
-		},
-	}
-	key := client.ObjectKeyFromObject(fake)
-	existing := &appsv1.ReplicaSet{}
-	e := f.GetResource(key, existing)
-	if e == nil && existing.DeletionTimestamp == nil {
-		return fmt.Errorf("%w: replicaset '%s/%s'", ErrAlreadyExisted, existing.Namespace, existing.Name)
-	}
-	t := func() bool {
-		existing := &appsv1.ReplicaSet{}
-		e := f.GetResource(key, existing)
-		b := api_errors.IsNotFound(e)
-		if !b {
-			f.Log("waiting for a same ReplicaSet %v/%v to finish deleting \n", rs.Namespace, rs.Name)
-			return false
-		}
-		return true
-	}
-	if !tools.Eventually(t, f.Config.ResourceDeleteTimeout, time.Second) {
-		return ErrTimeOut
+	return f.CreateReplicaSetContext(context.Background(), rs, opts...)
+}
+
+func (f *Framework) CreateReplicaSetContext(ctx context.Context, rs *appsv1.ReplicaSet, opts ...client.CreateOption) error {
+	if rs == nil {
+		return ErrWrongInput
 	}
-	return f.CreateResource(rs, opts...)
+	return Create(ctx, f, rs, opts...)
 }
 
 func (f *Framework) DeleteReplicaSet(name, namespace string, opts ...client.DeleteOption) error {
+	return f.DeleteReplicaSetContext(context.Background(), name, namespace, opts...)
+}
+
+func (f *Framework) DeleteReplicaSetContext(ctx context.Context, name, namespace string, opts ...client.DeleteOption) error {
 
 	if name == "" || namespace == "" {
 		return ErrWrongInput
@@ -61,31 +40,25 @@ func (f *Framework) DeleteReplicaSet(name, namespace string, opts ...client.Dele
 			Name:      name,
 		},
 	}
-	return f.DeleteResource(pod, opts...)
+	return f.DeleteResourceContext(ctx, pod, opts...)
 }
 
 func (f *Framework) GetReplicaSet(name, namespace string) (*appsv1.ReplicaSet, error) {
+	return f.GetReplicaSetContext(context.Background(), name, namespace)
+}
 
+func (f *Framework) GetReplicaSetContext(ctx context.Context, name, namespace string) (*appsv1.ReplicaSet, error) {
 	if name == "" || namespace == "" {
 		return nil, ErrWrongInput
 	}
-
-	rs := &appsv1.ReplicaSet{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: namespace,
-			Name:      name,
-		},
-	}
-	key := client.ObjectKeyFromObject(rs)
-	existing := &appsv1.ReplicaSet{}
-	e := f.GetResource(key, existing)
-	if e != nil {
-		return nil, e
-	}
-	return existing, e
+	return Get[*appsv1.ReplicaSet](ctx, f, name, namespace)
 }
 
 func (f *Framework) GetReplicaSetPodList(rs *appsv1.ReplicaSet) (*corev1.PodList, error) {
+	return f.GetReplicaSetPodListContext(context.Background(), rs)
+}
+
+func (f *Framework) GetReplicaSetPodListContext(ctx context.Context, rs *appsv1.ReplicaSet) (*corev1.PodList, error) {
 
 	if rs == nil {
 		return nil, ErrWrongInput
@@ -97,7 +70,7 @@ func (f *Framework) GetReplicaSetPodList(rs *appsv1.ReplicaSet) (*corev1.PodList
 			Selector: labels.SelectorFromSet(rs.Spec.Selector.MatchLabels),
 		},
 	}
-	e := f.ListResource(pods, opts...)
+	e := f.ListResourceContext(ctx, pods, opts...)
 	if e != nil {
 		return nil, e
 	}
@@ -105,11 +78,15 @@ func (f *Framework) GetReplicaSetPodList(rs *appsv1.ReplicaSet) (*corev1.PodList
 }
 
 func (f *Framework) ScaleReplicaSet(rs *appsv1.ReplicaSet, replicas int32) (*appsv1.ReplicaSet, error) {
+	return f.ScaleReplicaSetContext(context.Background(), rs, replicas)
+}
+
+func (f *Framework) ScaleReplicaSetContext(ctx context.Context, rs *appsv1.ReplicaSet, replicas int32) (*appsv1.ReplicaSet, error) {
 	if rs == nil {
 		return nil, ErrWrongInput
 	}
 	rs.Spec.Replicas = ptr.To(replicas)
-	err := f.UpdateResource(rs)
+	err := f.UpdateResourceContext(ctx, rs)
 	if err != nil {
 		return nil, err
 	}
@@ -117,46 +94,13 @@ func (f *Framework) ScaleReplicaSet(rs *appsv1.ReplicaSet, replicas int32) (*app
 }
 
 func (f *Framework) WaitReplicaSetReady(name, namespace string, ctx context.Context) (*appsv1.ReplicaSet, error) {
+	return f.WaitReplicaSetReadyContext(ctx, name, namespace)
+}
 
+func (f *Framework) WaitReplicaSetReadyContext(ctx context.Context, name, namespace string) (*appsv1.ReplicaSet, error) {
 	if name == "" || namespace == "" {
 		return nil, ErrWrongInput
 	}
+	return waitReady(ctx, f, name, namespace, newObject[*appsv1.ReplicaSet])
 
-	l := &client.ListOptions{
-		Namespace:     namespace,
-		FieldSelector: fields.OneTermEqualSelector("metadata.name", name),
-	}
-	watchInterface, err := f.KClient.Watch(ctx, &appsv1.ReplicaSetList{}, l)
-	if err != nil {
-		return nil, ErrWatch
-	}
-	defer watchInterface.Stop()
-
-	for {
-		select {
-		case event, ok := <-watchInterface.ResultChan():
-			f.Log("ReplicaSet %v/%v\n", event, ok)
-			if !ok {
-				return nil, ErrChanelClosed
-			}
-			f.Log("ReplicaSet %v/%v %v event \n", namespace, name, event.Type)
-			switch event.Type {
-			case watch.Error:
-				return nil, ErrEvent
-			case watch.Deleted:
-				return nil, ErrResDel
-			default:
-				rs, ok := event.Object.(*appsv1.ReplicaSet)
-				if !ok {
-					return nil, ErrGetObj
-				}
-				f.Log("ReplicaSet %v/%v readyReplicas=%+v\n", namespace, name, rs.Status.ReadyReplicas)
-				if rs.Status.ReadyReplicas == *(rs.Spec.Replicas) {
-					return rs, nil
-				}
-			}
-		case <-ctx.Done():
-			return nil, ErrTimeOut
-		}
-	}
 }
@@ -53,8 +53,9 @@ func GenerateExampleReplicaSetYaml(rsName, namespace string, replica, readyRepli
 		},
 		//the fake clientset will not schedule replicaset,so mock the number
 		Status: appsv1.ReplicaSetStatus{
-			ReadyReplicas: readyReplica,
-			//	Replicas:      replica,
+			ReadyReplicas:     readyReplica,
+			AvailableReplicas: readyReplica,
+			Replicas:          replica,
 		},
 	}
 }
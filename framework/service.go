@@ -3,46 +3,42 @@
 package framework
 
 import (
-	"fmt"
+	"context"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func (f *Framework) CreateService(service *corev1.Service, opts ...client.CreateOption) error {
+	return f.CreateServiceContext(context.Background(), service, opts...)
+}
+
+func (f *Framework) CreateServiceContext(ctx context.Context, service *corev1.Service, opts ...client.CreateOption) error {
 	if service == nil {
 		return ErrWrongInput
 	}
-	// try to wait for finish last deleting
-	key := types.NamespacedName{
-		Name:      service.Name,
-		Namespace: service.Namespace,
-	}
-	existing := &corev1.Service{}
-	e := f.GetResource(key, existing)
-	if e == nil && existing.DeletionTimestamp == nil {
-		return fmt.Errorf("failed to create , a same service %v/%v exists", service.Namespace, service.Name)
-	}
-	return f.CreateResource(service, opts...)
+	return Create(ctx, f, service, opts...)
 }
 
 func (f *Framework) GetService(name, namespace string) (*corev1.Service, error) {
+	return f.GetServiceContext(context.Background(), name, namespace)
+}
+
+func (f *Framework) GetServiceContext(ctx context.Context, name, namespace string) (*corev1.Service, error) {
 	if name == "" || namespace == "" {
 		return nil, ErrWrongInput
 	}
-	key := types.NamespacedName{
-		Name:      name,
-		Namespace: namespace,
-	}
-	service := &corev1.Service{}
-	return service, f.GetResource(key, service)
+	return Get[*corev1.Service](ctx, f, name, namespace)
 }
 
 func (f *Framework) ListService(options ...client.ListOption) (*corev1.ServiceList, error) {
+	return f.ListServiceContext(context.Background(), options...)
+}
+
+func (f *Framework) ListServiceContext(ctx context.Context, options ...client.ListOption) (*corev1.ServiceList, error) {
 	services := &corev1.ServiceList{}
-	err := f.ListResource(services, options...)
+	err := f.ListResourceContext(ctx, services, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -50,6 +46,10 @@ func (f *Framework) ListService(options ...client.ListOption) (*corev1.ServiceLi
 }
 
 func (f *Framework) DeleteService(name, namespace string, opts ...client.DeleteOption) error {
+	return f.DeleteServiceContext(context.Background(), name, namespace, opts...)
+}
+
+func (f *Framework) DeleteServiceContext(ctx context.Context, name, namespace string, opts ...client.DeleteOption) error {
 
 	if name == "" || namespace == "" {
 		return ErrWrongInput
@@ -61,5 +61,5 @@ func (f *Framework) DeleteService(name, namespace string, opts ...client.DeleteO
 			Name:      name,
 		},
 	}
-	return f.DeleteResource(service, opts...)
+	return f.DeleteResourceContext(ctx, service, opts...)
 }
@@ -10,6 +10,10 @@ import (
 )
 
 func (f *Framework) GetServiceAccount(saName, namespace string) (*corev1.ServiceAccount, error) {
+	return f.GetServiceAccountContext(context.Background(), saName, namespace)
+}
+
+func (f *Framework) GetServiceAccountContext(ctx context.Context, saName, namespace string) (*corev1.ServiceAccount, error) {
 	if saName == "" || namespace == "" {
 		return nil, ErrWrongInput
 	}
@@ -19,7 +23,7 @@ func (f *Framework) GetServiceAccount(saName, namespace string) (*corev1.Service
 		Name:      saName,
 	}
 	existing := &corev1.ServiceAccount{}
-	e := f.GetResource(key, existing)
+	e := f.GetResourceContext(ctx, key, existing)
 	if e != nil {
 		return nil, e
 	}
@@ -27,22 +31,18 @@ func (f *Framework) GetServiceAccount(saName, namespace string) (*corev1.Service
 }
 
 func (f *Framework) WaitServiceAccountReady(saName, namespace string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return f.WaitServiceAccountReadyContext(ctx, saName, namespace)
+}
+
+func (f *Framework) WaitServiceAccountReadyContext(ctx context.Context, saName, namespace string) error {
 	if saName == "" || namespace == "" {
 		return ErrWrongInput
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-	for {
-		select {
-		default:
-			as, _ := f.GetServiceAccount(saName, namespace)
-			if as != nil {
-				return nil
-			}
-			time.Sleep(time.Second)
-		case <-ctx.Done():
-			return ErrTimeOut
-		}
-	}
+	_, err := waitFor(ctx, f, client.ObjectKey{Namespace: namespace, Name: saName}, newObject[*corev1.ServiceAccount], func(*corev1.ServiceAccount) bool {
+		return true
+	})
+	return err
 }
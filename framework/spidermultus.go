@@ -3,7 +3,7 @@
 package framework
 
 import (
-	"fmt"
+	"context"
 
 	spiderv2beta1 "github.com/spidernet-io/spiderpool/pkg/k8s/apis/spiderpool.spidernet.io/v2beta1"
 
@@ -12,24 +12,20 @@ import (
 )
 
 func (f *Framework) GetSpiderMultusInstance(namespace, name string) (*spiderv2beta1.SpiderMultusConfig, error) {
-	obj := &spiderv2beta1.SpiderMultusConfig{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      name,
-			Namespace: namespace,
-		},
-	}
+	return f.GetSpiderMultusInstanceContext(context.Background(), namespace, name)
+}
 
-	key := client.ObjectKeyFromObject(obj)
-	nad := &spiderv2beta1.SpiderMultusConfig{}
-	if err := f.GetResource(key, nad); err != nil {
-		return nil, err
-	}
-	return nad, nil
+func (f *Framework) GetSpiderMultusInstanceContext(ctx context.Context, namespace, name string) (*spiderv2beta1.SpiderMultusConfig, error) {
+	return Get[*spiderv2beta1.SpiderMultusConfig](ctx, f, name, namespace)
 }
 
 func (f *Framework) ListSpiderMultusInstances(opts ...client.ListOption) (*spiderv2beta1.SpiderMultusConfigList, error) {
+	return f.ListSpiderMultusInstancesContext(context.Background(), opts...)
+}
+
+func (f *Framework) ListSpiderMultusInstancesContext(ctx context.Context, opts ...client.ListOption) (*spiderv2beta1.SpiderMultusConfigList, error) {
 	nads := &spiderv2beta1.SpiderMultusConfigList{}
-	if err := f.ListResource(nads, opts...); err != nil {
+	if err := f.ListResourceContext(ctx, nads, opts...); err != nil {
 		return nil, err
 	}
 
@@ -37,15 +33,22 @@ func (f *Framework) ListSpiderMultusInstances(opts ...client.ListOption) (*spide
 }
 
 func (f *Framework) CreateSpiderMultusInstance(nad *spiderv2beta1.SpiderMultusConfig, opts ...client.CreateOption) error {
-	exist, err := f.GetMultusInstance(nad.Namespace, nad.Name)
-	if err == nil && exist.DeletionTimestamp == nil {
-		return fmt.Errorf("failed to create %s/%s, instance has exists", nad.Namespace, nad.Name)
+	return f.CreateSpiderMultusInstanceContext(context.Background(), nad, opts...)
+}
+
+func (f *Framework) CreateSpiderMultusInstanceContext(ctx context.Context, nad *spiderv2beta1.SpiderMultusConfig, opts ...client.CreateOption) error {
+	if nad == nil {
+		return ErrWrongInput
 	}
-	return f.CreateResource(nad, opts...)
+	return Create(ctx, f, nad, opts...)
 }
 
 func (f *Framework) DeleteSpiderMultusInstance(namespace, name string) error {
-	return f.DeleteResource(&spiderv2beta1.SpiderMultusConfig{
+	return f.DeleteSpiderMultusInstanceContext(context.Background(), namespace, name)
+}
+
+func (f *Framework) DeleteSpiderMultusInstanceContext(ctx context.Context, namespace, name string) error {
+	return f.DeleteResourceContext(ctx, &spiderv2beta1.SpiderMultusConfig{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      name,
 			Namespace: namespace,
@@ -0,0 +1,209 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	api_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+)
+
+// each spec gets its own namespace, which is deleted when the spec finishes, like:
+//   BeforeEach(func() {
+//       ns, err := f.CreateTestNamespace(context.TODO(), nil)
+//   })
+// then f.Namespace is the namespace of the current spec
+
+const (
+	// E2E_RUN_ID identifies one run of the whole suite, it is random by default
+	E2E_RUN_ID = "E2E_RUN_ID"
+
+	TestNamespaceLabelRunID   = "e2eframework.spidernet.io/run-id"
+	TestNamespaceLabelSpec    = "e2eframework.spidernet.io/spec"
+	TestNamespaceLabelProcess = "e2eframework.spidernet.io/parallel-process"
+
+	PodSecurityEnforceLabel = "pod-security.kubernetes.io/enforce"
+
+	TestNamespaceQuotaName = "e2e-quota"
+
+	defaultTestNamespacePrefix = "e2e"
+)
+
+// RunID is shared by all the parallel processes when E2E_RUN_ID is set
+var RunID = func() string {
+	if v := os.Getenv(E2E_RUN_ID); len(v) > 0 {
+		return sanitizeLabelValue(v)
+	}
+	return utilrand.String(6)
+}()
+
+var (
+	invalidDNSChar        = regexp.MustCompile(`[^a-z0-9-]+`)
+	invalidLabelValueChar = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+// NamespaceOptions customizes the namespace of CreateTestNamespace
+type NamespaceOptions struct {
+	// prefix of the namespace name, default "e2e"
+	Prefix string
+	// extra labels of the namespace
+	Labels map[string]string
+	// PodSecurityLevel sets the label pod-security.kubernetes.io/enforce, like "privileged", "baseline" or "restricted"
+	PodSecurityLevel string
+	// Quota creates the ResourceQuota TestNamespaceQuotaName in the namespace
+	Quota *corev1.ResourceQuotaSpec
+}
+
+// the optional methods of TestingT, implemented by GinkgoT()
+type namedT interface {
+	Name() string
+}
+
+type parallelT interface {
+	ParallelProcess() int
+}
+
+// TestNamespaceName returns a DNS-1123 label like "e2e-<run id>-p<process>-<random>",
+// which does not collide across the parallel processes
+func (f *Framework) TestNamespaceName(prefix string) string {
+	prefix = strings.Trim(invalidDNSChar.ReplaceAllString(strings.ToLower(prefix), "-"), "-")
+	if prefix == "" {
+		prefix = defaultTestNamespacePrefix
+	}
+	runID := strings.Trim(invalidDNSChar.ReplaceAllString(strings.ToLower(RunID), "-"), "-")
+	if len(runID) > 16 {
+		runID = runID[:16]
+	}
+	suffix := fmt.Sprintf("-%s-p%d-%s", runID, f.parallelProcess(), utilrand.String(5))
+	if limit := 63 - len(suffix); len(prefix) > limit {
+		prefix = strings.TrimRight(prefix[:limit], "-")
+	}
+	return prefix + suffix
+}
+
+// CreateTestNamespace creates a unique namespace for the current spec, waits for the default ServiceAccount,
+// and sets it as f.Namespace. It is deleted when the spec finishes if f.t is CleanupT, like GinkgoT(),
+// or by DeleteTestNamespace. For a failed spec, it is dumped into Config.ArtifactsDir before deleting
+func (f *Framework) CreateTestNamespace(ctx context.Context, opts *NamespaceOptions) (string, error) {
+	if opts == nil {
+		opts = &NamespaceOptions{}
+	}
+
+	labels := map[string]string{}
+	for k, v := range opts.Labels {
+		labels[k] = v
+	}
+	labels[TestNamespaceLabelRunID] = RunID
+	labels[TestNamespaceLabelProcess] = strconv.Itoa(f.parallelProcess())
+	if t, ok := f.t.(namedT); ok {
+		if v := sanitizeLabelValue(t.Name()); v != "" {
+			labels[TestNamespaceLabelSpec] = v
+		}
+	}
+	if opts.PodSecurityLevel != "" {
+		labels[PodSecurityEnforceLabel] = opts.PodSecurityLevel
+	}
+
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   f.TestNamespaceName(opts.Prefix),
+			Labels: labels,
+		},
+	}
+	if err := f.CreateResourceContext(ctx, ns); err != nil {
+		return "", err
+	}
+	f.Log("created test namespace %v \n", ns.Name)
+	if t, ok := f.t.(CleanupT); ok {
+		name := ns.Name
+		t.Cleanup(func() {
+			f.dumpOnFailure(name)
+			if err := f.deleteTestNamespace(context.Background(), name); err != nil {
+				t.Errorf("failed to delete test namespace %v: %v", name, err)
+			}
+		})
+	}
+	f.Namespace = ns.Name
+
+	if opts.Quota != nil {
+		quota := &corev1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      TestNamespaceQuotaName,
+				Namespace: ns.Name,
+			},
+			Spec: *opts.Quota,
+		}
+		if err := f.CreateResourceContext(ctx, quota); err != nil {
+			return ns.Name, err
+		}
+		f.Untrack(quota)
+	}
+
+	if err := f.WaitServiceAccountReadyContext(ctx, "default", ns.Name); err != nil {
+		return ns.Name, err
+	}
+	return ns.Name, nil
+}
+
+// DeleteTestNamespace deletes f.Namespace and waits until it is gone
+func (f *Framework) DeleteTestNamespace(ctx context.Context) error {
+	if f.Namespace == "" {
+		return nil
+	}
+	return f.deleteTestNamespace(ctx, f.Namespace)
+}
+
+func (f *Framework) deleteTestNamespace(ctx context.Context, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, f.Config.ResourceDeleteTimeout)
+	defer cancel()
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if err := deleteAndWait(ctx, f, ns); err != nil && !api_errors.IsNotFound(err) {
+		return err
+	}
+	f.Untrack(ns)
+	if f.Namespace == name {
+		f.Namespace = ""
+	}
+	return nil
+}
+
+// dumpOnFailure dumps the namespace into Config.ArtifactsDir when the spec fails
+func (f *Framework) dumpOnFailure(namespace string) {
+	t, ok := f.t.(failedT)
+	if !ok || !t.Failed() || f.Config.ArtifactsDir == "" {
+		return
+	}
+	spec := namespace
+	if n, ok := f.t.(namedT); ok {
+		spec = n.Name()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), f.Config.ResourceDeleteTimeout)
+	defer cancel()
+	if err := f.DumpDiagnostics(ctx, f.ArtifactsDirFor(spec), namespace); err != nil {
+		f.Log("failed to dump the diagnostics: %v \n", err)
+	}
+}
+
+func (f *Framework) parallelProcess() int {
+	if t, ok := f.t.(parallelT); ok && t.ParallelProcess() > 0 {
+		return t.ParallelProcess()
+	}
+	return 1
+}
+
+// sanitizeLabelValue converts s to a valid label value, like the spec text
+func sanitizeLabelValue(s string) string {
+	s = invalidLabelValueChar.ReplaceAllString(s, "_")
+	if len(s) > 63 {
+		s = s[:63]
+	}
+	return strings.Trim(s, "_.-")
+}
@@ -0,0 +1,109 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework_test
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	e2e "github.com/spidernet-io/e2eframework/framework"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("test managed namespace", Label("testnamespace"), func() {
+	var f *e2e.Framework
+	var ctx context.Context
+
+	BeforeEach(func() {
+		f = fakeFramework()
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 20*time.Second)
+		DeferCleanup(cancel)
+
+		// simulate the controller creating the default ServiceAccount
+		go func() {
+			defer GinkgoRecover()
+			for ctx.Err() == nil {
+				nsList := &corev1.NamespaceList{}
+				Expect(f.ListResource(nsList, client.HasLabels{e2e.TestNamespaceLabelRunID})).To(Succeed())
+				for _, ns := range nsList.Items {
+					if _, e := f.GetServiceAccount("default", ns.Name); e != nil {
+						_ = CreateServiceAccount(f, GenerateExampleServiceAccountObj("default", ns.Name))
+					}
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+		}()
+	})
+
+	It("generate unique dns-1123 names", func() {
+		names := map[string]bool{}
+		for i := 0; i < 100; i++ {
+			name := f.TestNamespaceName("My_Test.Suite")
+			Expect(validation.IsDNS1123Label(name)).To(BeEmpty(), name)
+			Expect(name).To(HavePrefix("my-test-suite-"))
+			names[name] = true
+		}
+		Expect(names).To(HaveLen(100))
+
+		name := f.TestNamespaceName(strings.Repeat("a", 100))
+		Expect(validation.IsDNS1123Label(name)).To(BeEmpty(), name)
+		Expect(f.TestNamespaceName("")).To(HavePrefix("e2e-"))
+	})
+
+	It("create and delete the test namespace", func() {
+		name, e := f.CreateTestNamespace(ctx, &e2e.NamespaceOptions{
+			Labels:           map[string]string{"app": "e2e"},
+			PodSecurityLevel: "privileged",
+			Quota: &corev1.ResourceQuotaSpec{
+				Hard: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("10")},
+			},
+		})
+		Expect(e).NotTo(HaveOccurred())
+		Expect(f.Namespace).To(Equal(name))
+
+		ns, e := f.GetNamespace(name)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(ns.Labels).To(HaveKeyWithValue(e2e.TestNamespaceLabelRunID, e2e.RunID))
+		Expect(ns.Labels).To(HaveKeyWithValue(e2e.TestNamespaceLabelProcess, "1"))
+		Expect(ns.Labels).To(HaveKeyWithValue(e2e.PodSecurityEnforceLabel, "privileged"))
+		Expect(ns.Labels).To(HaveKeyWithValue("app", "e2e"))
+		Expect(ns.Labels[e2e.TestNamespaceLabelSpec]).To(ContainSubstring("create_and_delete_the_test_namespace"))
+
+		quota := &corev1.ResourceQuota{}
+		Expect(f.GetResource(client.ObjectKey{Namespace: name, Name: e2e.TestNamespaceQuotaName}, quota)).To(Succeed())
+
+		Expect(f.DeleteTestNamespace(ctx)).To(Succeed())
+		Expect(f.Namespace).To(BeEmpty())
+		_, e = f.GetNamespace(name)
+		Expect(e).To(HaveOccurred())
+		for _, obj := range f.TrackedObjects() {
+			Expect(obj.GetName()).NotTo(Equal(name))
+		}
+	})
+
+	Context("delete the namespace when the spec finishes", Ordered, func() {
+		var name string
+		var ff *e2e.Framework
+
+		It("create the namespace", func() {
+			var e error
+			ff = f
+			name, e = f.CreateTestNamespace(ctx, nil)
+			Expect(e).NotTo(HaveOccurred())
+		})
+
+		It("the namespace is gone", func() {
+			_, e := ff.GetNamespace(name)
+			Expect(e).To(HaveOccurred())
+			Expect(ff.Namespace).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,155 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// every object created by CreateResource is tracked, and Cleanup deletes them, like:
+//   f := NewFramework(GinkgoT(), nil)
+//   DeferCleanup(f.Cleanup)
+// or for the go test:
+//   f.RegisterCleanup(t)
+
+// CleanupT is implemented by *testing.T and GinkgoT()
+type CleanupT interface {
+	Cleanup(func())
+	Errorf(format string, args ...interface{})
+}
+
+// the kinds deleted after all the other objects, for the objects in them may depend on them
+var cleanupLastKinds = map[string]bool{
+	"namespace":                true,
+	"customresourcedefinition": true,
+}
+
+type objectTracker struct {
+	lock    sync.Mutex
+	objects []client.Object
+}
+
+// track records a copy of obj, the re-created object moves to the end
+func (f *Framework) track(obj client.Object) {
+	if f.tracker == nil {
+		return
+	}
+	kind := f.kindOf(obj)
+	key := client.ObjectKeyFromObject(obj)
+	copied, ok := obj.DeepCopyObject().(client.Object)
+	if !ok {
+		return
+	}
+
+	f.tracker.lock.Lock()
+	defer f.tracker.lock.Unlock()
+	for n, o := range f.tracker.objects {
+		if f.kindOf(o) == kind && client.ObjectKeyFromObject(o) == key {
+			f.tracker.objects = append(f.tracker.objects[:n], f.tracker.objects[n+1:]...)
+			break
+		}
+	}
+	f.tracker.objects = append(f.tracker.objects, copied)
+}
+
+// TrackedObjects returns the objects waiting for Cleanup, in the order of creating
+func (f *Framework) TrackedObjects() []client.Object {
+	if f.tracker == nil {
+		return nil
+	}
+	f.tracker.lock.Lock()
+	defer f.tracker.lock.Unlock()
+	return append([]client.Object{}, f.tracker.objects...)
+}
+
+// Untrack stops tracking obj, so Cleanup keeps it
+func (f *Framework) Untrack(obj client.Object) {
+	if f.tracker == nil || obj == nil {
+		return
+	}
+	kind := f.kindOf(obj)
+	key := client.ObjectKeyFromObject(obj)
+
+	f.tracker.lock.Lock()
+	defer f.tracker.lock.Unlock()
+	for n, o := range f.tracker.objects {
+		if f.kindOf(o) == kind && client.ObjectKeyFromObject(o) == key {
+			f.tracker.objects = append(f.tracker.objects[:n], f.tracker.objects[n+1:]...)
+			return
+		}
+	}
+}
+
+// Cleanup deletes the tracked objects in the reverse order of creating, with the namespace and CRD at last.
+// It waits for each object to be gone, at most Config.ResourceDeleteTimeout,
+// and returns the objects which would not go away, they are kept tracked for the next Cleanup
+func (f *Framework) Cleanup(ctx context.Context) error {
+	if f.tracker == nil {
+		return nil
+	}
+	f.tracker.lock.Lock()
+	objects := f.tracker.objects
+	f.tracker.objects = nil
+	f.tracker.lock.Unlock()
+
+	ordered := make([]client.Object, 0, len(objects))
+	last := []client.Object{}
+	for n := len(objects) - 1; n >= 0; n-- {
+		if cleanupLastKinds[f.kindOf(objects[n])] {
+			last = append(last, objects[n])
+			continue
+		}
+		ordered = append(ordered, objects[n])
+	}
+	ordered = append(ordered, last...)
+
+	var errs []error
+	leaked := []client.Object{}
+	for _, obj := range ordered {
+		if err := f.cleanupObject(ctx, obj); err != nil {
+			errs = append(errs, fmt.Errorf("failed to clean %s '%s': %w", f.kindOf(obj), keyString(client.ObjectKeyFromObject(obj)), err))
+			leaked = append(leaked, obj)
+		}
+	}
+	if len(leaked) == 0 {
+		return nil
+	}
+
+	// leaked is in the order of deleting, so it is reversed to keep the order of creating
+	slices.Reverse(leaked)
+	f.tracker.lock.Lock()
+	f.tracker.objects = append(leaked, f.tracker.objects...)
+	f.tracker.lock.Unlock()
+	err := errors.Join(errs...)
+	f.Log("%v \n", err)
+	return err
+}
+
+func (f *Framework) cleanupObject(ctx context.Context, obj client.Object) error {
+	ctx, cancel := context.WithTimeout(ctx, f.Config.ResourceDeleteTimeout)
+	defer cancel()
+	// the copy may carry a stale resourceVersion and uid
+	target := newObjectLike(obj)
+	target.SetName(obj.GetName())
+	target.SetNamespace(obj.GetNamespace())
+	return deleteAndWait(ctx, f, target, client.PropagationPolicy(metav1.DeletePropagationBackground))
+}
+
+// RegisterCleanup calls Cleanup when the test t finishes, and fails t for the objects which would not go away
+func (f *Framework) RegisterCleanup(t CleanupT) {
+	if t == nil {
+		return
+	}
+	t.Cleanup(func() {
+		if err := f.Cleanup(context.Background()); err != nil {
+			t.Errorf("%v", err)
+		}
+	})
+}
@@ -0,0 +1,149 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	e2e "github.com/spidernet-io/e2eframework/framework"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+type fakeCleanupT struct {
+	cleanups []func()
+	errs     []string
+}
+
+func (t *fakeCleanupT) Cleanup(f func()) {
+	t.cleanups = append(t.cleanups, f)
+}
+
+func (t *fakeCleanupT) Errorf(format string, args ...interface{}) {
+	t.errs = append(t.errs, fmt.Sprintf(format, args...))
+}
+
+var _ = Describe("test object tracker", Label("tracker"), func() {
+	var f *e2e.Framework
+	var ctx context.Context
+
+	BeforeEach(func() {
+		f = fakeFramework()
+		f.Config.ResourceDeleteTimeout = 3 * time.Second
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 20*time.Second)
+		DeferCleanup(cancel)
+	})
+
+	It("clean the created objects in reverse order", func() {
+		Expect(f.CreateNamespace("ns-tracker")).To(Succeed())
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm-tracker", Namespace: "ns-tracker"}}
+		Expect(f.CreateConfigmap(cm)).To(Succeed())
+		pod := generateExamplePodYaml("pod-tracker", "ns-tracker", nil, corev1.PodRunning)
+		Expect(f.CreatePod(pod)).To(Succeed())
+		dryRun := generateExamplePodYaml("pod-dryrun", "ns-tracker", nil, corev1.PodRunning)
+		Expect(f.CreateResource(dryRun, client.DryRunAll)).To(Succeed())
+
+		names := []string{}
+		for _, obj := range f.TrackedObjects() {
+			names = append(names, obj.GetName())
+		}
+		Expect(names).To(Equal([]string{"ns-tracker", "cm-tracker", "pod-tracker"}))
+
+		// re-created object moves to the end
+		Expect(f.DeleteConfigmap(cm.Name, cm.Namespace)).To(Succeed())
+		cm = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm-tracker", Namespace: "ns-tracker"}}
+		Expect(f.CreateConfigmap(cm)).To(Succeed())
+		Expect(f.TrackedObjects()).To(HaveLen(3))
+		Expect(f.TrackedObjects()[2].GetName()).To(Equal("cm-tracker"))
+
+		Expect(f.Cleanup(ctx)).To(Succeed())
+		Expect(f.TrackedObjects()).To(BeEmpty())
+		_, e := f.GetPod("pod-tracker", "ns-tracker")
+		Expect(e).To(HaveOccurred())
+		_, e = f.GetConfigmap("cm-tracker", "ns-tracker")
+		Expect(e).To(HaveOccurred())
+		_, e = f.GetNamespace("ns-tracker")
+		Expect(e).To(HaveOccurred())
+	})
+
+	It("report the object which would not go away", func() {
+		pod := generateExamplePodYaml("pod-stuck", "default", nil, corev1.PodRunning)
+		pod.Finalizers = []string{"e2e.spidernet.io/test"}
+		Expect(f.CreatePod(pod)).To(Succeed())
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm-gone", Namespace: "default"}}
+		Expect(f.CreateConfigmap(cm)).To(Succeed())
+		f.Untrack(cm)
+		Expect(f.TrackedObjects()).To(HaveLen(1))
+
+		t := &fakeCleanupT{}
+		f.RegisterCleanup(t)
+		Expect(t.cleanups).To(HaveLen(1))
+		t.cleanups[0]()
+		Expect(t.errs).To(HaveLen(1))
+		Expect(t.errs[0]).To(ContainSubstring("default/pod-stuck"))
+		Expect(f.TrackedObjects()).To(HaveLen(1))
+
+		_, e := f.GetConfigmap("cm-gone", "default")
+		Expect(e).NotTo(HaveOccurred())
+
+		p, e := f.GetPod("pod-stuck", "default")
+		Expect(e).NotTo(HaveOccurred())
+		p.Finalizers = nil
+		Expect(f.UpdateResource(p)).To(Succeed())
+		Expect(f.Cleanup(ctx)).To(Succeed())
+		Expect(f.TrackedObjects()).To(BeEmpty())
+	})
+
+	It("delete the leaked objects in reverse order by the next cleanup", func() {
+		lock := sync.Mutex{}
+		deleted := []string{}
+		f.KClient = interceptor.NewClient(f.KClient, interceptor.Funcs{
+			Delete: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.DeleteOption) error {
+				lock.Lock()
+				deleted = append(deleted, obj.GetName())
+				lock.Unlock()
+				return c.Delete(ctx, obj, opts...)
+			},
+		})
+		f.Config.ResourceDeleteTimeout = time.Second
+
+		// the pod depends on the configmap, both would not go away
+		for _, obj := range []client.Object{
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm-leak", Namespace: "default", Finalizers: []string{"e2e.spidernet.io/test"}}},
+			&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-leak", Namespace: "default", Finalizers: []string{"e2e.spidernet.io/test"}}},
+		} {
+			Expect(f.CreateResourceContext(ctx, obj)).To(Succeed())
+		}
+		Expect(f.Cleanup(ctx)).NotTo(Succeed())
+		names := []string{}
+		for _, obj := range f.TrackedObjects() {
+			names = append(names, obj.GetName())
+		}
+		Expect(names).To(Equal([]string{"cm-leak", "pod-leak"}))
+
+		lock.Lock()
+		deleted = nil
+		lock.Unlock()
+		Expect(f.Cleanup(ctx)).NotTo(Succeed())
+		lock.Lock()
+		Expect(deleted).To(Equal([]string{"pod-leak", "cm-leak"}))
+		lock.Unlock()
+
+		for _, obj := range f.TrackedObjects() {
+			Expect(f.GetResourceContext(ctx, client.ObjectKeyFromObject(obj), obj)).To(Succeed())
+			obj.SetFinalizers(nil)
+			Expect(f.UpdateResourceContext(ctx, obj)).To(Succeed())
+		}
+		Expect(f.Cleanup(ctx)).To(Succeed())
+		Expect(f.TrackedObjects()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,232 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// the unstructured operations work for the CRD which is not registered in the scheme, like whereabouts or calico.
+// The GVK is resolved by the RESTMapper of KClient, which discovers the api of the cluster, like:
+//   gvk, err := f.KindFor("ippools.whereabouts.cni.cncf.io")
+//   pool, err := f.WaitUnstructured(ctx, gvk, name, namespace, framework.FieldExists(".spec.range"))
+
+// FieldPredicate checks the fields of an unstructured object
+type FieldPredicate func(obj *unstructured.Unstructured) (bool, error)
+
+// KindFor resolves a resource like "ippools", "ippools.whereabouts.cni.cncf.io"
+// or "ippools.v1alpha1.whereabouts.cni.cncf.io" to the GVK served by the cluster
+func (f *Framework) KindFor(resource string) (schema.GroupVersionKind, error) {
+	if resource == "" {
+		return schema.GroupVersionKind{}, ErrWrongInput
+	}
+	gvr, gr := schema.ParseResourceArg(strings.ToLower(resource))
+	if gvr != nil {
+		if gvk, err := f.KClient.RESTMapper().KindFor(*gvr); err == nil {
+			return gvk, nil
+		}
+	}
+	return f.KClient.RESTMapper().KindFor(gr.WithVersion(""))
+}
+
+// RESTMappingFor returns the resource and scope of gvk served by the cluster
+func (f *Framework) RESTMappingFor(gvk schema.GroupVersionKind) (*meta.RESTMapping, error) {
+	if gvk.Kind == "" || gvk.Version == "" {
+		return nil, ErrWrongInput
+	}
+	return f.KClient.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+}
+
+// NewUnstructured returns an unstructured object of gvk, namespace is empty for the cluster scope object
+func NewUnstructured(gvk schema.GroupVersionKind, name, namespace string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetName(name)
+	obj.SetNamespace(namespace)
+	return obj
+}
+
+// checkUnstructured makes sure gvk is served by the cluster, and the namespace matches the scope
+func (f *Framework) checkUnstructured(gvk schema.GroupVersionKind, namespace string) error {
+	mapping, err := f.RESTMappingFor(gvk)
+	if err != nil {
+		return err
+	}
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace && namespace == "" {
+		return fmt.Errorf("%w: %v is namespaced, but namespace is empty", ErrWrongInput, gvk.Kind)
+	}
+	if mapping.Scope.Name() == meta.RESTScopeNameRoot && namespace != "" {
+		return fmt.Errorf("%w: %v is cluster scoped, but namespace is %v", ErrWrongInput, gvk.Kind, namespace)
+	}
+	return nil
+}
+
+// CreateUnstructured waits for a same-named object to finish deleting, then creates obj
+func (f *Framework) CreateUnstructured(ctx context.Context, obj *unstructured.Unstructured, opts ...client.CreateOption) error {
+	if obj == nil || obj.GetName() == "" {
+		return ErrWrongInput
+	}
+	if err := f.checkUnstructured(obj.GroupVersionKind(), obj.GetNamespace()); err != nil {
+		return err
+	}
+	return Create(ctx, f, obj, opts...)
+}
+
+func (f *Framework) GetUnstructured(ctx context.Context, gvk schema.GroupVersionKind, name, namespace string) (*unstructured.Unstructured, error) {
+	if name == "" {
+		return nil, ErrWrongInput
+	}
+	if err := f.checkUnstructured(gvk, namespace); err != nil {
+		return nil, err
+	}
+	obj := NewUnstructured(gvk, "", "")
+	if err := f.GetResourceContext(ctx, client.ObjectKey{Namespace: namespace, Name: name}, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// ListUnstructured lists the objects of gvk, the Kind of gvk is not the list kind
+func (f *Framework) ListUnstructured(ctx context.Context, gvk schema.GroupVersionKind, opts ...client.ListOption) (*unstructured.UnstructuredList, error) {
+	if gvk.Kind == "" || gvk.Version == "" {
+		return nil, ErrWrongInput
+	}
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := f.ListResourceContext(ctx, list, opts...); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (f *Framework) UpdateUnstructured(ctx context.Context, obj *unstructured.Unstructured, opts ...client.UpdateOption) error {
+	if obj == nil || obj.GetName() == "" {
+		return ErrWrongInput
+	}
+	return f.UpdateResourceContext(ctx, obj, opts...)
+}
+
+func (f *Framework) DeleteUnstructured(ctx context.Context, gvk schema.GroupVersionKind, name, namespace string, opts ...client.DeleteOption) error {
+	if name == "" {
+		return ErrWrongInput
+	}
+	if err := f.checkUnstructured(gvk, namespace); err != nil {
+		return err
+	}
+	return f.DeleteResourceContext(ctx, NewUnstructured(gvk, name, namespace), opts...)
+}
+
+// DeleteUnstructuredAndWait deletes the object, and waits until it is gone or ctx is done.
+// It succeeds when the object does not exist
+func (f *Framework) DeleteUnstructuredAndWait(ctx context.Context, gvk schema.GroupVersionKind, name, namespace string, opts ...client.DeleteOption) error {
+	if name == "" {
+		return ErrWrongInput
+	}
+	if err := f.checkUnstructured(gvk, namespace); err != nil {
+		return err
+	}
+	return deleteAndWait(ctx, f, NewUnstructured(gvk, name, namespace), opts...)
+}
+
+// WatchUnstructured watches the objects of gvk until ctx is done or Stop() is called
+func (f *Framework) WatchUnstructured(ctx context.Context, gvk schema.GroupVersionKind, opts ...client.ListOption) (watch.Interface, error) {
+	if gvk.Kind == "" || gvk.Version == "" {
+		return nil, ErrWrongInput
+	}
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	return f.KClient.Watch(ctx, list, opts...)
+}
+
+// WaitUnstructured waits until all preds are true for the object, or ctx is done.
+// A not-existed object is waited for, rather than failed, but a predicate error fails at once
+func (f *Framework) WaitUnstructured(ctx context.Context, gvk schema.GroupVersionKind, name, namespace string, preds ...FieldPredicate) (*unstructured.Unstructured, error) {
+	if name == "" {
+		return nil, ErrWrongInput
+	}
+	if err := f.checkUnstructured(gvk, namespace); err != nil {
+		return nil, err
+	}
+	newObj := func() *unstructured.Unstructured {
+		return NewUnstructured(gvk, "", "")
+	}
+	return waitObject(ctx, f, client.ObjectKey{Namespace: namespace, Name: name}, newObj, "", func(obj *unstructured.Unstructured, found bool) (bool, error) {
+		if !found {
+			return false, nil
+		}
+		for _, pred := range preds {
+			// stop waiting on the predicate error, like an invalid path
+			if ok, err := pred(obj); !ok || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	})
+}
+
+// GetField returns the values of the JSONPath like ".status.phase" or "{.status.conditions[*].type}".
+// It returns empty for the missing field
+func GetField(obj *unstructured.Unstructured, path string) ([]interface{}, error) {
+	if obj == nil || path == "" {
+		return nil, ErrWrongInput
+	}
+	if !strings.HasPrefix(path, "{") {
+		path = "{" + path + "}"
+	}
+	j := jsonpath.New("field").AllowMissingKeys(true)
+	if err := j.Parse(path); err != nil {
+		return nil, fmt.Errorf("%w: invalid field path %v: %v", ErrWrongInput, path, err)
+	}
+	results, err := j.FindResults(obj.Object)
+	if err != nil {
+		return nil, err
+	}
+	values := []interface{}{}
+	for _, r := range results {
+		for _, v := range r {
+			values = append(values, v.Interface())
+		}
+	}
+	return values, nil
+}
+
+// FieldExists is true when the field of path exists
+func FieldExists(path string) FieldPredicate {
+	return func(obj *unstructured.Unstructured) (bool, error) {
+		values, err := GetField(obj, path)
+		if err != nil {
+			return false, err
+		}
+		return len(values) > 0, nil
+	}
+}
+
+// FieldEquals is true when any value of path equals value, compared in the string format
+func FieldEquals(path string, value interface{}) FieldPredicate {
+	return func(obj *unstructured.Unstructured) (bool, error) {
+		values, err := GetField(obj, path)
+		if err != nil {
+			return false, err
+		}
+		for _, v := range values {
+			if fmt.Sprint(v) == fmt.Sprint(value) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+// ConditionStatus is true when the status of the condition in .status.conditions equals status, like ConditionStatus("Ready", "True")
+func ConditionStatus(conditionType, status string) FieldPredicate {
+	return FieldEquals(fmt.Sprintf(`.status.conditions[?(@.type=="%s")].status`, conditionType), status)
+}
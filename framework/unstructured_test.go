@@ -0,0 +1,125 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	e2e "github.com/spidernet-io/e2eframework/framework"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("test unstructured operation", Label("unstructured"), func() {
+	var f *e2e.Framework
+	var ctx context.Context
+	// the CRD out of the scheme
+	ippoolGVK := schema.GroupVersionKind{Group: "whereabouts.cni.cncf.io", Version: "v1alpha1", Kind: "IPPool"}
+	clusterGVK := schema.GroupVersionKind{Group: "crd.projectcalico.org", Version: "v1", Kind: "IPPool"}
+
+	BeforeEach(func() {
+		fakeEnv("/tmp/nokubeconfigfile")
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(ippoolGVK, meta.RESTScopeNamespace)
+		mapper.Add(clusterGVK, meta.RESTScopeRoot)
+		fakeClient := fake.NewClientBuilder().WithScheme(runtime.NewScheme()).WithRESTMapper(mapper).Build()
+
+		var e error
+		f, e = e2e.NewFramework(GinkgoT(), nil, fakeClient)
+		Expect(e).NotTo(HaveOccurred())
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 20*time.Second)
+		DeferCleanup(cancel)
+	})
+
+	It("resolve the kind", func() {
+		gvk, e := f.KindFor("ippools.whereabouts.cni.cncf.io")
+		Expect(e).NotTo(HaveOccurred())
+		Expect(gvk).To(Equal(ippoolGVK))
+		gvk, e = f.KindFor("ippools.v1.crd.projectcalico.org")
+		Expect(e).NotTo(HaveOccurred())
+		Expect(gvk).To(Equal(clusterGVK))
+		_, e = f.KindFor("notexisted.example.io")
+		Expect(e).To(HaveOccurred())
+
+		m, e := f.RESTMappingFor(ippoolGVK)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(m.Resource.Resource).To(Equal("ippools"))
+	})
+
+	It("operate the crd out of scheme", func() {
+		pool := e2e.NewUnstructured(ippoolGVK, "pool", "default")
+		Expect(unstructured.SetNestedField(pool.Object, "10.6.0.0/16", "spec", "range")).To(Succeed())
+		Expect(f.CreateUnstructured(ctx, pool)).To(Succeed())
+		Expect(f.CreateUnstructured(ctx, pool.DeepCopy())).To(MatchError(e2e.ErrAlreadyExisted))
+		Expect(f.CreateUnstructured(ctx, e2e.NewUnstructured(ippoolGVK, "pool", ""))).To(MatchError(e2e.ErrWrongInput))
+		Expect(f.CreateUnstructured(ctx, e2e.NewUnstructured(clusterGVK, "pool", "default"))).To(MatchError(e2e.ErrWrongInput))
+
+		p, e := f.GetUnstructured(ctx, ippoolGVK, "pool", "default")
+		Expect(e).NotTo(HaveOccurred())
+		v, e := e2e.GetField(p, ".spec.range")
+		Expect(e).NotTo(HaveOccurred())
+		Expect(v).To(Equal([]interface{}{"10.6.0.0/16"}))
+
+		list, e := f.ListUnstructured(ctx, ippoolGVK)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(list.Items).To(HaveLen(1))
+
+		Expect(unstructured.SetNestedField(p.Object, "10.7.0.0/16", "spec", "range")).To(Succeed())
+		Expect(f.UpdateUnstructured(ctx, p)).To(Succeed())
+		p, e = f.GetUnstructured(ctx, ippoolGVK, "pool", "default")
+		Expect(e).NotTo(HaveOccurred())
+		Expect(e2e.FieldEquals(".spec.range", "10.7.0.0/16")(p)).To(BeTrue())
+
+		Expect(f.DeleteUnstructuredAndWait(ctx, ippoolGVK, "pool", "default")).To(Succeed())
+		Expect(f.DeleteUnstructuredAndWait(ctx, ippoolGVK, "pool", "default")).To(Succeed())
+		Expect(f.DeleteUnstructured(ctx, ippoolGVK, "pool", "default")).NotTo(Succeed())
+
+		_, e = f.GetUnstructured(ctx, schema.GroupVersionKind{Group: "example.io", Version: "v1", Kind: "Foo"}, "foo", "")
+		Expect(e).To(HaveOccurred())
+	})
+
+	It("watch and wait with field predicates", func() {
+		w, e := f.WatchUnstructured(ctx, clusterGVK)
+		Expect(e).NotTo(HaveOccurred())
+		defer w.Stop()
+
+		go func() {
+			defer GinkgoRecover()
+			pool := e2e.NewUnstructured(clusterGVK, "default-ipv4-ippool", "")
+			Expect(f.CreateUnstructured(ctx, pool)).To(Succeed())
+			time.Sleep(time.Second)
+			pool.Object["status"] = map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Ready", "status": "True"},
+				},
+			}
+			Expect(f.UpdateUnstructured(ctx, pool)).To(Succeed())
+		}()
+
+		Eventually(w.ResultChan()).Should(Receive(WithTransform(func(ev watch.Event) watch.EventType { return ev.Type }, Equal(watch.Added))))
+
+		pool, e := f.WaitUnstructured(ctx, clusterGVK, "default-ipv4-ippool", "", e2e.ConditionStatus("Ready", "True"), e2e.FieldExists(".metadata.name"))
+		Expect(e).NotTo(HaveOccurred())
+		Expect(pool.GetName()).To(Equal("default-ipv4-ippool"))
+
+		shortCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+		_, e = f.WaitUnstructured(shortCtx, clusterGVK, "default-ipv4-ippool", "", e2e.FieldEquals(".spec.cidr", "10.0.0.0/8"))
+		Expect(e).To(MatchError(e2e.ErrTimeOut))
+
+		start := time.Now()
+		_, e = f.WaitUnstructured(ctx, clusterGVK, "default-ipv4-ippool", "", e2e.FieldExists(".spec[bad"))
+		Expect(e).To(MatchError(e2e.ErrWrongInput))
+		Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+	})
+})
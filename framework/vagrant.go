@@ -2,4 +2,307 @@
 // SPDX-License-Identifier: Apache-2.0
 package framework
 
-// operate vm
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// operate vm, the nodes of some clusters are the vagrant VMs, for kind could not load some kernel features, like:
+//   E2E_VAGRANT_DIR=/path/of/Vagrantfile E2E_VAGRANT_NODE_MAP=k8s-master=master,k8s-worker=worker
+// then:
+//   v, err := f.Vagrant()
+//   err = v.Reload(ctx, "k8s-worker")
+//   out, err := v.ExecOnVM(ctx, "k8s-worker", []string{"ip", "-4", "addr", "show", "eth1"})
+
+const (
+	// E2E_VAGRANT_DIR is the directory of the Vagrantfile
+	E2E_VAGRANT_DIR = "E2E_VAGRANT_DIR"
+	// E2E_VAGRANT_NODE_MAP maps the VM name to the node name, like "vm1=node1,vm2=node2"
+	E2E_VAGRANT_NODE_MAP = "E2E_VAGRANT_NODE_MAP"
+)
+
+// Vagrant runs the vagrant binary in the directory of the Vagrantfile,
+// and runs the commands on the VM by the ssh binary with the config of `vagrant ssh-config`
+type Vagrant struct {
+	// the directory of the Vagrantfile
+	Dir string
+	// NodeNames maps the VM name to the kubernetes node name, the missing VM has the same node name
+	NodeNames map[string]string
+	// the binaries, "vagrant" and "ssh" in PATH by default
+	VagrantBinary string
+	SSHBinary     string
+
+	lock sync.Mutex
+	// the ssh configs by the VM name, refreshed after the VM is up, halted or reloaded
+	sshConfigs map[string]*VagrantSSHConfig
+}
+
+// VagrantVM is one VM of `vagrant status`
+type VagrantVM struct {
+	Name     string
+	NodeName string
+	// like running, poweroff, not_created
+	State    string
+	Provider string
+}
+
+// VagrantSSHConfig is the ssh config of one VM
+type VagrantSSHConfig struct {
+	Host         string
+	HostName     string
+	User         string
+	Port         int
+	IdentityFile string
+	// the whole config, as the ssh_config file
+	Raw string
+}
+
+// NewVagrant returns the Vagrant of the Vagrantfile in dir
+func NewVagrant(dir string, nodeNames map[string]string) (*Vagrant, error) {
+	if dir == "" {
+		return nil, ErrWrongInput
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("%w: vagrant dir: %v", ErrWrongInput, err)
+	}
+	return &Vagrant{
+		Dir:           dir,
+		NodeNames:     nodeNames,
+		VagrantBinary: "vagrant",
+		SSHBinary:     "ssh",
+	}, nil
+}
+
+// Vagrant returns the Vagrant of ClusterInfo.VagrantDir and ClusterInfo.VagrantNodeMap
+func (f *Framework) Vagrant() (*Vagrant, error) {
+	if f.Info.VagrantDir == "" {
+		return nil, fmt.Errorf("%w: vagrant dir is not set by ENV %v or the config file", ErrWrongInput, E2E_VAGRANT_DIR)
+	}
+	return NewVagrant(f.Info.VagrantDir, f.Info.VagrantNodeMap)
+}
+
+// NodeName returns the node name of the VM
+func (v *Vagrant) NodeName(vm string) string {
+	if n, ok := v.NodeNames[vm]; ok {
+		return n
+	}
+	return vm
+}
+
+// VMName returns the VM name of the node
+func (v *Vagrant) VMName(node string) (string, error) {
+	for vm, n := range v.NodeNames {
+		if n == node {
+			return vm, nil
+		}
+	}
+	if _, ok := v.NodeNames[node]; ok {
+		return "", fmt.Errorf("%w: VM %s is mapped to node %s", ErrWrongInput, node, v.NodeNames[node])
+	}
+	return node, nil
+}
+
+func (v *Vagrant) vagrant(ctx context.Context, args ...string) ([]byte, error) {
+	stdout, _, err := runBinary(ctx, v.Dir, "vagrant", v.VagrantBinary, args...)
+	return stdout, err
+}
+
+// Status returns the VMs in the order of the name
+func (v *Vagrant) Status(ctx context.Context) ([]VagrantVM, error) {
+	out, err := v.vagrant(ctx, "status", "--machine-readable")
+	if err != nil {
+		return nil, err
+	}
+
+	// the line is timestamp,target,type,data...
+	vms := map[string]*VagrantVM{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), ",", 4)
+		if len(fields) < 4 || fields[1] == "" {
+			continue
+		}
+		vm, ok := vms[fields[1]]
+		if !ok {
+			vm = &VagrantVM{Name: fields[1], NodeName: v.NodeName(fields[1])}
+		}
+		switch fields[2] {
+		case "state":
+			vm.State = fields[3]
+		case "provider-name":
+			vm.Provider = fields[3]
+		default:
+			continue
+		}
+		vms[fields[1]] = vm
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	r := make([]VagrantVM, 0, len(vms))
+	for _, vm := range vms {
+		r = append(r, *vm)
+	}
+	sort.Slice(r, func(i, j int) bool { return r[i].Name < r[j].Name })
+	return r, nil
+}
+
+// SSHConfig returns the ssh config of the VM, which is cached until the VM is up, halted or reloaded
+func (v *Vagrant) SSHConfig(ctx context.Context, vm string) (*VagrantSSHConfig, error) {
+	if vm == "" {
+		return nil, ErrWrongInput
+	}
+	v.lock.Lock()
+	c, ok := v.sshConfigs[vm]
+	v.lock.Unlock()
+	if ok {
+		return c, nil
+	}
+
+	out, err := v.vagrant(ctx, "ssh-config", vm)
+	if err != nil {
+		return nil, err
+	}
+	c, err = parseSSHConfig(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the ssh-config of VM %s: %w", vm, err)
+	}
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if v.sshConfigs == nil {
+		v.sshConfigs = map[string]*VagrantSSHConfig{}
+	}
+	v.sshConfigs[vm] = c
+	return c, nil
+}
+
+func parseSSHConfig(out []byte) (*VagrantSSHConfig, error) {
+	c := &VagrantSSHConfig{Raw: string(out), Port: 22}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		value := strings.Trim(strings.Join(fields[1:], " "), `"`)
+		switch strings.ToLower(fields[0]) {
+		case "host":
+			c.Host = value
+		case "hostname":
+			c.HostName = value
+		case "user":
+			c.User = value
+		case "identityfile":
+			c.IdentityFile = value
+		case "port":
+			port, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid port %q", value)
+			}
+			c.Port = port
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if c.Host == "" || c.HostName == "" {
+		return nil, fmt.Errorf("miss Host or HostName")
+	}
+	return c, nil
+}
+
+func (v *Vagrant) resetSSHConfig(vms []string) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if len(vms) == 0 {
+		v.sshConfigs = nil
+		return
+	}
+	for _, vm := range vms {
+		delete(v.sshConfigs, vm)
+	}
+}
+
+// Up starts the VMs, empty means all VMs
+func (v *Vagrant) Up(ctx context.Context, vms ...string) error {
+	defer v.resetSSHConfig(vms)
+	_, err := v.vagrant(ctx, append([]string{"up"}, vms...)...)
+	return err
+}
+
+// Halt shuts down the VMs, empty means all VMs
+func (v *Vagrant) Halt(ctx context.Context, vms ...string) error {
+	defer v.resetSSHConfig(vms)
+	_, err := v.vagrant(ctx, append([]string{"halt"}, vms...)...)
+	return err
+}
+
+// Reload restarts the VMs, empty means all VMs
+func (v *Vagrant) Reload(ctx context.Context, vms ...string) error {
+	defer v.resetSSHConfig(vms)
+	_, err := v.vagrant(ctx, append([]string{"reload"}, vms...)...)
+	return err
+}
+
+// ExecOnVM runs command on the VM over ssh, every argument is quoted for the remote shell.
+// The ExecResult is returned even with the ExecExitError
+func (v *Vagrant) ExecOnVM(ctx context.Context, vm string, command []string) (*ExecResult, error) {
+	if vm == "" || len(command) == 0 {
+		return nil, ErrWrongInput
+	}
+	c, err := v.SSHConfig(ctx, vm)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.CreateTemp("", "vagrant-ssh-config-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString(c.Raw); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+
+	return runSSH(ctx, v.SSHBinary, []string{"-F", file.Name(), "-o", "BatchMode=yes", c.Host}, command)
+}
+
+// ExecOnNode runs command on the VM of the node, so Vagrant is a NodeExecutor
+func (v *Vagrant) ExecOnNode(ctx context.Context, node *corev1.Node, command []string) (*ExecResult, error) {
+	if node == nil {
+		return nil, ErrWrongInput
+	}
+	vm, err := v.VMName(node.Name)
+	if err != nil {
+		return nil, err
+	}
+	return v.ExecOnVM(ctx, vm, command)
+}
+
+// parseVagrantNodeMap parses "vm1=node1,vm2=node2"
+func parseVagrantNodeMap(raw string) (map[string]string, error) {
+	m := map[string]string{}
+	for _, item := range strings.Split(raw, ",") {
+		vm, node, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok || vm == "" || node == "" {
+			return nil, fmt.Errorf("%v has an invalid item %q, expect vm=node", E2E_VAGRANT_NODE_MAP, item)
+		}
+		m[vm] = node
+	}
+	return m, nil
+}
@@ -0,0 +1,150 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	e2e "github.com/spidernet-io/e2eframework/framework"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// the stub vagrant records the calls in calls.log of the working directory
+const fakeVagrantScript = `#!/bin/sh
+echo "$@" >> calls.log
+case "$1" in
+status)
+  echo '1600000000,k8s-master,metadata,provider,virtualbox'
+  echo '1600000000,k8s-master,provider-name,virtualbox'
+  echo '1600000000,k8s-master,state,running'
+  echo '1600000000,k8s-worker,provider-name,virtualbox'
+  echo '1600000000,k8s-worker,state,poweroff'
+  echo '1600000000,,ui,info,Current machine states:'
+  ;;
+ssh-config)
+  [ "$2" = "not-existed" ] && { echo "The machine with the name 'not-existed' was not found" >&2; exit 1; }
+  cat <<EOF
+Host $2
+  HostName 127.0.0.1
+  User vagrant
+  Port 2222
+  IdentityFile "/tmp/vagrant key"
+  StrictHostKeyChecking no
+EOF
+  ;;
+esac
+`
+
+// the stub ssh prints the config file and the remote command
+const fakeSSHScript = `#!/bin/sh
+cat "$2"
+shift 6
+echo "remote: $@"
+`
+
+var _ = Describe("test vagrant", Label("vagrant"), func() {
+	var ctx context.Context
+	var dir string
+	var v *e2e.Vagrant
+
+	BeforeEach(func() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 20*time.Second)
+		DeferCleanup(cancel)
+
+		dir = GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "vagrant"), []byte(fakeVagrantScript), 0o700)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "ssh"), []byte(fakeSSHScript), 0o700)).To(Succeed())
+
+		var e error
+		v, e = e2e.NewVagrant(dir, map[string]string{"k8s-master": "master"})
+		Expect(e).NotTo(HaveOccurred())
+		v.VagrantBinary = filepath.Join(dir, "vagrant")
+		v.SSHBinary = filepath.Join(dir, "ssh")
+	})
+
+	It("read the status and ssh-config", func() {
+		vms, e := v.Status(ctx)
+		Expect(e).NotTo(HaveOccurred())
+		Expect(vms).To(Equal([]e2e.VagrantVM{
+			{Name: "k8s-master", NodeName: "master", State: "running", Provider: "virtualbox"},
+			{Name: "k8s-worker", NodeName: "k8s-worker", State: "poweroff", Provider: "virtualbox"},
+		}))
+
+		c, e := v.SSHConfig(ctx, "k8s-master")
+		Expect(e).NotTo(HaveOccurred())
+		Expect(c.Host).To(Equal("k8s-master"))
+		Expect(c.HostName).To(Equal("127.0.0.1"))
+		Expect(c.Port).To(Equal(2222))
+		Expect(c.IdentityFile).To(Equal("/tmp/vagrant key"))
+
+		_, e = v.SSHConfig(ctx, "not-existed")
+		var exitErr *e2e.ExecExitError
+		Expect(errors.As(e, &exitErr)).To(BeTrue())
+		Expect(string(exitErr.Stderr)).To(ContainSubstring("not found"))
+
+		vm, e := v.VMName("master")
+		Expect(e).NotTo(HaveOccurred())
+		Expect(vm).To(Equal("k8s-master"))
+		_, e = v.VMName("k8s-master")
+		Expect(e).To(MatchError(e2e.ErrWrongInput))
+	})
+
+	It("operate the VMs and run the command over ssh", func() {
+		Expect(v.Up(ctx, "k8s-worker")).To(Succeed())
+		Expect(v.Halt(ctx)).To(Succeed())
+
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "master"}}
+		r, e := v.ExecOnNode(ctx, node, []string{"sh", "-c", "echo 'a b'"})
+		Expect(e).NotTo(HaveOccurred())
+		Expect(string(r.Stdout)).To(ContainSubstring("HostName 127.0.0.1"))
+		Expect(string(r.Stdout)).To(ContainSubstring(`remote: 'sh' '-c' 'echo '\''a b'\'''`))
+
+		// the ssh-config is cached until reload
+		_, e = v.ExecOnVM(ctx, "k8s-master", []string{"true"})
+		Expect(e).NotTo(HaveOccurred())
+		Expect(v.Reload(ctx, "k8s-master")).To(Succeed())
+		_, e = v.ExecOnVM(ctx, "k8s-master", []string{"true"})
+		Expect(e).NotTo(HaveOccurred())
+
+		calls, e := os.ReadFile(filepath.Join(dir, "calls.log"))
+		Expect(e).NotTo(HaveOccurred())
+		Expect(strings.Split(strings.TrimSpace(string(calls)), "\n")).To(Equal([]string{
+			"up k8s-worker",
+			"halt",
+			"ssh-config k8s-master",
+			"reload k8s-master",
+			"ssh-config k8s-master",
+		}))
+	})
+
+	It("configure by the cluster info", func() {
+		f := fakeFramework()
+		_, e := f.Vagrant()
+		Expect(e).To(MatchError(e2e.ErrWrongInput))
+
+		path := writeConfigFile(`
+apiVersion: e2eframework.spidernet.io/v1alpha1
+kind: ClusterConfig
+cluster:
+  vagrantDir: ` + dir + `
+  vagrantNodeMap:
+    k8s-worker: worker
+    k8s-master: master
+`)
+		Expect(e2e.LoadClusterConfig(path, "", &f.Info, &e2e.FConfig{})).To(Succeed())
+		Expect(f.Info.VagrantNodeMapRaw).To(Equal("k8s-master=master,k8s-worker=worker"))
+		v, e := f.Vagrant()
+		Expect(e).NotTo(HaveOccurred())
+		Expect(v.NodeName("k8s-worker")).To(Equal("worker"))
+	})
+})
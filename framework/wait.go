@@ -0,0 +1,385 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	api_errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// the wait engine lists the objects, then watches them from the resourceVersion of the list,
+// and checks the condition on every change, so it returns as soon as the condition holds, like:
+//   pods, err := framework.WaitList(ctx, f, func(l *corev1.PodList) (bool, error) {
+//       return len(l.Items) == 3, nil
+//   }, client.InNamespace(ns), client.MatchingLabels(label))
+// The watch resumes from the last resourceVersion when it is closed, and relists after "too old resourceVersion".
+// When ctx is done, it returns the *WaitTimeoutError with the last observed objects
+
+const (
+	// the delay before relisting for an unexpected watch error
+	waitRelistDelay = time.Second
+	// the max length of the status in WaitTimeoutError
+	waitStatusMaxLen = 512
+	// the max number of the objects in WaitTimeoutError
+	waitReportMaxObjects = 5
+)
+
+// WaitTimeoutError means ctx is done before the condition holds, errors.Is(err, ErrTimeOut) is true
+type WaitTimeoutError struct {
+	// like "pod 'default/nginx'" or "pods in namespace default"
+	Target string
+	// like "deleted" or "running", empty for a custom condition
+	Condition string
+	// the objects observed last time, empty when nothing is found
+	Last []client.Object
+}
+
+func (e *WaitTimeoutError) Error() string {
+	msg := fmt.Sprintf("%v: waiting for %v", ErrTimeOut, e.Target)
+	if e.Condition != "" {
+		msg += " to be " + e.Condition
+	} else {
+		msg += " to meet the condition"
+	}
+	if len(e.Last) == 0 {
+		return msg + ", nothing is found"
+	}
+	summaries := []string{}
+	for n, obj := range e.Last {
+		if n == waitReportMaxObjects {
+			summaries = append(summaries, fmt.Sprintf("and %d more", len(e.Last)-n))
+			break
+		}
+		summaries = append(summaries, objectSummary(obj))
+	}
+	return msg + ", last observed: " + strings.Join(summaries, "; ")
+}
+
+func (e *WaitTimeoutError) Unwrap() error {
+	return ErrTimeOut
+}
+
+// WaitList waits until cond returns true for the objects of the typed list L selected by opts, and returns them.
+// On timeout, the last observed objects are returned with the *WaitTimeoutError
+func WaitList[L client.ObjectList](ctx context.Context, f *Framework, cond func(L) (bool, error), opts ...client.ListOption) (L, error) {
+	var empty L
+	if f == nil || cond == nil {
+		return empty, ErrWrongInput
+	}
+	toList := func(objs []client.Object) (L, error) {
+		list := newObject[L]()
+		items := make([]runtime.Object, 0, len(objs))
+		for _, obj := range objs {
+			items = append(items, obj)
+		}
+		return list, meta.SetList(list, items)
+	}
+	sample := newObject[L]()
+	objs, err := f.watchUntil(ctx, sample, f.listTarget(sample, opts...), "", nil, func(objs []client.Object) (bool, error) {
+		list, err := toList(objs)
+		if err != nil {
+			return false, err
+		}
+		return cond(list)
+	}, opts...)
+	list, e := toList(objs)
+	if e != nil {
+		return empty, e
+	}
+	return list, err
+}
+
+// waitFor gets the object by newObj, which sets the GVK for the unstructured object
+func waitFor[T client.Object](ctx context.Context, f *Framework, key client.ObjectKey, newObj func() T, pred func(T) bool) (T, error) {
+	return waitObject(ctx, f, key, newObj, "", func(obj T, found bool) (bool, error) {
+		return found && pred(obj), nil
+	})
+}
+
+// waitGone waits until the object does not exist
+func waitGone[T client.Object](ctx context.Context, f *Framework, obj T) error {
+	_, err := waitObject(ctx, f, client.ObjectKeyFromObject(obj), func() T { return newObjectLike(obj) }, "deleted", func(_ T, found bool) (bool, error) {
+		return !found, nil
+	})
+	return err
+}
+
+// waitUntilNotDeleted waits until ready returns true for the object, and fails with ErrResDel when the found object is deleted
+func waitUntilNotDeleted[T client.Object](ctx context.Context, f *Framework, name, namespace, condition string, newObj func() T, ready func(T) (bool, error)) (T, error) {
+	var empty T
+	seen := false
+	key := client.ObjectKey{Namespace: namespace, Name: name}
+	obj, err := waitObject(ctx, f, key, newObj, condition, func(obj T, found bool) (bool, error) {
+		if !found {
+			if seen {
+				return false, fmt.Errorf("%w: %s '%s'", ErrResDel, f.kindOf(newObj()), keyString(key))
+			}
+			return false, nil
+		}
+		seen = true
+		return ready(obj)
+	})
+	if err != nil {
+		return empty, err
+	}
+	return obj, nil
+}
+
+// waitObject watches the object of key until cond returns true, found is false when it does not exist.
+// condition describes cond for the WaitTimeoutError
+func waitObject[T client.Object](ctx context.Context, f *Framework, key client.ObjectKey, newObj func() T, condition string, cond func(obj T, found bool) (bool, error)) (T, error) {
+	var last T
+	sample := newObj()
+	list, err := f.listFor(sample)
+	if err != nil {
+		return last, err
+	}
+	target := fmt.Sprintf("%s '%s'", f.kindOf(sample), keyString(key))
+	match := func(obj client.Object) bool {
+		return obj.GetName() == key.Name
+	}
+	objs, err := f.watchUntil(ctx, list, target, condition, match, func(objs []client.Object) (bool, error) {
+		if len(objs) == 0 {
+			var empty T
+			return cond(empty, false)
+		}
+		return cond(objs[0].(T), true)
+	}, client.InNamespace(key.Namespace), matchingName(key.Name))
+	if len(objs) > 0 {
+		last = objs[0].(T)
+	}
+	return last, err
+}
+
+// watchUntil lists and watches the objects of list selected by opts and match, until cond returns true for them.
+// It returns the objects sorted by the namespace and name
+func (f *Framework) watchUntil(ctx context.Context, list client.ObjectList, target, condition string, match func(client.Object) bool, cond func([]client.Object) (bool, error), opts ...client.ListOption) ([]client.Object, error) {
+	listOpts := &client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	// the selectors are checked again, in case the watch does not support them
+	selected := func(obj client.Object) bool {
+		if listOpts.Namespace != "" && obj.GetNamespace() != listOpts.Namespace {
+			return false
+		}
+		if listOpts.LabelSelector != nil && !listOpts.LabelSelector.Matches(labels.Set(obj.GetLabels())) {
+			return false
+		}
+		return match == nil || match(obj)
+	}
+
+	store := map[client.ObjectKey]client.Object{}
+	current := func() []client.Object {
+		objs := make([]client.Object, 0, len(store))
+		for _, obj := range store {
+			objs = append(objs, obj)
+		}
+		sort.Slice(objs, func(i, j int) bool {
+			return keyString(client.ObjectKeyFromObject(objs[i])) < keyString(client.ObjectKeyFromObject(objs[j]))
+		})
+		return objs
+	}
+	timeout := func() ([]client.Object, error) {
+		objs := current()
+		return objs, &WaitTimeoutError{Target: target, Condition: condition, Last: objs}
+	}
+
+	resourceVersion := ""
+	relist := true
+	for {
+		if relist {
+			l := list.DeepCopyObject().(client.ObjectList)
+			if err := f.KClient.List(ctx, l, listOpts); err != nil {
+				if ctx.Err() != nil {
+					return timeout()
+				}
+				return current(), err
+			}
+			items, err := meta.ExtractList(l)
+			if err != nil {
+				return current(), err
+			}
+			store = map[client.ObjectKey]client.Object{}
+			for _, item := range items {
+				if obj, ok := item.(client.Object); ok && selected(obj) {
+					store[client.ObjectKeyFromObject(obj)] = obj
+				}
+			}
+			resourceVersion = l.GetResourceVersion()
+			relist = false
+			if ok, err := cond(current()); ok || err != nil {
+				return current(), err
+			}
+		}
+
+		w, err := f.KClient.Watch(ctx, list.DeepCopyObject().(client.ObjectList), watchOptions(listOpts, resourceVersion))
+		if err != nil {
+			if ctx.Err() != nil {
+				return timeout()
+			}
+			if api_errors.IsResourceExpired(err) || api_errors.IsGone(err) {
+				relist = true
+				continue
+			}
+			return current(), fmt.Errorf("%w: %v", ErrWatch, err)
+		}
+
+		done, err := func() (bool, error) {
+			defer w.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return false, nil
+				case event, ok := <-w.ResultChan():
+					if !ok {
+						// resume from the last resourceVersion
+						relist = resourceVersion == ""
+						return false, nil
+					}
+					switch event.Type {
+					case watch.Error:
+						status := api_errors.FromObject(event.Object)
+						if !api_errors.IsResourceExpired(status) && !api_errors.IsGone(status) {
+							f.Log("watch %v: %v, relist later \n", target, status)
+							sleepContext(ctx, waitRelistDelay)
+						}
+						relist = true
+						return false, nil
+					case watch.Bookmark:
+						if obj, e := meta.Accessor(event.Object); e == nil {
+							resourceVersion = obj.GetResourceVersion()
+						}
+						continue
+					}
+					obj, ok := event.Object.(client.Object)
+					if !ok {
+						return false, ErrGetObj
+					}
+					resourceVersion = obj.GetResourceVersion()
+					key := client.ObjectKeyFromObject(obj)
+					if event.Type == watch.Deleted || !selected(obj) {
+						if _, ok := store[key]; !ok {
+							continue
+						}
+						delete(store, key)
+					} else {
+						store[key] = obj.DeepCopyObject().(client.Object)
+					}
+					if ok, err := cond(current()); ok || err != nil {
+						return true, err
+					}
+				}
+			}
+		}()
+		if done || err != nil {
+			return current(), err
+		}
+		if ctx.Err() != nil {
+			return timeout()
+		}
+	}
+}
+
+// matchingName selects the object of the name by the server, like client.MatchingFields{"metadata.name": name}.
+// It is set in the raw options, which the fake client without the field index ignores, then match filters the objects
+type matchingName string
+
+func (n matchingName) ApplyToList(opts *client.ListOptions) {
+	if opts.Raw == nil {
+		opts.Raw = &metav1.ListOptions{}
+	}
+	opts.Raw.FieldSelector = fields.OneTermEqualSelector("metadata.name", string(n)).String()
+}
+
+// watchOptions resumes the watch from resourceVersion
+func watchOptions(listOpts *client.ListOptions, resourceVersion string) *client.ListOptions {
+	opts := *listOpts
+	raw := &metav1.ListOptions{}
+	if listOpts.Raw != nil {
+		raw = listOpts.Raw.DeepCopy()
+	}
+	raw.ResourceVersion = resourceVersion
+	raw.AllowWatchBookmarks = true
+	opts.Raw = raw
+	opts.Limit = 0
+	opts.Continue = ""
+	return &opts
+}
+
+// listFor returns the empty list of the type of obj
+func (f *Framework) listFor(obj client.Object) (client.ObjectList, error) {
+	gvk, err := apiutil.GVKForObject(obj, f.KClient.Scheme())
+	if err != nil {
+		return nil, err
+	}
+	gvk.Kind += "List"
+	if _, ok := obj.(runtime.Unstructured); ok {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk)
+		return list, nil
+	}
+	o, err := f.KClient.Scheme().New(gvk)
+	if err != nil {
+		return nil, err
+	}
+	list, ok := o.(client.ObjectList)
+	if !ok {
+		return nil, fmt.Errorf("%w: %v is not a list", ErrWrongInput, gvk)
+	}
+	return list, nil
+}
+
+// listTarget describes the objects of list for the WaitTimeoutError
+func (f *Framework) listTarget(list client.ObjectList, opts ...client.ListOption) string {
+	target := fmt.Sprintf("%T", list)
+	if gvk, err := apiutil.GVKForObject(list, f.KClient.Scheme()); err == nil {
+		target = strings.ToLower(strings.TrimSuffix(gvk.Kind, "List")) + "s"
+	}
+	listOpts := &client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	if listOpts.Namespace != "" {
+		target += " in namespace " + listOpts.Namespace
+	}
+	if listOpts.LabelSelector != nil && !listOpts.LabelSelector.Empty() {
+		target += " with labels " + listOpts.LabelSelector.String()
+	}
+	return target
+}
+
+// objectSummary returns the name, resourceVersion and status of obj
+func objectSummary(obj client.Object) string {
+	summary := fmt.Sprintf("%s resourceVersion=%s", keyString(client.ObjectKeyFromObject(obj)), obj.GetResourceVersion())
+	if obj.GetDeletionTimestamp() != nil {
+		summary += " deleting"
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return summary
+	}
+	status, ok := content["status"]
+	if !ok {
+		return summary
+	}
+	data, err := json.Marshal(status)
+	if err != nil {
+		return summary
+	}
+	if len(data) > waitStatusMaxLen {
+		data = append(data[:waitStatusMaxLen], "..."...)
+	}
+	return summary + " status=" + string(data)
+}
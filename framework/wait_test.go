@@ -0,0 +1,153 @@
+// Copyright 2022 Authors of spidernet-io
+// SPDX-License-Identifier: Apache-2.0
+package framework_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	e2e "github.com/spidernet-io/e2eframework/framework"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+var _ = Describe("test wait engine", Label("wait"), func() {
+	var f *e2e.Framework
+	var ctx context.Context
+	label := map[string]string{"app": "wait"}
+
+	BeforeEach(func() {
+		f = fakeFramework()
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 20*time.Second)
+		DeferCleanup(cancel)
+	})
+
+	It("return as soon as the condition holds", func() {
+		go func() {
+			defer GinkgoRecover()
+			time.Sleep(time.Second)
+			for _, name := range []string{"wait-1", "wait-2", "other"} {
+				l := label
+				if name == "other" {
+					l = map[string]string{"app": "other"}
+				}
+				Expect(f.CreatePod(generateExamplePodYaml(name, "default", l, corev1.PodRunning))).To(Succeed())
+			}
+		}()
+
+		start := time.Now()
+		pods, e := e2e.WaitList(ctx, f, func(l *corev1.PodList) (bool, error) {
+			return len(l.Items) == 2, nil
+		}, client.InNamespace("default"), client.MatchingLabels(label))
+		Expect(e).NotTo(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+		Expect(pods.Items).To(HaveLen(2))
+		Expect(pods.Items[0].Name).To(Equal("wait-1"))
+		Expect(pods.Items[1].Name).To(Equal("wait-2"))
+
+		// the condition error stops waiting
+		condErr := errors.New("condition error")
+		_, e = e2e.WaitList(ctx, f, func(l *corev1.PodList) (bool, error) {
+			return false, condErr
+		})
+		Expect(e).To(MatchError(condErr))
+	})
+
+	It("report the last observed object on timeout", func() {
+		pod := generateExamplePodYaml("wait-pending", "default", label, corev1.PodPending)
+		Expect(f.CreatePod(pod)).To(Succeed())
+
+		shortCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		_, e := f.WaitPodStartedContext(shortCtx, pod.Name, pod.Namespace)
+		Expect(e).To(MatchError(e2e.ErrTimeOut))
+		var timeoutErr *e2e.WaitTimeoutError
+		Expect(errors.As(e, &timeoutErr)).To(BeTrue())
+		Expect(timeoutErr.Last).To(HaveLen(1))
+		Expect(timeoutErr.Last[0].(*corev1.Pod).Status.Phase).To(Equal(corev1.PodPending))
+		Expect(e.Error()).To(ContainSubstring("pod 'default/wait-pending' to be running"))
+		Expect(e.Error()).To(ContainSubstring(`"phase":"Pending"`))
+
+		shortCtx, cancel = context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		_, e = e2e.WaitFor(shortCtx, f, "not-existed", "default", func(*corev1.Pod) bool { return true })
+		Expect(errors.As(e, &timeoutErr)).To(BeTrue())
+		Expect(timeoutErr.Last).To(BeEmpty())
+		Expect(e.Error()).To(ContainSubstring("nothing is found"))
+	})
+
+	It("relist after the expired watch, and resume the closed watch", func() {
+		lock := sync.Mutex{}
+		lists := 0
+		watches := []string{}
+		selectors := []string{}
+		f.KClient = fake.NewClientBuilder().WithScheme(f.KClient.Scheme()).WithInterceptorFuncs(interceptor.Funcs{
+			List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+				listOpts := &client.ListOptions{}
+				listOpts.ApplyOptions(opts)
+				lock.Lock()
+				lists++
+				selectors = append(selectors, listOpts.AsListOptions().FieldSelector)
+				lock.Unlock()
+				if e := c.List(ctx, list, opts...); e != nil {
+					return e
+				}
+				// like the API server, which always sets the resourceVersion of the list
+				list.SetResourceVersion("100")
+				return nil
+			},
+			Watch: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) (watch.Interface, error) {
+				listOpts := &client.ListOptions{}
+				listOpts.ApplyOptions(opts)
+				lock.Lock()
+				watches = append(watches, listOpts.Raw.ResourceVersion)
+				selectors = append(selectors, listOpts.AsListOptions().FieldSelector)
+				n := len(watches)
+				lock.Unlock()
+				switch n {
+				case 1:
+					w := watch.NewFake()
+					go w.Error(&metav1.Status{
+						Status:  metav1.StatusFailure,
+						Code:    410,
+						Reason:  metav1.StatusReasonExpired,
+						Message: "too old resource version",
+					})
+					return w, nil
+				case 2:
+					w := watch.NewFake()
+					w.Stop()
+					return w, nil
+				}
+				return c.Watch(ctx, list, opts...)
+			},
+		}).Build()
+
+		go func() {
+			defer GinkgoRecover()
+			time.Sleep(time.Second)
+			Expect(f.CreatePod(generateExamplePodYaml("wait-relist", "default", label, corev1.PodRunning))).To(Succeed())
+		}()
+		_, e := f.WaitPodStartedContext(ctx, "wait-relist", "default")
+		Expect(e).NotTo(HaveOccurred())
+
+		lock.Lock()
+		defer lock.Unlock()
+		Expect(lists).To(Equal(2))
+		Expect(len(watches)).To(BeNumerically(">=", 3))
+		Expect(watches[1]).To(Equal("100"))
+		Expect(watches[2]).To(Equal("100"))
+		// the server selects the object by the name
+		Expect(selectors).To(HaveEach("metadata.name=wait-relist"))
+	})
+})
@@ -5,8 +5,10 @@ package tools
 import (
 	"github.com/asaskevich/govalidator"
 	corev1 "k8s.io/api/core/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
 
 	// metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"context"
 	"fmt"
 	"time"
 )
@@ -35,23 +37,35 @@ func CheckPodIpv6IPReady(pod *corev1.Pod) (string, bool) {
 	return "", false
 }
 
+// RandomName has a random suffix, so it does not collide across the parallel processes
 func RandomName() string {
 	m := time.Now()
-	return fmt.Sprintf("%v%v-%v", m.Minute(), m.Second(), m.Nanosecond())
+	return fmt.Sprintf("%v%v-%v-%v", m.Minute(), m.Second(), m.Nanosecond(), utilrand.String(5))
 }
 
 // simulate Eventually for internal
 func Eventually(f func() bool, timeout time.Duration, interval time.Duration) bool {
+	return EventuallyWithContext(context.Background(), f, timeout, interval)
+}
+
+// EventuallyWithContext is Eventually, which also gives up when ctx is done
+func EventuallyWithContext(ctx context.Context, f func() bool, timeout time.Duration, interval time.Duration) bool {
 	timeoutAfter := time.After(timeout)
 	for {
 		select {
 		case <-timeoutAfter:
 			return false
+		case <-ctx.Done():
+			return false
 		default:
 		}
 		if f() {
 			return true
 		}
-		time.Sleep(interval)
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return false
+		}
 	}
 }